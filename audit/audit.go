@@ -0,0 +1,79 @@
+// Package audit writes the state transitions of a light client to an
+// append-only JSON lines file, and rebuilds a light client from it.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Log is a btclightclient.AuditLogger appending the events as JSON lines to
+// a file.
+type Log struct {
+	mu  sync.Mutex
+	f   *os.File
+	enc *json.Encoder
+}
+
+var _ btclightclient.AuditLogger = (*Log)(nil)
+
+// Open opens the audit log at path for appending, creating it if needed.
+func Open(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &Log{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+// LogEvent appends ev to the log. The light client can't handle a failed
+// write, it is only logged.
+func (l *Log) LogEvent(ev btclightclient.AuditEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.enc.Encode(ev); err != nil {
+		log.Err(err).Msgf("Failed to write %s audit event of block %s", ev.Type, ev.Hash)
+	}
+}
+
+// Close flushes the log to disk and closes it.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.f.Sync(); err != nil {
+		l.f.Close()
+		return err
+	}
+	return l.f.Close()
+}
+
+// Read calls fn with the events of the audit log read from r, in order,
+// with their line number.
+func Read(r io.Reader, fn func(line int, ev btclightclient.AuditEvent) error) error {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var ev btclightclient.AuditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := fn(line, ev); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,135 @@
+package audit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
+	"github.com/gonative-cc/bitcoin-lightclient/chaingen"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"gotest.tools/assert"
+)
+
+func insert(t *testing.T, lc *btclightclient.BTCLightClient, blocks ...*chaingen.Block) {
+	t.Helper()
+	for _, b := range blocks {
+		outcome, err := lc.InsertHeaderFrom("test", b.Header())
+		if err != nil || outcome == btclightclient.HeaderDuplicate {
+			continue
+		}
+		assert.NilError(t, lc.CleanUpFork())
+	}
+}
+
+func TestReplay(t *testing.T) {
+	g := chaingen.New(&chaincfg.RegressionNetParams)
+	base := chaingen.Chain(g.Extend(g.Genesis(), 10)[9])
+	newLightClient := func() *btclightclient.BTCLightClient {
+		return btclightclient.NewBTCLightClientWithData(g.Params(), chaingen.Headers(base), 0,
+			btclightclient.WithFinalityDepth(3))
+	}
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+
+	lc := newLightClient()
+	l, err := Open(path)
+	assert.NilError(t, err)
+	lc.SetAuditLogger(l)
+	// a fork of the tip, a duplicate and an orphan header
+	fork := g.Extend(base[9], 2)
+	insert(t, lc, g.NextBlock(base[10]))
+	insert(t, lc, fork...)
+	insert(t, lc, fork[1], g.NextBlock(g.NextBlock(fork[1])))
+	assert.NilError(t, l.Close())
+
+	// the log is appended to after a restart, the fork overtakes the best
+	// chain which is pruned as the checkpoint moves
+	l, err = Open(path)
+	assert.NilError(t, err)
+	lc.SetAuditLogger(l)
+	insert(t, lc, g.Extend(fork[1], 3)...)
+	assert.NilError(t, l.Close())
+
+	f, err := os.Open(path)
+	assert.NilError(t, err)
+	defer f.Close()
+	counts := map[btclightclient.AuditEventType]int{}
+	var rejected btclightclient.AuditEvent
+	assert.NilError(t, Read(f, func(_ int, ev btclightclient.AuditEvent) error {
+		counts[ev.Type]++
+		if ev.Outcome != nil && *ev.Outcome == btclightclient.HeaderRejected {
+			rejected = ev
+		}
+		return nil
+	}))
+	assert.DeepEqual(t, counts, map[btclightclient.AuditEventType]int{
+		btclightclient.AuditOpen:       2,
+		btclightclient.AuditInsert:     8,
+		btclightclient.AuditReorg:      1,
+		btclightclient.AuditCheckpoint: 4,
+		btclightclient.AuditPrune:      2,
+	})
+	assert.Equal(t, rejected.Source, "test")
+	assert.Equal(t, rejected.Error, btclightclient.ErrParentBlockNotInChain.Error())
+
+	replayed := newLightClient()
+	_, err = f.Seek(0, 0)
+	assert.NilError(t, err)
+	// lc kept its forks on the restart
+	keep := func(lc *btclightclient.BTCLightClient) (*btclightclient.BTCLightClient, error) { return lc, nil }
+	replayed, stats, err := Replay(replayed, f, keep)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, stats, ReplayStats{Events: 17, Inserts: 8, Inserted: 6, Reopens: 1})
+	want, err := lc.ChainStatus()
+	assert.NilError(t, err)
+	got, err := replayed.ChainStatus()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, got, want)
+
+	// a light client not in the logged state
+	_, err = f.Seek(0, 0)
+	assert.NilError(t, err)
+	_, _, err = Replay(replayed, f, nil)
+	assert.Assert(t, errors.Is(err, ErrDiverged), err)
+
+	// a light client with another config
+	_, err = f.Seek(0, 0)
+	assert.NilError(t, err)
+	other := btclightclient.NewBTCLightClientWithData(g.Params(), chaingen.Headers(base), 0,
+		btclightclient.WithFinalityDepth(4))
+	_, _, err = Replay(other, f, nil)
+	assert.Assert(t, errors.Is(err, ErrDiverged), err)
+
+	// a light client in the logged state, without the first blocks, is
+	// rejected before any insertion
+	_, err = f.Seek(0, 0)
+	assert.NilError(t, err)
+	partial, err := btclightclient.NewBTCLightClientFromIterator(g.Params(),
+		btclightclient.NewHeaderSliceIterator(chaingen.Headers(base[2:])), 2,
+		newLightClient().TotalWorkAtBlock(base[1].Hash()), btclightclient.WithFinalityDepth(3))
+	assert.NilError(t, err)
+	partialRoot, err := partial.StateRoot()
+	assert.NilError(t, err)
+	baseRoot, err := newLightClient().StateRoot()
+	assert.NilError(t, err)
+	assert.Equal(t, partialRoot, baseRoot)
+	_, stats, err = Replay(partial, f, nil)
+	assert.Assert(t, errors.Is(err, ErrDiverged), err)
+	assert.ErrorContains(t, err, "line 1: ")
+	assert.ErrorContains(t, err, "store hash")
+	assert.Equal(t, stats.Inserts, 0)
+}
+
+func TestReplayInvalidLog(t *testing.T) {
+	g := chaingen.New(&chaincfg.RegressionNetParams)
+	lc := btclightclient.NewBTCLightClientWithData(g.Params(), []wire.BlockHeader{g.Genesis().Header()}, 0)
+	_, _, err := Replay(lc, strings.NewReader("{\"type\":\"insert\"}\nnot json\n"), nil)
+	assert.Assert(t, errors.Is(err, ErrNoOpenEvent), err)
+	assert.ErrorContains(t, err, "line 1")
+	_, _, err = Replay(lc, strings.NewReader("\nnot json\n"), nil)
+	assert.ErrorContains(t, err, "line 2")
+}
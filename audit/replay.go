@@ -0,0 +1,181 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
+)
+
+// ErrDiverged is returned when the replayed light client doesn't make the
+// state transitions of the audit log.
+var ErrDiverged = errors.New("replay diverged from the audit log")
+
+// ErrNoOpenEvent is returned when the audit log doesn't start with the open
+// event recording the starting state of the replay.
+var ErrNoOpenEvent = errors.New("audit log doesn't start with an open event")
+
+// ReplayStats counts the replayed events.
+type ReplayStats struct {
+	Events  int
+	Inserts int
+	// Inserted is the number of headers stored by the replay.
+	Inserted int
+	// Reopens is the number of restarts of the light client.
+	Reopens int
+}
+
+// Reopen returns the state of the light client lc after a restart of the
+// process writing the audit log, e.g. without the forks not saved.
+type Reopen func(lc *btclightclient.BTCLightClient) (*btclightclient.BTCLightClient, error)
+
+// recorder collects the events derived from the insertions.
+type recorder struct {
+	events []btclightclient.AuditEvent
+}
+
+func (r *recorder) LogEvent(ev btclightclient.AuditEvent) {
+	if ev.Type != btclightclient.AuditOpen && ev.Type != btclightclient.AuditInsert {
+		r.events = append(r.events, ev)
+	}
+}
+
+// Replay inserts the headers of the audit log read from r into lc, in order,
+// updating the fork choice after each stored header as the light client
+// writers do, and returns the resulting light client. lc must be loaded with
+// the blocks of the first open event, e.g. from a copy of the state file, and
+// the config used when the log was written: its store hash and state are
+// checked before any insertion. The next open events are restarts, the light
+// client is replaced by the one returned by reopen, a nil reopen keeps it.
+//
+// The replay checks lc makes the same state transitions: the outcome of each
+// insertion, the reorgs, checkpoints and pruned blocks following it, and the
+// state of the open events.
+func Replay(lc *btclightclient.BTCLightClient, r io.Reader, reopen Reopen) (*btclightclient.BTCLightClient, ReplayStats, error) {
+	var stats ReplayStats
+	rec := &recorder{}
+	lc.SetAuditLogger(rec)
+	defer func() { lc.SetAuditLogger(nil) }()
+
+	// logged are the events derived from the last insertion
+	var logged []btclightclient.AuditEvent
+	lastLine := 0
+	compare := func() error {
+		if err := compareDerived(logged, rec.events); err != nil {
+			return fmt.Errorf("line %d: %w", lastLine, err)
+		}
+		logged, rec.events = nil, nil
+		return nil
+	}
+
+	opened := false
+	err := Read(r, func(line int, ev btclightclient.AuditEvent) error {
+		stats.Events++
+		if !opened && ev.Type != btclightclient.AuditOpen {
+			return fmt.Errorf("line %d: %w", line, ErrNoOpenEvent)
+		}
+		switch ev.Type {
+		case btclightclient.AuditOpen:
+			if err := compare(); err != nil {
+				return err
+			}
+			lastLine = line
+			if opened && reopen != nil {
+				lc.SetAuditLogger(nil)
+				next, err := reopen(lc)
+				if err != nil {
+					return fmt.Errorf("line %d: reopen: %w", line, err)
+				}
+				lc = next
+				lc.SetAuditLogger(rec)
+				stats.Reopens++
+			}
+			opened = true
+			if err := checkOpen(lc, ev); err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+		case btclightclient.AuditInsert:
+			if err := compare(); err != nil {
+				return err
+			}
+			lastLine = line
+			stats.Inserts++
+			inserted, err := replayInsert(lc, ev)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+			if inserted {
+				stats.Inserted++
+			}
+		default:
+			logged = append(logged, ev)
+		}
+		return nil
+	})
+	if err != nil {
+		return lc, stats, err
+	}
+	return lc, stats, compare()
+}
+
+// checkOpen checks lc has the blocks and is in the state of the open event
+// ev.
+func checkOpen(lc *btclightclient.BTCLightClient, ev btclightclient.AuditEvent) error {
+	status, err := lc.ChainStatus()
+	if err != nil {
+		return err
+	}
+	if ev.StoreHash != nil {
+		storeHash, err := lc.StoreHash()
+		if err != nil {
+			return err
+		}
+		if storeHash != *ev.StoreHash {
+			return fmt.Errorf("%w: store hash %s, logged %s: the light client doesn't have the blocks of the log",
+				ErrDiverged, storeHash, ev.StoreHash)
+		}
+	}
+	if status.TipHash != ev.Hash ||
+		(ev.Checkpoint != nil && status.FinalizedHash != *ev.Checkpoint) ||
+		(ev.StateRoot != nil && status.StateRoot != *ev.StateRoot) {
+		return fmt.Errorf("%w: state changed outside of the log, tip %s and state root %s, logged tip %s and state root %v",
+			ErrDiverged, status.TipHash, status.StateRoot, ev.Hash, ev.StateRoot)
+	}
+	return nil
+}
+
+// replayInsert inserts the header of ev and checks its outcome, it reports
+// whether the header was stored.
+func replayInsert(lc *btclightclient.BTCLightClient, ev btclightclient.AuditEvent) (bool, error) {
+	header, err := btclightclient.BlockHeaderFromHex(ev.Header)
+	if err != nil {
+		return false, fmt.Errorf("header %s: %w", ev.Hash, err)
+	}
+	outcome, insertErr := lc.InsertHeaderFrom(ev.Source, header)
+	if ev.Outcome == nil || outcome != *ev.Outcome {
+		return false, fmt.Errorf("%w: header %s outcome %s (%v), logged %v", ErrDiverged, ev.Hash, outcome, insertErr, ev.Outcome)
+	}
+	if insertErr != nil || outcome == btclightclient.HeaderDuplicate {
+		return false, nil
+	}
+	return true, lc.CleanUpFork()
+}
+
+// compareDerived checks the events derived from an insertion match, in
+// order.
+func compareDerived(logged, replayed []btclightclient.AuditEvent) error {
+	keys := func(events []btclightclient.AuditEvent) []string {
+		ks := make([]string, len(events))
+		for i, ev := range events {
+			ks[i] = fmt.Sprintf("%s %s %d %v %d %s", ev.Type, ev.Hash, ev.Height, ev.OldTip, ev.Depth, ev.Reason)
+		}
+		return ks
+	}
+	want, got := keys(logged), keys(replayed)
+	if !slices.Equal(want, got) {
+		return fmt.Errorf("%w: derived events %q, logged %q", ErrDiverged, got, want)
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+package btclightclient
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// HeaderAccumulator is a Merkle mountain range over the hashes of the
+// finalized blocks, the leaf i is the block at height StartHeight() + i. It
+// is appended to as the checkpoint moves, and keeps the hashes of the blocks
+// pruned by the retention policy so that their proofs can still be built.
+//
+// The nodes are the double SHA-256 of their children, as in the Bitcoin
+// merkle trees. The peaks are the roots of the perfect trees of the range,
+// from the largest one.
+type HeaderAccumulator struct {
+	start int32
+	// levels[k] are the nodes of the perfect trees of 2^k leaves, levels[0]
+	// are the leaves.
+	levels [][]chainhash.Hash
+}
+
+// NewHeaderAccumulator returns the accumulator of the finalized blocks from
+// height start with the given hashes.
+func NewHeaderAccumulator(start int32, leaves []chainhash.Hash) *HeaderAccumulator {
+	a := &HeaderAccumulator{start: start}
+	for _, leaf := range leaves {
+		a.Append(leaf)
+	}
+	return a
+}
+
+// StartHeight returns the height of the first leaf.
+func (a *HeaderAccumulator) StartHeight() int32 {
+	return a.start
+}
+
+// Size returns the number of leaves.
+func (a *HeaderAccumulator) Size() int32 {
+	if len(a.levels) == 0 {
+		return 0
+	}
+	return int32(len(a.levels[0]))
+}
+
+// Leaves returns the hashes of the accumulated blocks.
+func (a *HeaderAccumulator) Leaves() []chainhash.Hash {
+	if len(a.levels) == 0 {
+		return nil
+	}
+	return a.levels[0]
+}
+
+// Append adds the hash of the block at height StartHeight() + Size().
+func (a *HeaderAccumulator) Append(hash chainhash.Hash) {
+	node := hash
+	for k := 0; ; k++ {
+		if k == len(a.levels) {
+			a.levels = append(a.levels, nil)
+		}
+		a.levels[k] = append(a.levels[k], node)
+		n := len(a.levels[k])
+		if n%2 == 1 {
+			return
+		}
+		node = hashNodes(a.levels[k][n-2], a.levels[k][n-1])
+	}
+}
+
+// peaks returns the peaks, from the largest tree.
+func (a *HeaderAccumulator) peaks() []chainhash.Hash {
+	peaks := []chainhash.Hash{}
+	for k := len(a.levels) - 1; k >= 0; k-- {
+		if n := len(a.levels[k]); n%2 == 1 {
+			peaks = append(peaks, a.levels[k][n-1])
+		}
+	}
+	return peaks
+}
+
+// Root returns the commitment to the accumulated blocks: the double SHA-256
+// of the start height and size as 4 bytes little endian integers, followed by
+// the peaks.
+func (a *HeaderAccumulator) Root() chainhash.Hash {
+	return accumulatorRoot(a.start, a.Size(), a.peaks())
+}
+
+// Prove returns the proof that the block at height is accumulated.
+func (a *HeaderAccumulator) Prove(height int32) (AncestorProof, error) {
+	size := a.Size()
+	if height < a.start || height >= a.start+size {
+		return AncestorProof{}, fmt.Errorf("%w: %d not in the accumulated heights [%d, %d)",
+			ErrHeightOutOfRange, height, a.start, a.start+size)
+	}
+	index := height - a.start
+	_, treeHeight, _ := peakOf(size, index)
+	siblings := make([]chainhash.Hash, treeHeight)
+	for k := range siblings {
+		siblings[k] = a.levels[k][(index>>k)^1]
+	}
+	return AncestorProof{
+		Height:      height,
+		StartHeight: a.start,
+		Size:        size,
+		Siblings:    siblings,
+		Peaks:       a.peaks(),
+	}, nil
+}
+
+// AncestorProof proves the block at Height is an ancestor of the checkpoint,
+// the last accumulated block, against the root of a HeaderAccumulator.
+type AncestorProof struct {
+	Height int32 `json:"height"`
+	// StartHeight and Size are the first height and the number of
+	// blocks of the accumulator.
+	StartHeight int32 `json:"start_height"`
+	Size        int32 `json:"size"`
+	// Siblings are the hashes from the block up to its peak.
+	Siblings []chainhash.Hash `json:"siblings"`
+	Peaks    []chainhash.Hash `json:"peaks"`
+}
+
+// VerifyAncestorProof checks the block with the given hash is accumulated at
+// proof.Height in the accumulator with the trusted root. It doesn't need a
+// light client.
+func VerifyAncestorProof(root chainhash.Hash, hash chainhash.Hash, proof AncestorProof) error {
+	if proof.Size <= 0 || proof.Height < proof.StartHeight || proof.Height-proof.StartHeight >= proof.Size {
+		return fmt.Errorf("%w: height %d not in the accumulated heights", ErrInvalidAncestorProof, proof.Height)
+	}
+	if len(proof.Peaks) != bits.OnesCount32(uint32(proof.Size)) {
+		return fmt.Errorf("%w: %d peaks for %d blocks", ErrInvalidAncestorProof, len(proof.Peaks), proof.Size)
+	}
+	index := proof.Height - proof.StartHeight
+	peak, treeHeight, local := peakOf(proof.Size, index)
+	if len(proof.Siblings) != treeHeight {
+		return fmt.Errorf("%w: %d siblings, expected %d", ErrInvalidAncestorProof, len(proof.Siblings), treeHeight)
+	}
+
+	node := hash
+	for k, sibling := range proof.Siblings {
+		if local>>k&1 == 0 {
+			node = hashNodes(node, sibling)
+		} else {
+			node = hashNodes(sibling, node)
+		}
+	}
+	if node != proof.Peaks[peak] {
+		return fmt.Errorf("%w: block %s doesn't match peak %d", ErrInvalidAncestorProof, hash, peak)
+	}
+	if accumulatorRoot(proof.StartHeight, proof.Size, proof.Peaks) != root {
+		return fmt.Errorf("%w: root mismatch", ErrInvalidAncestorProof)
+	}
+	return nil
+}
+
+// peakOf returns the tree of the leaf at index in a range of size leaves:
+// the index of its peak, the tree height and the leaf index in the tree.
+func peakOf(size, index int32) (peak, height int, local int32) {
+	offset := int32(0)
+	for k := 31; k >= 0; k-- {
+		leaves := int32(1) << k
+		if size&leaves == 0 {
+			continue
+		}
+		if index < offset+leaves {
+			return peak, k, index - offset
+		}
+		offset += leaves
+		peak++
+	}
+	return peak, 0, 0
+}
+
+func hashNodes(left, right chainhash.Hash) chainhash.Hash {
+	return chainhash.DoubleHashH(append(left[:], right[:]...))
+}
+
+func accumulatorRoot(start, size int32, peaks []chainhash.Hash) chainhash.Hash {
+	buf := binary.LittleEndian.AppendUint32(nil, uint32(start))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(size))
+	for _, peak := range peaks {
+		buf = append(buf, peak[:]...)
+	}
+	return chainhash.DoubleHashH(buf)
+}
+
+// accumulate appends the blocks finalized since the last call to the header
+// accumulator. The first call accumulates all the finalized blocks in the
+// store. When the finalized chain doesn't continue the accumulator, e.g. its
+// next blocks were pruned, the accumulator restarts from the stored blocks
+// and an ErrInvalidAccumulator error is returned.
+func (lc *BTCLightClient) accumulate() error {
+	checkpoint := lc.btcStore.LatestCheckPoint()
+	if checkpoint == nil {
+		return nil
+	}
+	// The checkpoint can move by several blocks, we follow the parents
+	// as the blocks between the checkpoints aren't indexed by height.
+	from := int32(0)
+	if lc.headerAcc != nil {
+		from = lc.headerAcc.StartHeight() + lc.headerAcc.Size()
+	}
+	blocks := lc.chainDownTo(checkpoint, from)
+	if len(blocks) == 0 {
+		return nil
+	}
+	first := blocks[len(blocks)-1]
+	var err error
+	if lc.headerAcc != nil && (first.Height != from || first.Header.PrevBlock != lc.headerAcc.Leaves()[lc.headerAcc.Size()-1]) {
+		err = fmt.Errorf("%w: block %s at height %d doesn't extend it, restarted from height %d",
+			ErrInvalidAccumulator, first.Header.BlockHash(), first.Height, first.Height)
+		lc.headerAcc = nil
+	}
+	if lc.headerAcc == nil {
+		lc.headerAcc = NewHeaderAccumulator(first.Height, nil)
+	}
+	for i := len(blocks) - 1; i >= 0; i-- {
+		lc.headerAcc.Append(blocks[i].Header.BlockHash())
+	}
+	return err
+}
+
+// chainDownTo returns lb and its parents down to height, from the highest
+// block. It stops at the first parent missing from the store.
+func (lc *BTCLightClient) chainDownTo(lb *LightBlock, height int32) []*LightBlock {
+	blocks := []*LightBlock{}
+	for lb != nil && lb.Height >= height {
+		blocks = append(blocks, lb)
+		lb = lc.btcStore.LightBlockByHash(lb.Header.PrevBlock)
+	}
+	return blocks
+}
+
+// HeaderAccumulator returns the accumulator of the finalized blocks, the last
+// one is the checkpoint.
+func (lc *BTCLightClient) HeaderAccumulator() *HeaderAccumulator {
+	return lc.headerAcc
+}
+
+// SetHeaderAccumulator replaces the header accumulator, e.g. with one saved
+// before a restart which covers the pruned blocks. acc must end with a
+// finalized block in the store, the blocks finalized after it are appended.
+func (lc *BTCLightClient) SetHeaderAccumulator(acc *HeaderAccumulator) error {
+	if err := lc.CheckStore(); err != nil {
+		return err
+	}
+	size := acc.Size()
+	if size == 0 {
+		return fmt.Errorf("%w: no blocks", ErrInvalidAccumulator)
+	}
+	last := acc.StartHeight() + size - 1
+	blocks := lc.chainDownTo(lc.btcStore.LatestCheckPoint(), last)
+	if len(blocks) == 0 || blocks[len(blocks)-1].Height != last {
+		return fmt.Errorf("%w: no finalized block at height %d", ErrInvalidAccumulator, last)
+	}
+	if hash := blocks[len(blocks)-1].Header.BlockHash(); hash != acc.Leaves()[size-1] {
+		return fmt.Errorf("%w: block %s at height %d", ErrInvalidAccumulator, hash, last)
+	}
+	lc.headerAcc = acc
+	return lc.accumulate()
+}
+
+// AncestorProof returns the hash of the finalized block at height and the
+// proof that it is an ancestor of the checkpoint, checked by
+// VerifyAncestorProof against the HeaderAccumulator root. Pruned blocks can
+// be proven as long as they are accumulated.
+func (lc *BTCLightClient) AncestorProof(height int32) (chainhash.Hash, AncestorProof, error) {
+	if err := lc.CheckStore(); err != nil {
+		return chainhash.Hash{}, AncestorProof{}, err
+	}
+	if lc.headerAcc == nil {
+		return chainhash.Hash{}, AncestorProof{}, ErrStoreUnavailable
+	}
+	proof, err := lc.headerAcc.Prove(height)
+	if err != nil {
+		return chainhash.Hash{}, AncestorProof{}, err
+	}
+	return lc.headerAcc.Leaves()[height-lc.headerAcc.StartHeight()], proof, nil
+}
@@ -0,0 +1,178 @@
+package btclightclient
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/gonative-cc/bitcoin-lightclient/chaingen"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"gotest.tools/assert"
+)
+
+func accumulatorLeaves(n int) []chainhash.Hash {
+	leaves := make([]chainhash.Hash, n)
+	for i := range leaves {
+		leaves[i] = chainhash.DoubleHashH(binary.LittleEndian.AppendUint32(nil, uint32(i)))
+	}
+	return leaves
+}
+
+func blockHashes(blocks []*chaingen.Block) []chainhash.Hash {
+	hashes := make([]chainhash.Hash, len(blocks))
+	for i, b := range blocks {
+		hashes[i] = b.Hash()
+	}
+	return hashes
+}
+
+func TestHeaderAccumulator(t *testing.T) {
+	leaves := accumulatorLeaves(33)
+	acc := NewHeaderAccumulator(100, nil)
+	roots := map[chainhash.Hash]bool{}
+	for size := 1; size <= len(leaves); size++ {
+		acc.Append(leaves[size-1])
+		assert.Equal(t, acc.Size(), int32(size))
+		root := acc.Root()
+		assert.Assert(t, !roots[root], "size %d", size)
+		roots[root] = true
+		assert.Equal(t, NewHeaderAccumulator(100, leaves[:size]).Root(), root)
+
+		for i, leaf := range leaves[:size] {
+			proof, err := acc.Prove(100 + int32(i))
+			assert.NilError(t, err)
+			assert.NilError(t, VerifyAncestorProof(root, leaf, proof), "size %d, leaf %d", size, i)
+		}
+	}
+	// the start height is committed to
+	assert.Assert(t, NewHeaderAccumulator(101, leaves).Root() != acc.Root())
+
+	_, err := acc.Prove(99)
+	assert.Assert(t, errors.Is(err, ErrHeightOutOfRange), err)
+	_, err = acc.Prove(133)
+	assert.Assert(t, errors.Is(err, ErrHeightOutOfRange), err)
+
+	root := acc.Root()
+	proof, err := acc.Prove(110)
+	assert.NilError(t, err)
+	invalid := map[string]func() (chainhash.Hash, chainhash.Hash, AncestorProof){
+		"other block": func() (chainhash.Hash, chainhash.Hash, AncestorProof) {
+			return root, leaves[11], proof
+		},
+		"other root": func() (chainhash.Hash, chainhash.Hash, AncestorProof) {
+			return leaves[0], leaves[10], proof
+		},
+		"other height": func() (chainhash.Hash, chainhash.Hash, AncestorProof) {
+			p := proof
+			p.Height++
+			return root, leaves[10], p
+		},
+		"height out of range": func() (chainhash.Hash, chainhash.Hash, AncestorProof) {
+			p := proof
+			p.Height = p.StartHeight + p.Size
+			return root, leaves[10], p
+		},
+		"missing sibling": func() (chainhash.Hash, chainhash.Hash, AncestorProof) {
+			p := proof
+			p.Siblings = p.Siblings[1:]
+			return root, leaves[10], p
+		},
+		"missing peak": func() (chainhash.Hash, chainhash.Hash, AncestorProof) {
+			p := proof
+			p.Peaks = p.Peaks[:1]
+			return root, leaves[10], p
+		},
+		"other size": func() (chainhash.Hash, chainhash.Hash, AncestorProof) {
+			p, err := NewHeaderAccumulator(100, leaves[:32]).Prove(110)
+			assert.NilError(t, err)
+			return root, leaves[10], p
+		},
+	}
+	for name, f := range invalid {
+		root, hash, proof := f()
+		err := VerifyAncestorProof(root, hash, proof)
+		assert.Assert(t, errors.Is(err, ErrInvalidAncestorProof), "%s: %v", name, err)
+	}
+}
+
+func TestLightClientAncestorProof(t *testing.T) {
+	params := retentionParams()
+	g := chaingen.New(params)
+	blocks := chaingen.Chain(g.Extend(g.Genesis(), 195)[194])
+	policy := RetentionPolicy{Mode: RetainLastFinalized, KeepFinalized: 5}
+	lc := NewBTCLightClientWithData(params, chaingen.Headers(blocks), 0, WithRetention(policy))
+	checkpoint := lc.btcStore.LatestCheckPoint().Height
+	assert.Equal(t, checkpoint, int32(188))
+	assert.Equal(t, lc.HeaderAccumulator().Root(), NewHeaderAccumulator(0, blockHashes(blocks[:189])).Root())
+
+	// the checkpoint moves 13 blocks on a fork, the pruned blocks can still
+	// be proven
+	fork := g.Extend(blocks[checkpoint], 20, chaingen.WithTimeDelta(params.TargetTimePerBlock/4))
+	insertBlocks(t, lc, fork)
+	assert.Equal(t, lc.btcStore.LatestCheckPoint().Height, int32(201))
+	finalized := append(blockHashes(blocks[:189]), blockHashes(fork[:13])...)
+	status, err := lc.ChainStatus()
+	assert.NilError(t, err)
+	assert.Equal(t, status.AccumulatorRoot, NewHeaderAccumulator(0, finalized).Root())
+	for _, height := range []int32{0, 10, 188, 195, 201} {
+		hash, proof, err := lc.AncestorProof(height)
+		assert.NilError(t, err)
+		assert.Equal(t, hash, finalized[height])
+		assert.NilError(t, VerifyAncestorProof(status.AccumulatorRoot, hash, proof))
+	}
+	_, _, err = lc.AncestorProof(202)
+	assert.Assert(t, errors.Is(err, ErrHeightOutOfRange), err)
+
+	// a light client bootstrapped from recent headers accumulates from its
+	// first header, until it is given the older accumulator
+	recent := NewBTCLightClientWithData(params, chaingen.Headers(blocks[150:]), 150)
+	assert.Equal(t, recent.HeaderAccumulator().StartHeight(), int32(150))
+	_, _, err = recent.AncestorProof(10)
+	assert.Assert(t, errors.Is(err, ErrHeightOutOfRange), err)
+	err = recent.SetHeaderAccumulator(NewHeaderAccumulator(0, blockHashes(blocks[:150])))
+	assert.Assert(t, errors.Is(err, ErrInvalidAccumulator), err)
+	err = recent.SetHeaderAccumulator(NewHeaderAccumulator(0, blockHashes(blocks[:190])))
+	assert.Assert(t, errors.Is(err, ErrInvalidAccumulator), err)
+	err = recent.SetHeaderAccumulator(NewHeaderAccumulator(1, blockHashes(blocks[:160])))
+	assert.Assert(t, errors.Is(err, ErrInvalidAccumulator), err)
+	assert.Equal(t, recent.HeaderAccumulator().StartHeight(), int32(150))
+
+	assert.NilError(t, recent.SetHeaderAccumulator(NewHeaderAccumulator(0, blockHashes(blocks[:160]))))
+	assert.Equal(t, recent.HeaderAccumulator().Root(), NewHeaderAccumulator(0, blockHashes(blocks[:189])).Root())
+	hash, proof, err := recent.AncestorProof(10)
+	assert.NilError(t, err)
+	assert.Equal(t, hash, blocks[10].Hash())
+	assert.NilError(t, VerifyAncestorProof(recent.HeaderAccumulator().Root(), hash, proof))
+}
+
+func TestAccumulatorRestart(t *testing.T) {
+	params := retentionParams()
+	g := chaingen.New(params)
+	blocks := chaingen.Chain(g.Extend(g.Genesis(), 195)[194])
+	policy := RetentionPolicy{Mode: RetainLastFinalized, KeepFinalized: 5}
+	lc := NewBTCLightClientWithData(params, chaingen.Headers(blocks), 0, WithRetention(policy))
+
+	// the blocks after the accumulator were pruned, it restarts from the
+	// oldest stored block
+	lc.headerAcc = NewHeaderAccumulator(0, blockHashes(blocks[:100]))
+	next := g.Extend(blocks[195], 2)
+	assert.NilError(t, lc.InsertHeader(next[0].Header()))
+	err := lc.CleanUpFork()
+	assert.Assert(t, errors.Is(err, ErrInvalidAccumulator), err)
+	checkpoint := lc.btcStore.LatestCheckPoint().Height
+	assert.Equal(t, checkpoint, int32(189))
+	start := lc.HeaderAccumulator().StartHeight()
+	assert.Assert(t, start > 100)
+	assert.Equal(t, lc.HeaderAccumulator().Root(), NewHeaderAccumulator(start, blockHashes(blocks[start:190])).Root())
+	_, _, err = lc.AncestorProof(checkpoint)
+	assert.NilError(t, err)
+
+	// an accumulator of another chain restarts from the next block
+	lc.headerAcc = NewHeaderAccumulator(start, accumulatorLeaves(int(checkpoint-start+1)))
+	assert.NilError(t, lc.InsertHeader(next[1].Header()))
+	err = lc.CleanUpFork()
+	assert.Assert(t, errors.Is(err, ErrInvalidAccumulator), err)
+	assert.Equal(t, lc.HeaderAccumulator().Root(), NewHeaderAccumulator(190, blockHashes(blocks[190:191])).Root())
+	assert.NilError(t, lc.CleanUpFork())
+}
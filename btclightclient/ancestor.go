@@ -0,0 +1,151 @@
+package btclightclient
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// invertLowestOne turns the lowest set bit of n off.
+func invertLowestOne(n int32) int32 {
+	return n & (n - 1)
+}
+
+// skipHeight is the height of the skip ancestor of a block at height, as in
+// Bitcoin Core. Any ancestor can be reached in O(log n) skips.
+func skipHeight(height int32) int32 {
+	if height < 2 {
+		return 0
+	}
+	// Odd heights skip to an ancestor before the even ones, otherwise the
+	// walk would jump between the same heights.
+	if height&1 == 1 {
+		return invertLowestOne(invertLowestOne(height-1)) + 1
+	}
+	return invertLowestOne(height)
+}
+
+// buildSkip sets the skip ancestor of lb, when its parent is in the store.
+func (lb *LightBlock) buildSkip(store Store) {
+	parent := store.LightBlockByHash(lb.Header.PrevBlock)
+	if parent == nil {
+		return
+	}
+	if skip := parent.ancestor(store, skipHeight(lb.Height)); skip != nil {
+		skipHash := skip.Header.BlockHash()
+		lb.skip = &skipHash
+	}
+}
+
+// ancestor returns the ancestor of lb at height, following the skip list. It
+// returns nil when the height is out of range or the ancestor is not in the
+// store.
+func (lb *LightBlock) ancestor(store Store, height int32) *LightBlock {
+	if height < 0 || height > lb.Height {
+		return nil
+	}
+
+	walk := lb
+	for walk != nil && walk.Height > height {
+		heightSkip := skipHeight(walk.Height)
+		heightSkipPrev := skipHeight(walk.Height - 1)
+		// Only follow the skip if the parent skip isn't a better
+		// shortcut.
+		if walk.skip != nil && (heightSkip == height ||
+			(heightSkip > height && !(heightSkipPrev < heightSkip-2 && heightSkipPrev >= height))) {
+			walk = store.LightBlockByHash(*walk.skip)
+		} else {
+			walk = store.LightBlockByHash(walk.Header.PrevBlock)
+		}
+	}
+	return walk
+}
+
+// AncestorAt returns the ancestor at height of the block with the given hash,
+// finalized or on any fork.
+func (lc *BTCLightClient) AncestorAt(hash chainhash.Hash, height int32) (*LightBlock, error) {
+	lb := lc.btcStore.LightBlockByHash(hash)
+	if lb == nil {
+		return nil, ErrBlockNotInChain
+	}
+	if height < 0 || height > lb.Height {
+		return nil, fmt.Errorf("%w: height %d, block %s is at height %d",
+			ErrHeightOutOfRange, height, hash, lb.Height)
+	}
+	ancestor := lc.ancestorOf(lb, height)
+	if ancestor == nil {
+		return nil, fmt.Errorf("%w: no block at height %d before %s", ErrMissingAncestor, height, hash)
+	}
+	return ancestor, nil
+}
+
+// ancestorOf returns the ancestor of lb at height. All the stored blocks are
+// finalized or descend from the checkpoint, so finalized ancestors are looked
+// up by height, the others with the skip list.
+func (lc *BTCLightClient) ancestorOf(lb *LightBlock, height int32) *LightBlock {
+	if int64(height) <= lc.btcStore.LatestFinalizedHeight() {
+		return lc.btcStore.LightBlockAtHeight(int64(height))
+	}
+	return lb.ancestor(lc.btcStore, height)
+}
+
+// BlockLocator returns the block locator of the block with the given hash,
+// as used by the getheaders P2P message: the hashes of the block and its 11
+// parents, then of ancestors exponentially further apart, down to the
+// genesis block. Ancestors the light client doesn't have, older than its
+// first header or pruned, are skipped and the locator ends with the network
+// genesis hash.
+func (lc *BTCLightClient) BlockLocator(hash chainhash.Hash) (blockchain.BlockLocator, error) {
+	lb := lc.btcStore.LightBlockByHash(hash)
+	if lb == nil {
+		return nil, ErrBlockNotInChain
+	}
+
+	locator := blockchain.BlockLocator{}
+	step := int32(1)
+	for height, entries := lb.Height, 1; ; entries++ {
+		if ancestor := lc.ancestorOf(lb, height); ancestor != nil {
+			ancestorHash := ancestor.Header.BlockHash()
+			locator = append(locator, &ancestorHash)
+		}
+		if height == 0 {
+			break
+		}
+		height = max(height-step, 0)
+		if entries > 10 {
+			step *= 2
+		}
+	}
+	if last := locator[len(locator)-1]; !last.IsEqual(lc.params.GenesisHash) {
+		locator = append(locator, lc.params.GenesisHash)
+	}
+	return locator, nil
+}
+
+// BlockByHash returns the stored block with the given hash, finalized or on
+// any fork.
+func (lc *BTCLightClient) BlockByHash(hash chainhash.Hash) (*LightBlock, error) {
+	lb := lc.btcStore.LightBlockByHash(hash)
+	if lb == nil {
+		return nil, ErrBlockNotInChain
+	}
+	return lb, nil
+}
+
+// BlockAtHeight returns the block at height of the most difficult fork.
+func (lc *BTCLightClient) BlockAtHeight(height int32) (*LightBlock, error) {
+	if err := lc.CheckStore(); err != nil {
+		return nil, err
+	}
+	return lc.AncestorAt(lc.btcStore.MostDifficultFork().Header.BlockHash(), height)
+}
+
+// IsFinalized reports whether lb is on the finalized chain.
+func (lc *BTCLightClient) IsFinalized(lb *LightBlock) bool {
+	if int64(lb.Height) > lc.btcStore.LatestFinalizedHeight() {
+		return false
+	}
+	finalized := lc.btcStore.LightBlockAtHeight(int64(lb.Height))
+	return finalized != nil && finalized.Header.BlockHash() == lb.Header.BlockHash()
+}
@@ -0,0 +1,192 @@
+package btclightclient
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gonative-cc/bitcoin-lightclient/chaingen"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"gotest.tools/assert"
+)
+
+// countingStore counts the blocks looked up by hash.
+type countingStore struct {
+	Store
+	lookups int
+}
+
+func (s *countingStore) LightBlockByHash(hash chainhash.Hash) *LightBlock {
+	s.lookups++
+	return s.Store.LightBlockByHash(hash)
+}
+
+func TestSkipHeight(t *testing.T) {
+	for height := int32(0); height < 10000; height++ {
+		skip := skipHeight(height)
+		assert.Assert(t, skip >= 0)
+		if height >= 2 {
+			assert.Assert(t, skip < height-1 || height < 3, "height %d skip %d", height, skip)
+		}
+	}
+	assert.Equal(t, skipHeight(1024), int32(0))
+	assert.Equal(t, skipHeight(1025), int32(1))
+	assert.Equal(t, skipHeight(1000), int32(992))
+}
+
+func TestAncestorAt(t *testing.T) {
+	g := chaingen.New(&chaincfg.RegressionNetParams)
+	main := chaingen.Chain(g.Extend(g.Genesis(), 1000)[999])
+	lc := NewBTCLightClientWithData(g.Params(), chaingen.Headers(main[:996]), 0)
+	// the fork is not finalized
+	fork := g.Extend(main[993], 3)
+	insertBlocks(t, lc, fork)
+	insertBlocks(t, lc, main[996:])
+
+	for _, tip := range []*chaingen.Block{main[1000], fork[2]} {
+		for height := int32(0); height <= tip.Height; height++ {
+			ancestor, err := lc.AncestorAt(tip.Hash(), height)
+			assert.NilError(t, err)
+			assert.Equal(t, ancestor.Height, height)
+			assert.Equal(t, ancestor.Header.BlockHash(), tip.Ancestor(height).Hash())
+		}
+	}
+
+	store := &countingStore{Store: lc.btcStore}
+	lc.btcStore = store
+	for height := int32(0); height <= 1000; height++ {
+		store.lookups = 0
+		_, err := lc.AncestorAt(main[1000].Hash(), height)
+		assert.NilError(t, err)
+		assert.Assert(t, store.lookups <= 40, "%d lookups for height %d", store.lookups, height)
+	}
+
+	_, err := lc.AncestorAt(main[1000].Hash(), 1001)
+	assert.Assert(t, errors.Is(err, ErrHeightOutOfRange), err)
+	_, err = lc.AncestorAt(main[1000].Hash(), -1)
+	assert.Assert(t, errors.Is(err, ErrHeightOutOfRange), err)
+	_, err = lc.AncestorAt(chainhash.Hash{}, 0)
+	assert.Assert(t, errors.Is(err, ErrBlockNotInChain), err)
+
+	// blocks before the first header are not known
+	lc = NewBTCLightClientWithData(g.Params(), chaingen.Headers(main[500:]), 500)
+	ancestor, err := lc.AncestorAt(main[1000].Hash(), 500)
+	assert.NilError(t, err)
+	assert.Equal(t, ancestor.Header.BlockHash(), main[500].Hash())
+	_, err = lc.AncestorAt(main[1000].Hash(), 499)
+	assert.Assert(t, errors.Is(err, ErrMissingAncestor), err)
+}
+
+func TestCleanUpForkLookups(t *testing.T) {
+	g := chaingen.New(&chaincfg.RegressionNetParams)
+	lc := NewBTCLightClientWithData(g.Params(), chaingen.Headers([]*chaingen.Block{g.Genesis()}), 0,
+		WithFinalityDepth(500))
+	// a fork and the best chain as old as the finality depth
+	best := g.Extend(g.Genesis(), 499)
+	fork := g.Extend(best[0], 497)
+	insertBlocks(t, lc, best)
+	insertBlocks(t, lc, fork)
+	assert.Equal(t, lc.btcStore.LatestCheckPoint().Height, int32(0))
+
+	// the forks are checked with the skip list, not walked down to the
+	// checkpoint
+	store := &countingStore{Store: lc.btcStore}
+	lc.btcStore = store
+	next := g.Extend(best[498], 2)
+	insertBlocks(t, lc, next[:1])
+	assert.Assert(t, store.lookups <= 100, "%d lookups", store.lookups)
+	assert.Equal(t, lc.btcStore.LatestCheckPoint().Header.BlockHash(), best[0].Hash())
+	assert.Assert(t, lc.btcStore.IsForkHead(fork[496].Hash()))
+
+	// the fork not descending from the new checkpoint is removed
+	insertBlocks(t, lc, next[1:])
+	assert.Equal(t, lc.btcStore.LatestCheckPoint().Header.BlockHash(), best[1].Hash())
+	assert.Assert(t, lc.btcStore.LightBlockByHash(fork[0].Hash()) == nil)
+	assert.Assert(t, !lc.btcStore.IsForkHead(fork[496].Hash()))
+}
+
+func TestBlockLocator(t *testing.T) {
+	g := chaingen.New(&chaincfg.RegressionNetParams)
+	main := chaingen.Chain(g.Extend(g.Genesis(), 1000)[999])
+	lc := NewBTCLightClientWithData(g.Params(), chaingen.Headers(main[:996]), 0)
+	fork := g.Extend(main[993], 3)
+	insertBlocks(t, lc, fork)
+
+	locatorHeights := func(tip int32) []int32 {
+		heights := []int32{}
+		for h := tip; h > tip-12 && h >= 0; h-- {
+			heights = append(heights, h)
+		}
+		for step := int32(2); heights[len(heights)-1] > 0; step *= 2 {
+			heights = append(heights, max(heights[len(heights)-1]-step, 0))
+		}
+		return heights
+	}
+
+	for _, tip := range []*chaingen.Block{main[995], fork[2], main[5]} {
+		locator, err := lc.BlockLocator(tip.Hash())
+		assert.NilError(t, err)
+		heights := locatorHeights(tip.Height)
+		assert.Equal(t, len(locator), len(heights))
+		for i, h := range heights {
+			assert.Equal(t, *locator[i], tip.Ancestor(h).Hash(), "entry %d", i)
+		}
+	}
+
+	// the locator of a client started after the genesis block ends with the
+	// network genesis hash
+	lc = NewBTCLightClientWithData(g.Params(), chaingen.Headers(main[500:]), 500)
+	locator, err := lc.BlockLocator(main[1000].Hash())
+	assert.NilError(t, err)
+	heights := locatorHeights(1000)
+	known := 0
+	for heights[known] >= 500 {
+		assert.Equal(t, *locator[known], main[heights[known]].Hash())
+		known++
+	}
+	assert.Equal(t, len(locator), known+1)
+	assert.Equal(t, *locator[known], *g.Params().GenesisHash)
+
+	_, err = lc.BlockLocator(chainhash.Hash{})
+	assert.Assert(t, errors.Is(err, ErrBlockNotInChain), err)
+}
+
+func TestBlockLookup(t *testing.T) {
+	lc, main, fork := consistencyLightClient(t)
+
+	lb, err := lc.BlockByHash(main[20].Hash())
+	assert.NilError(t, err)
+	assert.Equal(t, lb.Height, int32(20))
+	assert.Assert(t, !lc.IsFinalized(lb))
+	_, err = lc.BlockByHash(chainhash.Hash{})
+	assert.Assert(t, errors.Is(err, ErrBlockNotInChain), err)
+
+	// the heights are looked up on the best chain
+	lb, err = lc.BlockAtHeight(20)
+	assert.NilError(t, err)
+	assert.Equal(t, lb.Header.BlockHash(), fork[0].Hash())
+	lb, err = lc.BlockAtHeight(13)
+	assert.NilError(t, err)
+	assert.Equal(t, lb.Header.BlockHash(), main[13].Hash())
+	assert.Assert(t, lc.IsFinalized(lb))
+	_, err = lc.BlockAtHeight(22)
+	assert.Assert(t, errors.Is(err, ErrHeightOutOfRange), err)
+}
+
+func TestForkHeads(t *testing.T) {
+	lc, main, fork := consistencyLightClient(t)
+	heads, err := lc.ForkHeads()
+	assert.NilError(t, err)
+	assert.Equal(t, len(heads), 2)
+	assert.Equal(t, heads[0].Header.BlockHash(), fork[1].Hash())
+	assert.Equal(t, heads[1].Header.BlockHash(), main[20].Hash())
+	// main[19] is on the best chain
+	blocks, err := lc.ForkBlocks()
+	assert.NilError(t, err)
+	assert.Equal(t, len(blocks), 1)
+	assert.Equal(t, blocks[0].Header.BlockHash(), main[20].Hash())
+
+	_, err = NewBTCLightClient(&chaincfg.RegressionNetParams).ForkHeads()
+	assert.Assert(t, errors.Is(err, ErrStoreUnavailable), err)
+}
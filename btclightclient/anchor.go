@@ -0,0 +1,117 @@
+package btclightclient
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// MedianTimeBlocks is the number of previous blocks used to compute the
+// median time past a new header timestamp must be after.
+const MedianTimeBlocks = 11
+
+// TrustedAnchor is a block the light client trusts without validation. It
+// lets the light client start from any height, e.g. close to the mainnet tip,
+// instead of syncing from genesis.
+type TrustedAnchor struct {
+	Height int32
+	Hash   chainhash.Hash
+	Header wire.BlockHeader
+	// ChainWork is the cumulative work of the chain up to and including the
+	// anchor block, as reported by bitcoind getblockheader "chainwork".
+	ChainWork *big.Int
+	// Ancestors are the headers before the anchor, ordered by height and
+	// ending with the anchor parent. They must reach back to the first
+	// block of the anchor retarget period and contain at least the last
+	// MedianTimeBlocks headers, so the headers after the anchor can be
+	// validated.
+	Ancestors []wire.BlockHeader
+}
+
+// RequiredAncestors returns the minimum number of ancestors the anchor needs
+// to validate the headers built on top of it.
+func RequiredAncestors(params *chaincfg.Params, height int32) int32 {
+	required := int32(MedianTimeBlocks)
+	if !params.PoWNoRetargeting {
+		// the first block of the retarget period is needed to compute
+		// the next difficulty.
+		blocksPerRetarget := blocksPerRetarget(params)
+		required = max(required, height%blocksPerRetarget)
+	}
+	return min(required, height)
+}
+
+// Validate checks the anchor is consistent: the hash matches the header, the
+// ancestors build a chain to the anchor and there are enough of them.
+func (a TrustedAnchor) Validate(params *chaincfg.Params) error {
+	if a.Height < 0 {
+		return fmt.Errorf("%w: negative height %d", ErrInvalidAnchor, a.Height)
+	}
+	if a.Header.BlockHash() != a.Hash {
+		return fmt.Errorf("%w: header hash %s doesn't match %s", ErrInvalidAnchor, a.Header.BlockHash(), a.Hash)
+	}
+	if a.ChainWork == nil || a.ChainWork.Sign() <= 0 {
+		return fmt.Errorf("%w: chain work must be positive", ErrInvalidAnchor)
+	}
+	if required := RequiredAncestors(params, a.Height); int32(len(a.Ancestors)) < required {
+		return fmt.Errorf("%w: need %d ancestors, got %d", ErrInvalidAnchor, required, len(a.Ancestors))
+	}
+	if int32(len(a.Ancestors)) > a.Height {
+		return fmt.Errorf("%w: %d ancestors for height %d", ErrInvalidAnchor, len(a.Ancestors), a.Height)
+	}
+
+	chain := append(a.Ancestors[:len(a.Ancestors):len(a.Ancestors)], a.Header)
+	work := big.NewInt(0)
+	for i, header := range chain {
+		if i > 0 && header.PrevBlock != chain[i-1].BlockHash() {
+			return fmt.Errorf("%w: %s", ErrHeaderNotLinked, header.BlockHash())
+		}
+		if err := blockchain.CheckBlockHeaderSanity(&header, params.PowLimit, newBlockMedianTimeSource(&header), blockchain.BFNone); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidAnchor, err)
+		}
+		work.Add(work, blockchain.CalcWork(header.Bits))
+	}
+	if a.ChainWork.Cmp(work) < 0 {
+		return fmt.Errorf("%w: chain work %s is less than the work of the anchor headers", ErrInvalidAnchor, a.ChainWork)
+	}
+	return nil
+}
+
+// NewBTCLightClientFromAnchor creates a light client starting at a trusted
+// anchor. The anchor and its ancestors are finalized, the anchor is the
+// checkpoint and the only fork head. The total work of each block is
+// derived from the anchor chain work, so it matches the real chain work.
+func NewBTCLightClientFromAnchor(params *chaincfg.Params, anchor TrustedAnchor, opts ...Option) (*BTCLightClient, error) {
+	if err := anchor.Validate(params); err != nil {
+		return nil, err
+	}
+
+	chain := append(anchor.Ancestors[:len(anchor.Ancestors):len(anchor.Ancestors)], anchor.Header)
+	// chain work of the parent of the first stored block
+	baseWork := new(big.Int).Set(anchor.ChainWork)
+	for _, header := range chain {
+		baseWork.Sub(baseWork, blockchain.CalcWork(header.Bits))
+	}
+
+	lc := NewBTCLightClient(params, opts...)
+	startHeight := anchor.Height - int32(len(anchor.Ancestors))
+	previousPower := baseWork
+	var lb *LightBlock
+	for i, header := range chain {
+		lb = NewLightBlock(startHeight+int32(i), header)
+		lc.btcStore.SetBlock(lb, previousPower)
+		lc.btcStore.SetLightBlockByHeight(lb)
+		previousPower = lc.btcStore.TotalWorkAtBlock(header.BlockHash())
+	}
+	lc.btcStore.SetLatestCheckPoint(lb)
+	lc.btcStore.SetIsHead(lb.Header.BlockHash())
+	lc.prunedHeight = startHeight
+	if err := lc.finalized(); err != nil {
+		return nil, err
+	}
+	return lc, nil
+}
@@ -0,0 +1,104 @@
+package btclightclient
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"gotest.tools/assert"
+)
+
+func testAnchor(t *testing.T) TrustedAnchor {
+	headers := make([]wire.BlockHeader, len(HEADERS))
+	for i, s := range HEADERS {
+		h, err := BlockHeaderFromHex(s)
+		assert.NilError(t, err)
+		headers[i] = h
+	}
+
+	last := len(headers) - 1
+	chainWork, _ := new(big.Int).SetString("10000000000", 16)
+	return TrustedAnchor{
+		Height:    int32(last),
+		Hash:      headers[last].BlockHash(),
+		Header:    headers[last],
+		ChainWork: chainWork,
+		Ancestors: headers[last-MedianTimeBlocks : last],
+	}
+}
+
+func TestRequiredAncestors(t *testing.T) {
+	assert.Equal(t, RequiredAncestors(&chaincfg.RegressionNetParams, 5000), int32(MedianTimeBlocks))
+	assert.Equal(t, RequiredAncestors(&chaincfg.RegressionNetParams, 3), int32(3))
+	assert.Equal(t, RequiredAncestors(&chaincfg.MainNetParams, 2016*400+1000), int32(1000))
+	assert.Equal(t, RequiredAncestors(&chaincfg.MainNetParams, 2016*400+2), int32(MedianTimeBlocks))
+	assert.Equal(t, RequiredAncestors(&chaincfg.MainNetParams, 2016*400+2015), int32(2015))
+}
+
+func TestNewBTCLightClientFromAnchor(t *testing.T) {
+	anchor := testAnchor(t)
+	lc, err := NewBTCLightClientFromAnchor(&chaincfg.RegressionNetParams, anchor)
+	assert.NilError(t, err)
+
+	assert.Equal(t, lc.LatestFinalizedBlockHash(), anchor.Hash)
+	assert.Equal(t, lc.LatestFinalizedBlockHeight(), int64(anchor.Height))
+	assert.Equal(t, lc.TotalWorkAtBlock(anchor.Hash).Cmp(anchor.ChainWork), 0)
+	assert.Assert(t, lc.btcStore.IsForkHead(anchor.Hash))
+	first := lc.btcStore.LightBlockAtHeight(int64(anchor.Height) - MedianTimeBlocks)
+	assert.Equal(t, first.Header.BlockHash(), anchor.Ancestors[0].BlockHash())
+	assert.Equal(t, len(lc.MainChain()), MedianTimeBlocks+1)
+
+	// headers on top of the anchor are validated with the anchor context
+	header, err := BlockHeaderFromHex(CommonTestCases()["Append a fork"].header)
+	assert.NilError(t, err)
+	assert.NilError(t, lc.InsertHeader(header))
+	want := new(big.Int).Add(anchor.ChainWork, blockchain.CalcWork(header.Bits))
+	assert.Equal(t, lc.TotalWorkAtBlock(header.BlockHash()).Cmp(want), 0)
+
+	// forks from the anchor parent are rejected
+	header, err = BlockHeaderFromHex(CommonTestCases()["Create fork"].header)
+	assert.NilError(t, err)
+	assert.Equal(t, lc.InsertHeader(header), ErrForkTooOld)
+}
+
+func TestInvalidAnchor(t *testing.T) {
+	tcs := map[string]func(a *TrustedAnchor){
+		"hash mismatch": func(a *TrustedAnchor) {
+			a.Hash = a.Ancestors[0].BlockHash()
+		},
+		"missing ancestors": func(a *TrustedAnchor) {
+			a.Ancestors = a.Ancestors[1:]
+		},
+		"unlinked ancestors": func(a *TrustedAnchor) {
+			a.Ancestors = append([]wire.BlockHeader{}, a.Ancestors...)
+			a.Ancestors[3], a.Ancestors[4] = a.Ancestors[4], a.Ancestors[3]
+		},
+		"chain work lower than the headers work": func(a *TrustedAnchor) {
+			a.ChainWork = big.NewInt(1)
+		},
+		"missing chain work": func(a *TrustedAnchor) {
+			a.ChainWork = nil
+		},
+		"more ancestors than height": func(a *TrustedAnchor) {
+			a.Height = 5
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			anchor := testAnchor(t)
+			tc(&anchor)
+			_, err := NewBTCLightClientFromAnchor(&chaincfg.RegressionNetParams, anchor)
+			assert.Assert(t, errors.Is(err, ErrInvalidAnchor) || errors.Is(err, ErrHeaderNotLinked), err)
+		})
+	}
+
+	// mainnet needs the first block of the retarget period
+	anchor := testAnchor(t)
+	anchor.Height = 2016*100 + 500
+	err := anchor.Validate(&chaincfg.MainNetParams)
+	assert.ErrorContains(t, err, "need 500 ancestors")
+}
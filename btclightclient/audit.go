@@ -0,0 +1,123 @@
+package btclightclient
+
+import (
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// AuditEventType is the kind of state transition of an AuditEvent.
+type AuditEventType string
+
+const (
+	// AuditOpen records the state when the audit logger is set, the next
+	// events apply to it.
+	AuditOpen AuditEventType = "open"
+	// AuditInsert is a header insertion attempt.
+	AuditInsert AuditEventType = "insert"
+	// AuditReorg is a change of the best tip to a block not extending it.
+	AuditReorg AuditEventType = "reorg"
+	// AuditCheckpoint is a move of the latest finalized block.
+	AuditCheckpoint AuditEventType = "checkpoint"
+	// AuditPrune is the removal of a block, from a stale fork or by the
+	// retention policy.
+	AuditPrune AuditEventType = "prune"
+)
+
+// Prune reasons of the AuditPrune events.
+const (
+	PruneStaleFork = "stale_fork"
+	PruneRetention = "retention"
+)
+
+// AuditEvent is a state transition of the light client.
+type AuditEvent struct {
+	Time time.Time      `json:"time"`
+	Type AuditEventType `json:"type"`
+	// Hash and Height are the block of the event: the best tip of open
+	// and reorg events, the inserted header, the new checkpoint or the
+	// pruned block.
+	Hash   chainhash.Hash `json:"hash"`
+	Height int32          `json:"height,omitempty"`
+
+	// Source identifies the submitter of an inserted header.
+	Source string `json:"source,omitempty"`
+	// Header is the hex encoded inserted header.
+	Header  string         `json:"header,omitempty"`
+	Outcome *InsertOutcome `json:"outcome,omitempty"`
+	// Error is the reason of a rejected header.
+	Error string `json:"error,omitempty"`
+
+	// OldTip and Depth are the best tip before a reorg and its number of
+	// blocks not on the new best chain.
+	OldTip *chainhash.Hash `json:"old_tip,omitempty"`
+	Depth  int32           `json:"depth,omitempty"`
+
+	// Checkpoint, StateRoot and StoreHash are the latest finalized block,
+	// the state root and the hash of the stored blocks of an open event,
+	// the starting state of a replay.
+	Checkpoint *chainhash.Hash `json:"checkpoint,omitempty"`
+	StateRoot  *chainhash.Hash `json:"state_root,omitempty"`
+	StoreHash  *chainhash.Hash `json:"store_hash,omitempty"`
+	// Reason is why a block was pruned.
+	Reason string `json:"reason,omitempty"`
+}
+
+// AuditLogger records the state transitions of the light client, it is
+// called with the light client lock held.
+type AuditLogger interface {
+	LogEvent(AuditEvent)
+}
+
+// SetAuditLogger records the next state transitions with l, starting with an
+// AuditOpen event of the current state. A nil logger disables it.
+func (lc *BTCLightClient) SetAuditLogger(l AuditLogger) {
+	lc.auditLog = l
+	status, err := lc.ChainStatus()
+	if err != nil {
+		return
+	}
+	storeHash, err := lc.StoreHash()
+	if err != nil {
+		return
+	}
+	lc.audit(AuditEvent{
+		Type:       AuditOpen,
+		Hash:       status.TipHash,
+		Height:     status.TipHeight,
+		Checkpoint: &status.FinalizedHash,
+		StateRoot:  &status.StateRoot,
+		StoreHash:  &storeHash,
+	})
+}
+
+func (lc *BTCLightClient) audit(ev AuditEvent) {
+	if lc.auditLog == nil {
+		return
+	}
+	ev.Time = time.Now().UTC()
+	lc.auditLog.LogEvent(ev)
+}
+
+func (lc *BTCLightClient) auditInsert(source string, header *LightBlock, outcome InsertOutcome, err error) {
+	if lc.auditLog == nil {
+		return
+	}
+	hexHeader, _ := BlockHeaderToHex(header.Header)
+	ev := AuditEvent{
+		Type:    AuditInsert,
+		Hash:    header.Header.BlockHash(),
+		Height:  header.Height,
+		Source:  source,
+		Header:  hexHeader,
+		Outcome: &outcome,
+	}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	lc.audit(ev)
+}
+
+func (lc *BTCLightClient) auditPrune(lb *LightBlock, reason string) {
+	lc.audit(AuditEvent{Type: AuditPrune, Hash: lb.Header.BlockHash(), Height: lb.Height, Reason: reason})
+}
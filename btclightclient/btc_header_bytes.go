@@ -23,7 +23,27 @@ func BlockHeaderFromHex(hexStr string) (wire.BlockHeader, error) {
 		return header, err
 	}
 
-	reader := bytes.NewReader(data)
-	err = header.Deserialize(reader)
+	return BlockHeaderFromBytes(data)
+}
+
+// BlockHeaderFromBytes deserializes an 80 bytes header
+func BlockHeaderFromBytes(data []byte) (wire.BlockHeader, error) {
+	var header wire.BlockHeader
+
+	if len(data) != BTCHeaderSize {
+		return header, ErrInvalidHeaderSize
+	}
+
+	err := header.Deserialize(bytes.NewReader(data))
 	return header, err
 }
+
+// BlockHeaderToHex serializes header to 80 bytes hex string
+func BlockHeaderToHex(header wire.BlockHeader) (string, error) {
+	var buf bytes.Buffer
+	buf.Grow(BTCHeaderSize)
+	if err := header.Serialize(&buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf.Bytes()), nil
+}
@@ -1,8 +1,11 @@
 package btclightclient
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"math/big"
+	"slices"
 	"time"
 
 	"github.com/btcsuite/btcd/blockchain"
@@ -14,29 +17,79 @@ import (
 type BTCLightClient struct {
 	params   *chaincfg.Params
 	btcStore Store
+	metrics  *Metrics
+	// number of blocks on top of a block before it is finalized.
+	finalityDepth int32
+	versionBits   *versionBitsTracker
+	headerAcc     *HeaderAccumulator
+	retention     RetentionPolicy
+	// blocks below prunedHeight were pruned by the retention policy.
+	prunedHeight int32
+	limits       InsertLimits
+	auditLog     AuditLogger
 }
 
-func NewBTCLightClient(params *chaincfg.Params) *BTCLightClient {
-	return &BTCLightClient{
-		params:   params,
-		btcStore: NewMemStore(),
+// Option configures optional light client settings.
+type Option func(*BTCLightClient)
+
+// WithFinalityDepth overrides the default finality depth (MaxForkAge).
+func WithFinalityDepth(depth int32) Option {
+	return func(lc *BTCLightClient) {
+		lc.finalityDepth = depth
+	}
+}
+
+func NewBTCLightClient(params *chaincfg.Params, opts ...Option) *BTCLightClient {
+	lc := &BTCLightClient{
+		params:        params,
+		btcStore:      NewMemStore(),
+		finalityDepth: MaxForkAge,
+		versionBits:   newVersionBitsTracker(params),
+	}
+	for _, opt := range opts {
+		opt(lc)
 	}
+	return lc
+}
+
+// SetMetrics enables prometheus instrumentation of the light client.
+func (lc *BTCLightClient) SetMetrics(m *Metrics) {
+	lc.metrics = m
+	m.updateChain(lc.btcStore)
 }
 
 func (lc *BTCLightClient) ChainParams() *chaincfg.Params {
 	return lc.params
 }
 
+func (lc *BTCLightClient) FinalityDepth() int32 {
+	return lc.finalityDepth
+}
+
+// blocksPerRetarget returns the number of blocks between difficulty
+// adjustments (2016 on mainnet).
+func blocksPerRetarget(params *chaincfg.Params) int32 {
+	return int32(params.TargetTimespan / params.TargetTimePerBlock)
+}
+
 func (lc *BTCLightClient) BlocksPerRetarget() int32 {
-	return int32(lc.params.TargetTimespan / time.Second)
+	return blocksPerRetarget(lc.params)
 }
 
+// MinRetargetTimespan returns the minimum retarget timespan in seconds.
 func (lc *BTCLightClient) MinRetargetTimespan() int64 {
-	return int64(lc.BlocksPerRetarget()) / lc.params.RetargetAdjustmentFactor
+	return int64(lc.params.TargetTimespan/time.Second) / lc.params.RetargetAdjustmentFactor
 }
 
+// MaxRetargetTimespan returns the maximum retarget timespan in seconds.
 func (lc *BTCLightClient) MaxRetargetTimespan() int64 {
-	return int64(lc.BlocksPerRetarget()) * lc.params.RetargetAdjustmentFactor
+	return int64(lc.params.TargetTimespan/time.Second) * lc.params.RetargetAdjustmentFactor
+}
+
+// TotalWorkAtBlock returns the cumulative chain work up to and including
+// the block, or nil if the block is unknown.
+func (lc *BTCLightClient) TotalWorkAtBlock(h chainhash.Hash) *big.Int {
+	return lc.btcStore.TotalWorkAtBlock(h)
 }
 
 func (lc *BTCLightClient) VerifyCheckpoint(height int32, hash *chainhash.Hash) bool {
@@ -49,10 +102,62 @@ func (lc *BTCLightClient) FindPreviousCheckpoint() (blockchain.HeaderCtx, error)
 
 // We assume we always insert valid header. Acctually, Cosmos can revert a state
 // when module return error so this assumtion is reasonable
+// A header already stored is not inserted again, ErrDuplicateHeader is
+// returned and the state is unchanged.
 func (lc *BTCLightClient) InsertHeader(header wire.BlockHeader) error {
+	_, err := lc.insert("", header)
+	return err
+}
+
+// insert inserts header submitted by source, and records the insertion in
+// the metrics and the audit log.
+func (lc *BTCLightClient) insert(source string, header wire.BlockHeader) (InsertOutcome, error) {
+	start := time.Now()
+	oldTip := lc.btcStore.MostDifficultFork()
+	parent := lc.btcStore.LightBlockByHash(header.PrevBlock)
+	outcome := HeaderInserted
+	if parent != nil && !lc.btcStore.IsForkHead(header.PrevBlock) {
+		outcome = HeaderNewFork
+	}
+	var height int32
+	if parent != nil {
+		height = parent.Height + 1
+	}
+
+	err := lc.insertHeader(header)
+	lc.metrics.observeInsert(start, err)
+	switch {
+	case errors.Is(err, ErrDuplicateHeader):
+		outcome = HeaderDuplicate
+	case err != nil:
+		outcome = HeaderRejected
+	}
+	lc.auditInsert(source, NewLightBlock(height, header), outcome, err)
+	if err != nil {
+		return outcome, err
+	}
+
+	newTip := lc.btcStore.MostDifficultFork()
+	depth := lc.reorgDepth(oldTip, newTip)
+	lc.metrics.observeReorg(depth)
+	if depth > 0 {
+		oldTipHash := oldTip.Header.BlockHash()
+		lc.audit(AuditEvent{
+			Type:   AuditReorg,
+			Hash:   newTip.Header.BlockHash(),
+			Height: newTip.Height,
+			OldTip: &oldTipHash,
+			Depth:  depth,
+		})
+	}
+	lc.metrics.updateChain(lc.btcStore)
+	return outcome, nil
+}
+
+func (lc *BTCLightClient) insertHeader(header wire.BlockHeader) error {
 
 	if lb := lc.btcStore.LightBlockByHash(header.BlockHash()); lb != nil {
-		return ErrBlockNotInChain
+		return ErrDuplicateHeader
 	}
 
 	parentHash := header.PrevBlock
@@ -60,6 +165,9 @@ func (lc *BTCLightClient) InsertHeader(header wire.BlockHeader) error {
 	if parent == nil {
 		return ErrParentBlockNotInChain
 	}
+	if err := lc.checkLimits(parent, header); err != nil {
+		return err
+	}
 
 	// we need to handle 2 cases:
 	// extend the exist fork
@@ -80,61 +188,60 @@ func (lc *BTCLightClient) InsertHeader(header wire.BlockHeader) error {
 	return lc.CreateNewFork(parent, header)
 }
 
-func (lc *BTCLightClient) forkOfBlockhash(bh chainhash.Hash) ([]*LightBlock, error) {
+// descendsFromCheckpoint reports whether lb is the checkpoint or descends
+// from it. The ancestor at the checkpoint height is found with the skip list,
+// in O(log n) whatever the fork length. The ancestors of a stale fork can be
+// removed with another stale fork sharing them.
+func (lc *BTCLightClient) descendsFromCheckpoint(lb *LightBlock) bool {
 	checkpoint := lc.btcStore.LatestCheckPoint()
-	checkpointHash := checkpoint.Header.BlockHash()
-	fork := make([]*LightBlock, 0)
-
-	for count := 0; count <= MaxForkAge; count++ {
-		curr := lc.btcStore.LightBlockByHash(bh)
-		if checkpoint.Height > curr.Height {
-			return nil, ErrForkTooOld
-		}
-		fork = append(fork, curr)
-		if bh.IsEqual(&checkpointHash) {
-			return fork, nil
-		}
-		bh = curr.Header.PrevBlock
-	}
-
-	return nil, ErrForkTooOld
+	ancestor := lb.ancestor(lc.btcStore, checkpoint.Height)
+	return ancestor != nil && ancestor.Header.BlockHash() == checkpoint.Header.BlockHash()
 }
 
-// We follow:
-// - select the next finalize block base on 2 conditions:
-//   - this fork len greater than MaxForkAge
-//   - this fork is the most powerful fork
+// CleanUpFork updates the checkpoint and removes the stale forks, it is
+// called after each stored header.
 //
-// - Remove all invalid forks
-// - Update map(height => block) in btcStore
+// The checkpoint moves when the best fork, the one with the most work, is at
+// least finalityDepth blocks above it: the new checkpoint is the block
+// finalityDepth-1 blocks below the best tip. A fork descending from the
+// checkpoint becomes the best fork whatever its length, e.g. a testnet fork
+// of minimum difficulty blocks, so the best fork can be older than the
+// finality depth and the checkpoint then moves by several blocks, all
+// finalized at once. The finalized blocks are never reorganized, the forks
+// which don't descend from the new checkpoint are removed down to the
+// finalized chain.
 func (lc *BTCLightClient) CleanUpFork() error {
-	mostPowerForkLatestBlock := lc.btcStore.MostDifficultFork()
-
-	mostPowerForkAge, err := lc.ForkAge(mostPowerForkLatestBlock.Header.BlockHash())
-
+	best := lc.btcStore.MostDifficultFork()
+	bestAge, err := lc.ForkAge(best.Header.BlockHash())
 	if err != nil {
 		return err
 	}
-
-	// extract most power fork
-	fork, err := lc.forkOfBlockhash(mostPowerForkLatestBlock.Header.BlockHash())
-
-	if err != nil {
-		return err
+	if !lc.descendsFromCheckpoint(best) {
+		return ErrForkTooOld
 	}
 
-	if mostPowerForkAge >= MaxForkAge {
-		// fork[MaxForkAge - 1] always not nil because fork len >= maxforkage
-		lc.btcStore.SetLatestCheckPoint(fork[MaxForkAge-1])
-		lc.btcStore.SetLightBlockByHeight(fork[MaxForkAge-1])
+	// the fork cleanup goes on when the accumulator restarts
+	var accErr error
+	if bestAge >= lc.finalityDepth {
+		oldCheckpoint := lc.btcStore.LatestCheckPoint()
+		checkpoint := best.ancestor(lc.btcStore, best.Height-lc.finalityDepth+1)
+		if checkpointHash := checkpoint.Header.BlockHash(); checkpointHash != oldCheckpoint.Header.BlockHash() {
+			lc.audit(AuditEvent{Type: AuditCheckpoint, Hash: checkpointHash, Height: checkpoint.Height})
+		}
+		lc.btcStore.SetLatestCheckPoint(checkpoint)
+		for lb := checkpoint; lb != nil && lb.Height >= oldCheckpoint.Height; lb = lc.btcStore.LightBlockByHash(lb.Header.PrevBlock) {
+			lc.btcStore.SetLightBlockByHeight(lb)
+		}
+		accErr = lc.finalized()
 		for _, h := range lc.btcStore.LatestBlockHashOfFork() {
-			_, err := lc.forkOfBlockhash(h)
-
 			// clean other fork not start at checkpoint
-			if err != nil {
+			if head := lc.btcStore.LightBlockByHash(h); head == nil || !lc.descendsFromCheckpoint(head) {
+				// remove the fork blocks down to the finalized
+				// chain, including those at the checkpoint height
 				removedHash := h
-				removeBlock := lc.btcStore.LightBlockByHash(removedHash)
-				for removeBlock.Height > fork[MaxForkAge-1].Height {
+				removeBlock := head
+				for removeBlock != nil && lc.btcStore.LightBlockAtHeight(int64(removeBlock.Height)) != removeBlock {
+					lc.auditPrune(removeBlock, PruneStaleFork)
 					lc.btcStore.RemoveBlock(removedHash)
 					removedHash = removeBlock.Header.PrevBlock
 					removeBlock = lc.btcStore.LightBlockByHash(removedHash)
@@ -145,7 +252,29 @@ func (lc *BTCLightClient) CleanUpFork() error {
 
 	}
 
-	return nil
+	lc.metrics.updateChain(lc.btcStore)
+	return accErr
+}
+
+// reorgDepth returns the number of blocks of oldTip's chain that are not part
+// of newTip's chain. It returns 0 when newTip extends oldTip.
+func (lc *BTCLightClient) reorgDepth(oldTip, newTip *LightBlock) int32 {
+	if oldTip == nil || newTip == nil {
+		return 0
+	}
+
+	a, b := oldTip, newTip
+	for a.Header.BlockHash() != b.Header.BlockHash() {
+		if a.Height >= b.Height {
+			a = lc.btcStore.LightBlockByHash(a.Header.PrevBlock)
+		} else {
+			b = lc.btcStore.LightBlockByHash(b.Header.PrevBlock)
+		}
+		if a == nil || b == nil {
+			return 0
+		}
+	}
+	return oldTip.Height - a.Height
 }
 
 func (lc *BTCLightClient) CreateNewFork(parent *LightBlock, header wire.BlockHeader) error {
@@ -200,13 +329,20 @@ func (b *BlockMedianTimeSource) Offset() time.Duration {
 
 func (lc *BTCLightClient) CheckHeader(parent wire.BlockHeader, header wire.BlockHeader) error {
 	noFlag := blockchain.BFNone
-	fork, err := lc.forkOfBlockhash(parent.BlockHash())
-	if err != nil {
-		return err
+	parentBlock := lc.btcStore.LightBlockByHash(parent.BlockHash())
+	if parentBlock == nil || !lc.descendsFromCheckpoint(parentBlock) {
+		return ErrForkTooOld
 	}
-	latestLightBlock := fork[0]
-
-	if err := blockchain.CheckBlockHeaderContext(&header, NewHeaderContext(latestLightBlock, lc.btcStore, fork), noFlag, lc, true); err != nil {
+	prevNode := NewHeaderContext(parentBlock, lc.btcStore)
+
+	err := lc.checkHeaderContext(&header, prevNode)
+	// Without all the ancestors the difficulty and the median time can't
+	// be computed, the result of the checks is meaningless.
+	if height, missing := prevNode.MissingAncestor(); missing {
+		return fmt.Errorf("%w: block at height %d is needed to validate %s",
+			ErrMissingAncestor, height, header.BlockHash())
+	}
+	if err != nil {
 		return err
 	}
 
@@ -216,6 +352,19 @@ func (lc *BTCLightClient) CheckHeader(parent wire.BlockHeader, header wire.Block
 	return nil
 }
 
+func (lc *BTCLightClient) checkHeaderContext(header *wire.BlockHeader, prevNode *HeaderContext) error {
+	contextFlags := blockchain.BFNone
+	if EnforceBIP94(lc.params) {
+		// btcd doesn't implement BIP94, we check the difficulty and
+		// the timestamp ourselves.
+		if err := lc.checkBIP94Header(header, prevNode); err != nil {
+			return err
+		}
+		contextFlags = blockchain.BFFastAdd
+	}
+	return blockchain.CheckBlockHeaderContext(header, prevNode, contextFlags, lc, true)
+}
+
 // query status, use for test
 func (lc *BTCLightClient) Status() {
 	fmt.Println(lc.params.Net)
@@ -239,28 +388,112 @@ func (lc *BTCLightClient) IsBlockPresent(h chainhash.Hash) bool {
 	return (lightBlock != nil)
 }
 
-// TODO: make it more simple
-func NewBTCLightClientWithData(params *chaincfg.Params, headers []wire.BlockHeader, start int) *BTCLightClient {
-	lc := NewBTCLightClient(params)
-	lb := NewLightBlock(int32(start), headers[0])
-	lc.btcStore.SetBlock(lb, big.NewInt(0))
+// MainChain returns the blocks of the most difficult fork, from the oldest
+// block known by the store up to the fork head.
+func (lc *BTCLightClient) MainChain() []*LightBlock {
+	chain := []*LightBlock{}
+	for lb := lc.btcStore.MostDifficultFork(); lb != nil; lb = lc.btcStore.LightBlockByHash(lb.Header.PrevBlock) {
+		chain = append(chain, lb)
+	}
+	slices.Reverse(chain)
+	return chain
+}
+
+// HeaderIterator yields headers ordered by height. Next returns io.EOF after
+// the last header.
+type HeaderIterator interface {
+	Next() (wire.BlockHeader, error)
+}
+
+type headerSliceIterator struct {
+	headers []wire.BlockHeader
+}
+
+// NewHeaderSliceIterator returns a HeaderIterator over headers.
+func NewHeaderSliceIterator(headers []wire.BlockHeader) HeaderIterator {
+	return &headerSliceIterator{headers: headers}
+}
+
+func (it *headerSliceIterator) Next() (wire.BlockHeader, error) {
+	if len(it.headers) == 0 {
+		return wire.BlockHeader{}, io.EOF
+	}
+	h := it.headers[0]
+	it.headers = it.headers[1:]
+	return h, nil
+}
+
+// NewBTCLightClientWithData creates a light client from trusted headers,
+// the first header is at height start.
+func NewBTCLightClientWithData(params *chaincfg.Params, headers []wire.BlockHeader, start int, opts ...Option) *BTCLightClient {
+	lc, err := NewBTCLightClientFromIterator(params, NewHeaderSliceIterator(headers), start, big.NewInt(0), opts...)
+	if err != nil {
+		panic(err)
+	}
+	return lc
+}
+
+// NewBTCLightClientFromIterator creates a light client from a stream of
+// trusted headers, the first header is at height start and baseWork is the
+// chain work of its parent (zero when unknown). Headers are not
+// validated, we only check each header builds on the previous one. Only the
+// last finality depth headers are kept in a buffer, so the headers can be
+// streamed from a file.
+// The headers deeper than the finality depth are finalized, the last header
+// is the fork head.
+func NewBTCLightClientFromIterator(params *chaincfg.Params, it HeaderIterator, start int, baseWork *big.Int, opts ...Option) (*BTCLightClient, error) {
+	lc := NewBTCLightClient(params, opts...)
+
+	header, err := it.Next()
+	if err == io.EOF {
+		return nil, ErrNoHeaders
+	}
+	if err != nil {
+		return nil, err
+	}
+	lb := NewLightBlock(int32(start), header)
+	lc.btcStore.SetBlock(lb, baseWork)
 	lc.btcStore.SetLatestCheckPoint(lb)
 	lc.btcStore.SetLightBlockByHeight(lb)
-	for i, header := range headers[1:] {
-		previousPower := lc.btcStore.TotalWorkAtBlock(header.PrevBlock)
-		lb := NewLightBlock(int32(start+i+1), header)
-		lc.btcStore.SetBlock(lb, previousPower)
-		if len(headers)-i > MaxForkAge {
-			lc.btcStore.SetLightBlockByHeight(lb)
+	lc.prunedHeight = lb.Height
+
+	// blocks not finalized yet
+	pending := make([]*LightBlock, 0, lc.finalityDepth)
+	last := lb
+	for {
+		header, err := it.Next()
+		if err == io.EOF {
+			break
 		}
-
-		if len(headers)-i-1 == MaxForkAge {
-			lc.btcStore.SetLatestCheckPoint(lb)
+		if err != nil {
+			return nil, err
+		}
+		if header.PrevBlock != last.Header.BlockHash() {
+			return nil, fmt.Errorf("%w: %s at height %d", ErrHeaderNotLinked, header.BlockHash(), last.Height+1)
 		}
 
-		if i == len(headers)-2 {
-			lc.btcStore.SetIsHead(header.BlockHash())
+		previousPower := lc.btcStore.TotalWorkAtBlock(header.PrevBlock)
+		lb := NewLightBlock(last.Height+1, header)
+		lc.btcStore.SetBlock(lb, previousPower)
+		pending = append(pending, lb)
+		if len(pending) >= int(lc.finalityDepth) {
+			lc.btcStore.SetLatestCheckPoint(pending[0])
+			lc.btcStore.SetLightBlockByHeight(pending[0])
+			// prune while streaming, so a long header file fits in
+			// memory with a retention policy
+			if err := lc.finalized(); err != nil {
+				return nil, err
+			}
+			pending = pending[1:]
 		}
+		last = lb
 	}
-	return lc
+
+	lc.btcStore.SetIsHead(last.Header.BlockHash())
+	// the first header is finalized even without finality depth headers
+	// on top of it
+	if err := lc.accumulate(); err != nil {
+		return nil, err
+	}
+	return lc, nil
 }
@@ -1,7 +1,9 @@
 package btclightclient
 
 import (
+	"bytes"
 	"math/big"
+	"slices"
 
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
@@ -24,8 +26,13 @@ type Store interface {
 	SetLatestCheckPoint(lb *LightBlock)
 	SetLightBlockByHeight(lb *LightBlock)
 	MostDifficultFork() *LightBlock
+	// LatestBlockHashOfFork returns the fork heads in a deterministic
+	// order.
 	LatestBlockHashOfFork() []chainhash.Hash
 	RemoveBlock(h chainhash.Hash)
+	// CheckConsistency checks the indexes agree with the stored headers,
+	// and rebuilds them when repair is set.
+	CheckConsistency(repair bool) []Inconsistency
 }
 
 type MemStore struct {
@@ -33,8 +40,12 @@ type MemStore struct {
 	lightBlockByHashMap   map[chainhash.Hash]*LightBlock
 	latestBlockHashOfFork map[chainhash.Hash]struct{}
 	totalWorkMap          map[chainhash.Hash]*big.Int
-	latestcheckpoint      *LightBlock
-	mostDifficultFork     *LightBlock
+	// childCount is the number of stored blocks on top of each block
+	childCount        map[chainhash.Hash]int
+	latestcheckpoint  *LightBlock
+	mostDifficultFork *LightBlock
+	// seq is the sequence number of the last block received
+	seq uint64
 }
 
 func NewMemStore() *MemStore {
@@ -43,13 +54,39 @@ func NewMemStore() *MemStore {
 		lightBlockByHashMap:   make(map[chainhash.Hash]*LightBlock),
 		latestBlockHashOfFork: make(map[chainhash.Hash]struct{}),
 		totalWorkMap:          make(map[chainhash.Hash]*big.Int),
+		childCount:            make(map[chainhash.Hash]int),
 		latestcheckpoint:      nil,
 		mostDifficultFork:     nil,
 	}
 }
 
+// RemoveBlock removes the block from all the indexes. When a fork head is
+// removed, its parent becomes the fork head if it has no other children and is
+// not finalized.
 func (s *MemStore) RemoveBlock(h chainhash.Hash) {
+	lb := s.lightBlockByHashMap[h]
+	if lb == nil {
+		return
+	}
+	isHead := s.IsForkHead(h)
 	delete(s.lightBlockByHashMap, h)
+	delete(s.totalWorkMap, h)
+	delete(s.latestBlockHashOfFork, h)
+	if s.lightblockMap[int64(lb.Height)] == lb {
+		delete(s.lightblockMap, int64(lb.Height))
+	}
+
+	parentHash := lb.Header.PrevBlock
+	if s.childCount[parentHash]--; s.childCount[parentHash] <= 0 {
+		delete(s.childCount, parentHash)
+		parent := s.lightBlockByHashMap[parentHash]
+		if isHead && parent != nil && s.lightblockMap[int64(parent.Height)] != parent {
+			s.SetIsHead(parentHash)
+		}
+	}
+	if s.mostDifficultFork == lb {
+		s.selectMostDifficultFork()
+	}
 }
 
 func (s *MemStore) SetLightBlockByHeight(lb *LightBlock) {
@@ -84,22 +121,19 @@ func (s *MemStore) SetLatestCheckPoint(lb *LightBlock) {
 
 func (s *MemStore) SetBlock(lb *LightBlock, previousPower *big.Int) {
 	blockHash := lb.Header.BlockHash()
+	lb.buildSkip(s)
 	s.lightBlockByHashMap[blockHash] = lb
 
 	power := big.NewInt(0)
 	power = power.Add(previousPower, lb.CalcWork())
+	s.totalWorkMap[blockHash] = power
+	s.childCount[lb.Header.PrevBlock]++
 
-	powerForkBlock := s.MostDifficultFork()
-	mostPower := big.NewInt(0)
-	if powerForkBlock != nil {
-		mostPower = s.totalWorkMap[powerForkBlock.Header.BlockHash()]
-	}
-
-	if mostPower.Cmp(power) < 0 {
+	s.seq++
+	lb.seq = s.seq
+	if tip := s.MostDifficultFork(); tip == nil || s.betterTip(lb, tip) {
 		s.mostDifficultFork = lb
 	}
-
-	s.totalWorkMap[blockHash] = power
 }
 
 func (s *MemStore) AddBlock(parent *LightBlock, header wire.BlockHeader) error {
@@ -121,11 +155,14 @@ func (s *MemStore) SetIsNotHead(bh chainhash.Hash) {
 	delete(s.latestBlockHashOfFork, bh)
 }
 
+// LatestBlockHashOfFork returns the fork heads sorted by hash, so that the
+// forks are always visited in the same order.
 func (s *MemStore) LatestBlockHashOfFork() []chainhash.Hash {
 	hashes := []chainhash.Hash{}
 	for h := range s.latestBlockHashOfFork {
 		hashes = append(hashes, h)
 	}
+	slices.SortFunc(hashes, func(a, b chainhash.Hash) int { return bytes.Compare(a[:], b[:]) })
 
 	return hashes
 }
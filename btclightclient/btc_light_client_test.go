@@ -1,6 +1,8 @@
 package btclightclient
 
 import (
+	"errors"
+	"math/big"
 	"testing"
 
 	"github.com/btcsuite/btcd/chaincfg"
@@ -215,3 +217,41 @@ func TestCleanup(t *testing.T) {
 	listFork := lc.btcStore.LatestBlockHashOfFork()
 	assert.Assert(t, len(listFork) == 1)
 }
+
+func TestNewBTCLightClientFromIterator(t *testing.T) {
+	headers := make([]wire.BlockHeader, len(HEADERS))
+	for i, s := range HEADERS {
+		h, err := BlockHeaderFromHex(s)
+		assert.NilError(t, err)
+		headers[i] = h
+	}
+
+	lc, err := NewBTCLightClientFromIterator(&chaincfg.RegressionNetParams, NewHeaderSliceIterator(headers), 100, big.NewInt(0))
+	assert.NilError(t, err)
+	tip := lc.btcStore.MostDifficultFork()
+	assert.Equal(t, tip.Height, int32(100+len(headers)-1))
+	assert.Assert(t, lc.btcStore.IsForkHead(tip.Header.BlockHash()))
+	assert.Equal(t, lc.LatestFinalizedBlockHeight(), int64(100+len(headers)-MaxForkAge))
+	assert.Assert(t, lc.btcStore.LightBlockAtHeight(lc.LatestFinalizedBlockHeight()) != nil)
+	assert.Assert(t, lc.btcStore.LightBlockAtHeight(lc.LatestFinalizedBlockHeight()+1) == nil)
+	assert.Equal(t, len(lc.MainChain()), len(headers))
+
+	// a shorter finality depth finalizes more blocks
+	lc, err = NewBTCLightClientFromIterator(&chaincfg.RegressionNetParams, NewHeaderSliceIterator(headers), 0, big.NewInt(0), WithFinalityDepth(2))
+	assert.NilError(t, err)
+	assert.Equal(t, lc.LatestFinalizedBlockHeight(), int64(len(headers)-2))
+
+	_, err = NewBTCLightClientFromIterator(&chaincfg.RegressionNetParams, NewHeaderSliceIterator(nil), 0, big.NewInt(0))
+	assert.Equal(t, err, ErrNoHeaders)
+
+	unlinked := []wire.BlockHeader{headers[0], headers[2]}
+	_, err = NewBTCLightClientFromIterator(&chaincfg.RegressionNetParams, NewHeaderSliceIterator(unlinked), 0, big.NewInt(0))
+	assert.Assert(t, errors.Is(err, ErrHeaderNotLinked))
+}
+
+func TestRetargetParams(t *testing.T) {
+	lc := NewBTCLightClient(&chaincfg.MainNetParams)
+	assert.Equal(t, lc.BlocksPerRetarget(), int32(2016))
+	assert.Equal(t, lc.MinRetargetTimespan(), int64(14*24*60*60/4))
+	assert.Equal(t, lc.MaxRetargetTimespan(), int64(14*24*60*60*4))
+}
@@ -0,0 +1,462 @@
+package btclightclient
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// Store indexes reported by the consistency check.
+const (
+	IndexBlocks            = "blocks"
+	IndexHeights           = "heights"
+	IndexWork              = "work"
+	IndexChildren          = "children"
+	IndexForkHeads         = "fork_heads"
+	IndexCheckpoint        = "checkpoint"
+	IndexMostDifficultFork = "most_difficult_fork"
+	IndexProofOfWork       = "proof_of_work"
+)
+
+// Inconsistency is a store index entry which doesn't agree with the stored
+// headers.
+type Inconsistency struct {
+	Index       string         `json:"index"`
+	Hash        chainhash.Hash `json:"hash"`
+	Height      int32          `json:"height"`
+	Description string         `json:"description"`
+	// Repaired reports whether the entry was repaired.
+	Repaired bool `json:"repaired"`
+}
+
+func (i Inconsistency) String() string {
+	s := fmt.Sprintf("%s: block %s at height %d: %s", i.Index, i.Hash, i.Height, i.Description)
+	if i.Repaired {
+		s += " (repaired)"
+	}
+	return s
+}
+
+// consistencyCheck collects the inconsistencies of a MemStore.
+type consistencyCheck struct {
+	s        *MemStore
+	repair   bool
+	issues   []Inconsistency
+	blocks   map[chainhash.Hash]*LightBlock
+	children map[chainhash.Hash][]*LightBlock
+}
+
+func (c *consistencyCheck) report(index string, lb *LightBlock, hash chainhash.Hash, format string, args ...any) {
+	issue := Inconsistency{
+		Index:       index,
+		Hash:        hash,
+		Description: fmt.Sprintf(format, args...),
+		Repaired:    c.repair,
+	}
+	if lb != nil {
+		issue.Height = lb.Height
+	}
+	c.issues = append(c.issues, issue)
+}
+
+// CheckConsistency re-derives the store indexes from the stored headers:
+// block hashes, heights and total work from the parent blocks, the finalized
+// chain from the checkpoint, the fork heads from the blocks without children
+// and the most difficult fork from the fork heads. It returns the entries
+// which don't match, sorted by index and height. When repair is set the
+// indexes are rebuilt, stale fork blocks are removed.
+func (s *MemStore) CheckConsistency(repair bool) []Inconsistency {
+	c := &consistencyCheck{s: s, repair: repair}
+	c.checkBlocks()
+	c.indexChildren()
+	c.checkParentHeights()
+	c.checkCheckpoint()
+	c.checkHeights()
+	c.checkForkHeads()
+	c.checkChildren()
+	c.checkWork()
+	c.checkMostDifficultFork()
+
+	sort.SliceStable(c.issues, func(i, j int) bool {
+		a, b := c.issues[i], c.issues[j]
+		if a.Index != b.Index {
+			return a.Index < b.Index
+		}
+		if a.Height != b.Height {
+			return a.Height < b.Height
+		}
+		return bytes.Compare(a.Hash[:], b.Hash[:]) < 0
+	})
+	return c.issues
+}
+
+// checkBlocks checks every block is stored by its hash. The other checks use
+// the blocks by their actual hash.
+func (c *consistencyCheck) checkBlocks() {
+	c.blocks = map[chainhash.Hash]*LightBlock{}
+	for hash, lb := range c.s.lightBlockByHashMap {
+		if lb == nil {
+			c.report(IndexBlocks, nil, hash, "no block")
+			continue
+		}
+		blockHash := lb.Header.BlockHash()
+		if blockHash != hash {
+			c.report(IndexBlocks, lb, hash, "stored by the hash of another block %s", blockHash)
+		}
+		if blockHash == hash || c.s.lightBlockByHashMap[blockHash] == nil {
+			c.blocks[blockHash] = lb
+		}
+	}
+	// the finalized blocks can be recovered from the height index
+	for _, lb := range c.s.lightblockMap {
+		if lb == nil {
+			continue
+		}
+		if hash := lb.Header.BlockHash(); c.blocks[hash] == nil {
+			c.report(IndexBlocks, lb, hash, "finalized block missing, recovered from the height index")
+			c.blocks[hash] = lb
+		}
+	}
+	if c.repair {
+		c.s.lightBlockByHashMap = c.blocks
+	}
+}
+
+// stored returns the stored block with the hash of lb, or nil.
+func (c *consistencyCheck) stored(lb *LightBlock) *LightBlock {
+	if lb == nil {
+		return nil
+	}
+	return c.blocks[lb.Header.BlockHash()]
+}
+
+// checkCheckpoint checks the checkpoint is a stored block. It is repaired
+// with the highest stored finalized block.
+func (c *consistencyCheck) checkCheckpoint() {
+	checkpoint := c.s.latestcheckpoint
+	if checkpoint != nil && c.stored(checkpoint) == checkpoint {
+		return
+	}
+
+	var hash chainhash.Hash
+	if checkpoint != nil {
+		hash = checkpoint.Header.BlockHash()
+	}
+	c.report(IndexCheckpoint, checkpoint, hash, "checkpoint is not a stored block")
+	if !c.repair {
+		return
+	}
+	if c.s.latestcheckpoint = c.stored(checkpoint); c.s.latestcheckpoint != nil {
+		return
+	}
+	for _, lb := range c.s.lightblockMap {
+		if c.stored(lb) == lb && (c.s.latestcheckpoint == nil || lb.Height > c.s.latestcheckpoint.Height) {
+			c.s.latestcheckpoint = lb
+		}
+	}
+}
+
+// checkHeights checks the height index is the chain of the checkpoint. Older
+// blocks, after a gap left by the retention policy, must be stored at their
+// height.
+func (c *consistencyCheck) checkHeights() {
+	checkpoint := c.s.latestcheckpoint
+	if checkpoint == nil {
+		return
+	}
+
+	// the finalized chain from the checkpoint down to the first gap
+	finalized := map[int32]*LightBlock{}
+	for lb := c.stored(checkpoint); lb != nil; lb = c.blocks[lb.Header.PrevBlock] {
+		finalized[lb.Height] = lb
+	}
+
+	for h, lb := range finalized {
+		if c.s.lightblockMap[int64(h)] == nil {
+			c.report(IndexHeights, lb, lb.Header.BlockHash(), "finalized block missing from the height index")
+		}
+	}
+	for height, lb := range c.s.lightblockMap {
+		h := int32(height)
+		switch {
+		case lb == nil || c.stored(lb) != lb:
+			var hash chainhash.Hash
+			if lb != nil {
+				hash = lb.Header.BlockHash()
+			}
+			c.report(IndexHeights, lb, hash, "finalized block at height %d is not stored", h)
+		case h > checkpoint.Height:
+			c.report(IndexHeights, lb, lb.Header.BlockHash(), "finalized block above the checkpoint")
+		case finalized[h] != nil && finalized[h] != lb:
+			c.report(IndexHeights, lb, lb.Header.BlockHash(), "block is not on the checkpoint chain")
+		case finalized[h] == nil && lb.Height != h:
+			c.report(IndexHeights, lb, lb.Header.BlockHash(), "block stored at height %d", h)
+		default:
+			continue
+		}
+		if c.repair {
+			delete(c.s.lightblockMap, height)
+		}
+	}
+	if c.repair {
+		for h, lb := range finalized {
+			c.s.lightblockMap[int64(h)] = lb
+		}
+	}
+}
+
+func (c *consistencyCheck) indexChildren() {
+	c.children = map[chainhash.Hash][]*LightBlock{}
+	for _, lb := range c.blocks {
+		c.children[lb.Header.PrevBlock] = append(c.children[lb.Header.PrevBlock], lb)
+	}
+}
+
+// checkForkHeads checks the fork heads are the blocks without children
+// descending from the checkpoint. Other blocks without children are either
+// finalized blocks kept by the retention policy, or stale fork blocks.
+func (c *consistencyCheck) checkForkHeads() {
+	checkpoint := c.s.latestcheckpoint
+	for hash := range c.s.latestBlockHashOfFork {
+		lb := c.blocks[hash]
+		switch {
+		case lb == nil:
+			c.report(IndexForkHeads, nil, hash, "fork head is not stored")
+		case len(c.children[hash]) > 0:
+			c.report(IndexForkHeads, lb, hash, "fork head has children")
+		default:
+			continue
+		}
+		if c.repair {
+			delete(c.s.latestBlockHashOfFork, hash)
+		}
+	}
+	if checkpoint == nil {
+		return
+	}
+
+	checkpointHash := checkpoint.Header.BlockHash()
+	var stale []*LightBlock
+	for hash, lb := range c.blocks {
+		if len(c.children[hash]) > 0 {
+			continue
+		}
+		if lb.Height < checkpoint.Height {
+			if c.s.lightblockMap[int64(lb.Height)] != lb {
+				stale = append(stale, lb)
+			}
+			continue
+		}
+		ancestor := lb
+		for ancestor != nil && ancestor.Height > checkpoint.Height {
+			ancestor = c.blocks[ancestor.Header.PrevBlock]
+		}
+		if ancestor == nil || ancestor.Header.BlockHash() != checkpointHash {
+			stale = append(stale, lb)
+			continue
+		}
+		if _, ok := c.s.latestBlockHashOfFork[hash]; !ok {
+			c.report(IndexForkHeads, lb, hash, "block without children is not a fork head")
+			if c.repair {
+				c.s.latestBlockHashOfFork[hash] = struct{}{}
+			}
+		}
+	}
+
+	// the stale blocks are found in map order
+	sort.Slice(stale, func(i, j int) bool {
+		a, b := stale[i].Header.BlockHash(), stale[j].Header.BlockHash()
+		return bytes.Compare(a[:], b[:]) < 0
+	})
+	for _, lb := range stale {
+		c.report(IndexForkHeads, lb, lb.Header.BlockHash(), "stale fork not descending from the checkpoint")
+		if !c.repair {
+			continue
+		}
+		// remove the fork down to the finalized chain
+		for lb != nil && c.s.lightblockMap[int64(lb.Height)] != lb && len(c.children[lb.Header.BlockHash()]) == 0 {
+			hash := lb.Header.BlockHash()
+			parentHash := lb.Header.PrevBlock
+			c.s.RemoveBlock(hash)
+			siblings := c.children[parentHash]
+			for i, sibling := range siblings {
+				if sibling == lb {
+					c.children[parentHash] = append(siblings[:i:i], siblings[i+1:]...)
+					break
+				}
+			}
+			lb = c.blocks[parentHash]
+		}
+	}
+}
+
+// checkChildren checks the number of children of the blocks.
+func (c *consistencyCheck) checkChildren() {
+	hashes := map[chainhash.Hash]struct{}{}
+	for hash := range c.s.childCount {
+		hashes[hash] = struct{}{}
+	}
+	for hash := range c.children {
+		hashes[hash] = struct{}{}
+	}
+	for hash := range hashes {
+		count := len(c.children[hash])
+		if c.s.childCount[hash] == count {
+			continue
+		}
+		c.report(IndexChildren, c.blocks[hash], hash, "%d children, expected %d", c.s.childCount[hash], count)
+		if !c.repair {
+			continue
+		}
+		if count == 0 {
+			delete(c.s.childCount, hash)
+		} else {
+			c.s.childCount[hash] = count
+		}
+	}
+}
+
+// walk calls f on the stored blocks, parents before children.
+func (c *consistencyCheck) walk(f func(lb, parent *LightBlock)) {
+	var queue []*LightBlock
+	for _, lb := range c.blocks {
+		if c.blocks[lb.Header.PrevBlock] == nil {
+			queue = append(queue, lb)
+		}
+	}
+	for len(queue) > 0 {
+		lb := queue[0]
+		queue = queue[1:]
+		f(lb, c.blocks[lb.Header.PrevBlock])
+		queue = append(queue, c.children[lb.Header.BlockHash()]...)
+	}
+}
+
+// checkParentHeights checks the height of the blocks follows their parent.
+func (c *consistencyCheck) checkParentHeights() {
+	c.walk(func(lb, parent *LightBlock) {
+		if parent == nil || lb.Height == parent.Height+1 {
+			return
+		}
+		c.report(IndexHeights, lb, lb.Header.BlockHash(), "height is not the parent height %d + 1", parent.Height)
+		if c.repair {
+			lb.Height = parent.Height + 1
+		}
+	})
+}
+
+// checkWork checks the total work of the blocks from their parent. The work
+// of the oldest stored blocks can't be recomputed, it must include at least
+// their own work.
+func (c *consistencyCheck) checkWork() {
+	for hash := range c.s.totalWorkMap {
+		if c.blocks[hash] == nil {
+			c.report(IndexWork, nil, hash, "total work of a block not stored")
+			if c.repair {
+				delete(c.s.totalWorkMap, hash)
+			}
+		}
+	}
+
+	expected := map[chainhash.Hash]*big.Int{}
+	c.walk(func(lb, parent *LightBlock) {
+		hash := lb.Header.BlockHash()
+		stored := c.s.totalWorkMap[hash]
+		work := lb.CalcWork()
+		if parent != nil {
+			work.Add(work, expected[lb.Header.PrevBlock])
+		} else if stored != nil && stored.Cmp(work) >= 0 {
+			work = stored
+		}
+		expected[hash] = work
+
+		if stored == nil || stored.Cmp(work) != 0 {
+			c.report(IndexWork, lb, hash, "total work %v, expected %v", stored, work)
+			if c.repair {
+				c.s.totalWorkMap[hash] = new(big.Int).Set(work)
+			}
+		}
+	})
+}
+
+// checkMostDifficultFork checks the most difficult fork is the best tip
+// selected by the fork choice.
+func (c *consistencyCheck) checkMostDifficultFork() {
+	var best *LightBlock
+	for hash := range c.s.latestBlockHashOfFork {
+		if lb := c.blocks[hash]; lb != nil && (best == nil || c.s.betterTip(lb, best)) {
+			best = lb
+		}
+	}
+	if best == nil {
+		best = c.stored(c.s.latestcheckpoint)
+	}
+
+	current := c.s.mostDifficultFork
+	if current == best {
+		return
+	}
+	var hash chainhash.Hash
+	if current != nil {
+		hash = current.Header.BlockHash()
+	}
+	c.report(IndexMostDifficultFork, current, hash, "not the best fork head")
+	if c.repair {
+		c.s.mostDifficultFork = best
+	}
+}
+
+// CheckConsistency checks the store indexes, see MemStore.CheckConsistency,
+// and the proof of work of the best chain headers. Headers with an invalid
+// proof of work can't be repaired, they are only reported.
+func (lc *BTCLightClient) CheckConsistency(repair bool) []Inconsistency {
+	issues := lc.btcStore.CheckConsistency(repair)
+	for _, lb := range lc.headers() {
+		hash := lb.Header.BlockHash()
+		target := blockchain.CompactToBig(lb.Header.Bits)
+		if target.Sign() <= 0 || target.Cmp(lc.params.PowLimit) > 0 || blockchain.HashToBig(&hash).Cmp(target) > 0 {
+			issues = append(issues, Inconsistency{
+				Index:       IndexProofOfWork,
+				Hash:        hash,
+				Height:      lb.Height,
+				Description: "block hash doesn't meet the difficulty target",
+			})
+		}
+	}
+	if repair {
+		lc.metrics.updateChain(lc.btcStore)
+	}
+	return issues
+}
+
+// headers returns the finalized blocks and the blocks of the forks, walking
+// the forks by hash down to the checkpoint.
+func (lc *BTCLightClient) headers() []*LightBlock {
+	headers := []*LightBlock{}
+	checkpoint := lc.btcStore.LatestCheckPoint()
+	if checkpoint == nil {
+		return headers
+	}
+	for h := int64(0); h <= int64(checkpoint.Height); h++ {
+		if lb := lc.btcStore.LightBlockAtHeight(h); lb != nil {
+			headers = append(headers, lb)
+		}
+	}
+	seen := map[chainhash.Hash]bool{}
+	for _, head := range lc.btcStore.LatestBlockHashOfFork() {
+		for hash := head; !seen[hash]; {
+			seen[hash] = true
+			lb := lc.btcStore.LightBlockByHash(hash)
+			if lb == nil || lb.Header.BlockHash() != hash || lb.Height <= checkpoint.Height {
+				break
+			}
+			headers = append(headers, lb)
+			hash = lb.Header.PrevBlock
+		}
+	}
+	return headers
+}
@@ -0,0 +1,188 @@
+package btclightclient
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/gonative-cc/bitcoin-lightclient/chaingen"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"gotest.tools/assert"
+)
+
+// consistencyLightClient returns a light client with a 20 blocks chain and a
+// 2 blocks fork from the block before the tip.
+func consistencyLightClient(t *testing.T) (*BTCLightClient, []*chaingen.Block, []*chaingen.Block) {
+	t.Helper()
+	g := chaingen.New(&chaincfg.RegressionNetParams)
+	main := chaingen.Chain(g.Extend(g.Genesis(), 20)[19])
+	lc := NewBTCLightClientWithData(g.Params(), chaingen.Headers(main), 0)
+	fork := g.Extend(main[19], 2)
+	for _, b := range fork {
+		assert.NilError(t, lc.InsertHeader(b.Header()))
+	}
+	assert.Equal(t, len(lc.CheckConsistency(false)), 0)
+	return lc, main, fork
+}
+
+func indexes(issues []Inconsistency) []string {
+	s := []string{}
+	for _, issue := range issues {
+		s = append(s, issue.Index)
+	}
+	return s
+}
+
+func TestCheckConsistency(t *testing.T) {
+	testCases := []struct {
+		name    string
+		corrupt func(s *MemStore, main, fork []*chaingen.Block)
+		issues  []string
+	}{
+		{
+			name: "block stored by another hash",
+			corrupt: func(s *MemStore, main, _ []*chaingen.Block) {
+				s.lightBlockByHashMap[main[3].Hash()] = s.lightBlockByHashMap[main[4].Hash()]
+			},
+			issues: []string{IndexBlocks, IndexBlocks},
+		},
+		{
+			name: "wrong height",
+			corrupt: func(s *MemStore, _, fork []*chaingen.Block) {
+				s.LightBlockByHash(fork[1].Hash()).Height = 30
+			},
+			issues: []string{IndexHeights},
+		},
+		{
+			name: "wrong total work",
+			corrupt: func(s *MemStore, main, _ []*chaingen.Block) {
+				s.totalWorkMap[main[18].Hash()] = big.NewInt(1)
+			},
+			issues: []string{IndexWork},
+		},
+		{
+			name: "missing total work",
+			corrupt: func(s *MemStore, _, fork []*chaingen.Block) {
+				delete(s.totalWorkMap, fork[0].Hash())
+			},
+			issues: []string{IndexWork},
+		},
+		{
+			name: "total work of a removed block",
+			corrupt: func(s *MemStore, _, _ []*chaingen.Block) {
+				s.totalWorkMap[chainhash.Hash{1}] = big.NewInt(1)
+			},
+			issues: []string{IndexWork},
+		},
+		{
+			name: "wrong number of children",
+			corrupt: func(s *MemStore, main, _ []*chaingen.Block) {
+				s.childCount[main[19].Hash()] = 1
+			},
+			issues: []string{IndexChildren},
+		},
+		{
+			name: "finalized block missing from the height index",
+			corrupt: func(s *MemStore, main, _ []*chaingen.Block) {
+				delete(s.lightblockMap, int64(main[5].Height))
+			},
+			issues: []string{IndexHeights},
+		},
+		{
+			name: "fork block in the height index",
+			corrupt: func(s *MemStore, _, fork []*chaingen.Block) {
+				s.SetLightBlockByHeight(s.LightBlockByHash(fork[1].Hash()))
+			},
+			issues: []string{IndexHeights},
+		},
+		{
+			name: "fork head with children",
+			corrupt: func(s *MemStore, _, fork []*chaingen.Block) {
+				s.SetIsHead(fork[0].Hash())
+			},
+			issues: []string{IndexForkHeads},
+		},
+		{
+			name: "missing fork head",
+			corrupt: func(s *MemStore, main, _ []*chaingen.Block) {
+				s.SetIsNotHead(main[20].Hash())
+			},
+			issues: []string{IndexForkHeads},
+		},
+		{
+			name: "stale fork",
+			corrupt: func(s *MemStore, main, _ []*chaingen.Block) {
+				stale := NewLightBlock(main[2].Height, main[2].Header())
+				stale.Header.Nonce++
+				s.SetBlock(stale, s.TotalWorkAtBlock(main[1].Hash()))
+			},
+			issues: []string{IndexForkHeads},
+		},
+		{
+			name: "checkpoint not stored",
+			corrupt: func(s *MemStore, _, _ []*chaingen.Block) {
+				checkpoint := *s.LatestCheckPoint()
+				s.SetLatestCheckPoint(&checkpoint)
+			},
+			issues: []string{IndexCheckpoint},
+		},
+		{
+			name: "most difficult fork",
+			corrupt: func(s *MemStore, main, _ []*chaingen.Block) {
+				s.mostDifficultFork = s.LightBlockByHash(main[20].Hash())
+			},
+			issues: []string{IndexMostDifficultFork},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lc, main, fork := consistencyLightClient(t)
+			s := lc.btcStore.(*MemStore)
+			work := s.TotalWorkAtBlock(fork[1].Hash()).Int64()
+			tc.corrupt(s, main, fork)
+
+			issues := lc.CheckConsistency(false)
+			assert.DeepEqual(t, indexes(issues), tc.issues)
+			assert.Assert(t, !issues[0].Repaired)
+
+			issues = lc.CheckConsistency(true)
+			assert.DeepEqual(t, indexes(issues), tc.issues)
+			assert.Assert(t, issues[0].Repaired)
+			assert.Equal(t, len(lc.CheckConsistency(false)), 0)
+			assertIndexesConsistent(t, s)
+
+			assert.Equal(t, lc.btcStore.MostDifficultFork().Header.BlockHash(), fork[1].Hash())
+			assert.Equal(t, lc.btcStore.LatestCheckPoint().Header.BlockHash(), main[13].Hash())
+			assert.Equal(t, lc.btcStore.TotalWorkAtBlock(fork[1].Hash()).Int64(), work)
+		})
+	}
+}
+
+func TestCheckConsistencyRetention(t *testing.T) {
+	params := retentionParams()
+	g := chaingen.New(params)
+	blocks := chaingen.Chain(g.Extend(g.Genesis(), 100)[99])
+	lc := NewBTCLightClientWithData(params, chaingen.Headers(blocks), 0,
+		WithRetention(RetentionPolicy{Mode: RetainRetargetAnchors}))
+
+	// the anchors left by the retention policy are consistent
+	assert.Equal(t, len(lc.CheckConsistency(false)), 0)
+	assert.Assert(t, lc.btcStore.LightBlockAtHeight(40) != nil)
+	assert.Assert(t, lc.btcStore.LightBlockAtHeight(41) == nil)
+}
+
+func TestCheckProofOfWork(t *testing.T) {
+	lc, main, _ := consistencyLightClient(t)
+	lb := lc.btcStore.LightBlockByHash(main[10].Hash())
+	// the hash changes, the block must be stored by its new hash
+	lb.Header.Bits = 0x1d00ffff
+	issues := lc.CheckConsistency(true)
+	assert.Assert(t, len(issues) > 0)
+
+	issues = lc.CheckConsistency(true)
+	assert.DeepEqual(t, indexes(issues), []string{IndexProofOfWork})
+	assert.Equal(t, issues[0].Height, int32(10))
+	assert.Assert(t, !issues[0].Repaired)
+}
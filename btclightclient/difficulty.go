@@ -0,0 +1,97 @@
+package btclightclient
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// MaxTimewarp is how much the first block of a retarget period can be before
+// its parent, in seconds, on BIP94 networks.
+const MaxTimewarp = 600
+
+// checkBIP94Header runs the contextual header checks btcd skips with
+// BFFastAdd, using the BIP94 difficulty rules, and the BIP94 timewarp rule.
+func (lc *BTCLightClient) checkBIP94Header(header *wire.BlockHeader, prevNode blockchain.HeaderCtx) error {
+	expectedDifficulty, err := lc.calcNextRequiredDifficultyBIP94(prevNode, header.Timestamp)
+	if err != nil {
+		return err
+	}
+	if header.Bits != expectedDifficulty {
+		return blockchain.RuleError{
+			ErrorCode: blockchain.ErrUnexpectedDifficulty,
+			Description: fmt.Sprintf("block difficulty of %d is not the expected value of %d",
+				header.Bits, expectedDifficulty),
+		}
+	}
+
+	medianTime := blockchain.CalcPastMedianTime(prevNode)
+	if !header.Timestamp.After(medianTime) {
+		return blockchain.RuleError{
+			ErrorCode: blockchain.ErrTimeTooOld,
+			Description: fmt.Sprintf("block timestamp of %v is not after expected %v",
+				header.Timestamp, medianTime),
+		}
+	}
+
+	// The first block of a period can't be much older than its parent,
+	// otherwise miners can shorten the period timespan and lower the
+	// difficulty.
+	if (prevNode.Height()+1)%lc.BlocksPerRetarget() == 0 &&
+		header.Timestamp.Unix() < prevNode.Timestamp()-MaxTimewarp {
+		return fmt.Errorf("%w: block timestamp of %v is more than %ds before its parent",
+			ErrTimewarp, header.Timestamp, MaxTimewarp)
+	}
+	return nil
+}
+
+// calcNextRequiredDifficultyBIP94 is btcd calcNextRequiredDifficulty with
+// the BIP94 change: the new target is computed from the target of the first
+// block of the period, which can't use the minimum difficulty exception,
+// instead of the last block.
+func (lc *BTCLightClient) calcNextRequiredDifficultyBIP94(lastNode blockchain.HeaderCtx, newBlockTime time.Time) (uint32, error) {
+	params := lc.params
+	blocksPerRetarget := lc.BlocksPerRetarget()
+
+	if (lastNode.Height()+1)%blocksPerRetarget != 0 {
+		if !params.ReduceMinDifficulty {
+			return lastNode.Bits(), nil
+		}
+		// Return minimum difficulty when more than the desired amount
+		// of time has elapsed without mining a block.
+		allowMinTime := lastNode.Timestamp() + int64(params.MinDiffReductionTime/time.Second)
+		if newBlockTime.Unix() > allowMinTime {
+			return params.PowLimitBits, nil
+		}
+		// Otherwise return the difficulty of the last block which did
+		// not have the special minimum difficulty rule applied.
+		iterNode := lastNode
+		for iterNode != nil && iterNode.Height()%blocksPerRetarget != 0 &&
+			iterNode.Bits() == params.PowLimitBits {
+			iterNode = iterNode.Parent()
+		}
+		if iterNode == nil {
+			return params.PowLimitBits, nil
+		}
+		return iterNode.Bits(), nil
+	}
+
+	firstNode := lastNode.RelativeAncestorCtx(blocksPerRetarget - 1)
+	if firstNode == nil {
+		return 0, blockchain.AssertError("unable to obtain previous retarget block")
+	}
+
+	actualTimespan := lastNode.Timestamp() - firstNode.Timestamp()
+	adjustedTimespan := min(max(actualTimespan, lc.MinRetargetTimespan()), lc.MaxRetargetTimespan())
+
+	newTarget := blockchain.CompactToBig(firstNode.Bits())
+	newTarget.Mul(newTarget, big.NewInt(adjustedTimespan))
+	newTarget.Div(newTarget, big.NewInt(int64(params.TargetTimespan/time.Second)))
+	if newTarget.Cmp(params.PowLimit) > 0 {
+		newTarget.Set(params.PowLimit)
+	}
+	return blockchain.BigToCompact(newTarget), nil
+}
@@ -0,0 +1,266 @@
+package btclightclient
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gonative-cc/bitcoin-lightclient/chaingen"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"gotest.tools/assert"
+)
+
+// minDifficultyParams returns regtest based params with the testnet
+// difficulty rules and 20 blocks retarget periods, so chains spanning
+// several periods can be mined in tests.
+func minDifficultyParams(net wire.BitcoinNet) *chaincfg.Params {
+	params := chaincfg.RegressionNetParams
+	params.Net = net
+	params.PoWNoRetargeting = false
+	params.ReduceMinDifficulty = true
+	params.MinDiffReductionTime = 2 * params.TargetTimePerBlock
+	params.TargetTimespan = 20 * params.TargetTimePerBlock
+	return &params
+}
+
+func newGeneratedLightClient(g *chaingen.Generator) *BTCLightClient {
+	return NewBTCLightClientWithData(g.Params(), []wire.BlockHeader{g.Genesis().Header()}, 0)
+}
+
+func insertBlocks(t *testing.T, lc *BTCLightClient, blocks []*chaingen.Block) {
+	t.Helper()
+	for _, b := range blocks {
+		assert.NilError(t, lc.InsertHeader(b.Header()), "block %d", b.Height)
+		assert.NilError(t, lc.CleanUpFork())
+	}
+}
+
+func assertRuleError(t *testing.T, err error, code blockchain.ErrorCode) {
+	t.Helper()
+	var ruleErr blockchain.RuleError
+	assert.Assert(t, errors.As(err, &ruleErr), err)
+	assert.Equal(t, ruleErr.ErrorCode, code)
+}
+
+func TestMinDifficulty(t *testing.T) {
+	params := minDifficultyParams(wire.TestNet3)
+	g := chaingen.New(params)
+	lc := newGeneratedLightClient(g)
+
+	// the first period is mined 4 times faster than the target, the
+	// difficulty goes up at the retarget.
+	period := g.Extend(g.Genesis(), 20, chaingen.WithTimeDelta(params.TargetTimePerBlock/4))
+	insertBlocks(t, lc, period)
+	retarget := period[19]
+	bits := retarget.Header().Bits
+	assert.Assert(t, bits != params.PowLimitBits)
+
+	// blocks mined more than 20 minutes after their parent use the minimum
+	// difficulty.
+	slow := g.Extend(retarget, 10, chaingen.WithTimeDelta(time.Hour))
+	for _, b := range slow {
+		assert.Equal(t, b.Header().Bits, params.PowLimitBits)
+	}
+	insertBlocks(t, lc, slow)
+
+	// the next block on time walks back through the minimum difficulty
+	// blocks, past the finalized checkpoint, to the retarget block.
+	onTime := g.NextBlock(slow[9])
+	assert.Equal(t, onTime.Header().Bits, bits)
+	insertBlocks(t, lc, []*chaingen.Block{onTime})
+
+	// same walk from a fork
+	fork := g.NextBlock(slow[5])
+	assert.Equal(t, fork.Header().Bits, bits)
+	assert.NilError(t, lc.InsertHeader(fork.Header()))
+
+	// a block on time can't use the minimum difficulty
+	invalid := g.NextBlock(onTime, chaingen.WithBits(params.PowLimitBits))
+	assertRuleError(t, lc.InsertHeader(invalid.Header()), blockchain.ErrUnexpectedDifficulty)
+
+	// testnet3 retargets from the last block of the period, when it is
+	// mined at the minimum difficulty the difficulty is reset.
+	rest := g.Extend(onTime, 8, chaingen.WithTimeDelta(time.Hour))
+	insertBlocks(t, lc, rest)
+	next := g.NextBlock(rest[7])
+	assert.Equal(t, next.Height%20, int32(0))
+	assert.Equal(t, next.Header().Bits, params.PowLimitBits)
+	insertBlocks(t, lc, []*chaingen.Block{next})
+}
+
+func TestBIP94(t *testing.T) {
+	params := minDifficultyParams(TestNet4Net)
+	assert.Assert(t, EnforceBIP94(params))
+	g := chaingen.New(params)
+	g.BIP94 = true
+	lc := newGeneratedLightClient(g)
+
+	period := g.Extend(g.Genesis(), 20, chaingen.WithTimeDelta(params.TargetTimePerBlock/4))
+	period = append(period, g.Extend(period[19], 18)...)
+	// the last block of the period uses the minimum difficulty
+	last := g.NextBlock(period[len(period)-1], chaingen.WithTimeDelta(time.Hour))
+	assert.Equal(t, last.Header().Bits, params.PowLimitBits)
+	insertBlocks(t, lc, append(period, last))
+
+	t.Run("retarget from the first block of the period", func(t *testing.T) {
+		next := g.NextBlock(last)
+		assert.Equal(t, next.Height%20, int32(0))
+		assert.Assert(t, next.Header().Bits != params.PowLimitBits)
+		assert.NilError(t, lc.InsertHeader(next.Header()))
+
+		g.BIP94 = false
+		legacy := g.NextBlock(last)
+		g.BIP94 = true
+		assert.Equal(t, legacy.Header().Bits, params.PowLimitBits)
+		assertRuleError(t, lc.InsertHeader(legacy.Header()), blockchain.ErrUnexpectedDifficulty)
+	})
+
+	t.Run("minimum difficulty", func(t *testing.T) {
+		invalid := g.NextBlock(period[35], chaingen.WithBits(params.PowLimitBits))
+		assertRuleError(t, lc.InsertHeader(invalid.Header()), blockchain.ErrUnexpectedDifficulty)
+	})
+
+	t.Run("timewarp", func(t *testing.T) {
+		parentTime := last.Header().Timestamp
+		warped := g.NextBlock(last, chaingen.WithTimestamp(parentTime.Add(-(MaxTimewarp+1)*time.Second)))
+		assert.Assert(t, errors.Is(lc.InsertHeader(warped.Header()), ErrTimewarp))
+
+		allowed := g.NextBlock(last, chaingen.WithTimestamp(parentTime.Add(-MaxTimewarp*time.Second)))
+		assert.NilError(t, lc.InsertHeader(allowed.Header()))
+	})
+
+	t.Run("timestamp before median time past", func(t *testing.T) {
+		old := g.NextBlock(period[36], chaingen.WithTimestamp(period[30].Header().Timestamp))
+		assertRuleError(t, lc.InsertHeader(old.Header()), blockchain.ErrTimeTooOld)
+	})
+}
+
+func TestTestNet4Genesis(t *testing.T) {
+	assert.Equal(t, TestNet4Params.GenesisHash.String(), "00000000da84f2bafbbc53dee25a72ae507ff4914b867c565be350b0da8bf043")
+	assert.Equal(t, TestNet4Params.GenesisBlock.Header.MerkleRoot.String(), "7aa0a7ae1e223414cb807e40cd57e667b718e42aaf9306db9102fe28912b7b4e")
+	assert.Assert(t, EnforceBIP94(&TestNet4Params))
+	assert.Assert(t, !EnforceBIP94(&chaincfg.TestNet3Params))
+}
+
+func TestTestNet3Headers(t *testing.T) {
+	// testnet3 blocks 1 and 2
+	hash := func(s string) chainhash.Hash {
+		h, err := chainhash.NewHashFromStr(s)
+		assert.NilError(t, err)
+		return *h
+	}
+	block1 := wire.BlockHeader{
+		Version:    1,
+		PrevBlock:  *chaincfg.TestNet3Params.GenesisHash,
+		MerkleRoot: hash("f0315ffc38709d70ad5647e22048358dd3745f3ce3874223c80a7c92fab0c8ba"),
+		Timestamp:  time.Unix(1296688928, 0),
+		Bits:       0x1d00ffff,
+		Nonce:      1924588547,
+	}
+	block2 := wire.BlockHeader{
+		Version:    1,
+		PrevBlock:  block1.BlockHash(),
+		MerkleRoot: hash("20222eb90f5895556926c112bb5aa0df4ab5abc3107e21a6950aec3b2e3541e2"),
+		Timestamp:  time.Unix(1296688946, 0),
+		Bits:       0x1d00ffff,
+		Nonce:      875942400,
+	}
+	assert.Equal(t, block2.BlockHash().String(), "000000006c02c8ea6e4ff69651f7fcde348fb9d557a06e6957b65552002a7820")
+
+	genesis := chaincfg.TestNet3Params.GenesisBlock.Header
+	lc := NewBTCLightClientWithData(&chaincfg.TestNet3Params, []wire.BlockHeader{genesis}, 0)
+	assert.NilError(t, lc.InsertHeader(block1))
+	assert.NilError(t, lc.InsertHeader(block2))
+}
+
+func TestCustomSignetHeaders(t *testing.T) {
+	// blocks 1 and 2 of a signet with the OP_TRUE challenge, mined with
+	// chaingen.
+	headers := []string{
+		"00000020f61eee3b63a380a477a063af32b2bbc97c9ff9f01f2c4225e973988108000000209be492aa25b117cba458642878ef2655393c48e362316d959a495d40730cf658914d5fae77031ee9e88a00",
+		"00000020f32b5b9423f8762f64e5491a6e5483e0ca785d89c73aa82880b571e8c80000000f1dde856c49bc5150af620360d15a4c69d48ce1887689205ec1602f711938f9b0934d5fae77031e55663d00",
+	}
+	params := chaincfg.CustomSignetParams([]byte{0x51}, nil)
+	lc := NewBTCLightClientWithData(&params, []wire.BlockHeader{params.GenesisBlock.Header}, 0)
+	for _, headerHex := range headers {
+		header, err := BlockHeaderFromHex(headerHex)
+		assert.NilError(t, err)
+		assert.NilError(t, lc.InsertHeader(header))
+	}
+
+	// signet has no minimum difficulty exception, a regtest difficulty
+	// block is rejected.
+	g := chaingen.New(&params)
+	easy := g.NextBlock(g.Genesis(), chaingen.WithBits(chaincfg.RegressionNetParams.PowLimitBits))
+	assertRuleError(t, lc.InsertHeader(easy.Header()), blockchain.ErrUnexpectedDifficulty)
+}
+
+// retargetParams returns regtest based params with the mainnet retarget
+// rules: 2016 blocks periods and no minimum difficulty exception.
+func retargetParams() *chaincfg.Params {
+	params := chaincfg.RegressionNetParams
+	params.PoWNoRetargeting = false
+	params.ReduceMinDifficulty = false
+	return &params
+}
+
+func TestRetargetAcrossLongFork(t *testing.T) {
+	params := retargetParams()
+	g := chaingen.New(params)
+	fast := chaingen.WithTimeDelta(params.TargetTimePerBlock / 2)
+	main := chaingen.Chain(g.Extend(g.Genesis(), 2010, fast)[2009])
+	lc := NewBTCLightClientWithData(params, chaingen.Headers(main), 0)
+
+	// the main chain crosses the retarget at height 2016, the first block
+	// of the period is finalized.
+	main = append(main, g.Extend(main[2010], 6, fast)...)
+	assert.Assert(t, main[2016].Header().Bits != params.PowLimitBits)
+	insertBlocks(t, lc, main[2011:])
+	checkpoint := lc.btcStore.LatestCheckPoint().Height
+	assert.Equal(t, checkpoint, int32(2016-MaxForkAge+1))
+
+	// a fork from the checkpoint long enough to cross the retarget too: the
+	// last block of the period is in the fork.
+	fork := g.Extend(main[checkpoint], 2018-int(checkpoint))
+	retarget := fork[len(fork)-3]
+	assert.Equal(t, retarget.Height, int32(2016))
+	assert.Assert(t, retarget.Header().Bits != main[2016].Header().Bits)
+
+	invalid := g.NextBlock(retarget.Parent, chaingen.WithBits(main[2016].Header().Bits))
+	for _, b := range fork[:len(fork)-3] {
+		assert.NilError(t, lc.InsertHeader(b.Header()), "block %d", b.Height)
+	}
+	assertRuleError(t, lc.InsertHeader(invalid.Header()), blockchain.ErrUnexpectedDifficulty)
+
+	insertBlocks(t, lc, fork[len(fork)-3:])
+	assert.Equal(t, lc.btcStore.MostDifficultFork().Header.BlockHash(), fork[len(fork)-1].Hash())
+}
+
+func TestRetargetMissingAncestor(t *testing.T) {
+	params := retargetParams()
+	g := chaingen.New(params)
+	fast := chaingen.WithTimeDelta(params.TargetTimePerBlock / 2)
+	blocks := chaingen.Chain(g.Extend(g.Genesis(), 2016, fast)[2015])
+
+	// the client is bootstrapped after the first block of the period, the
+	// retarget can't be validated.
+	lc := NewBTCLightClientWithData(params, chaingen.Headers(blocks[100:2016]), 100)
+	err := lc.InsertHeader(blocks[2016].Header())
+	assert.Assert(t, errors.Is(err, ErrMissingAncestor), err)
+
+	// blocks in the middle of the period only need the median time blocks
+	lc = NewBTCLightClientWithData(params, chaingen.Headers(blocks[100:2010]), 100)
+	insertBlocks(t, lc, blocks[2010:2016])
+	err = lc.InsertHeader(blocks[2016].Header())
+	assert.Assert(t, errors.Is(err, ErrMissingAncestor), err)
+
+	// the median time of a block right after the bootstrap header needs the
+	// blocks before it
+	lc = NewBTCLightClientWithData(params, chaingen.Headers(blocks[100:101]), 100)
+	err = lc.InsertHeader(blocks[101].Header())
+	assert.Assert(t, errors.Is(err, ErrMissingAncestor), err)
+}
@@ -5,11 +5,31 @@ import "errors"
 // Light client errors
 var ErrForkTooOld = errors.New("fork too old")
 var ErrBlockNotInChain = errors.New("block not in the chain")
+var ErrDuplicateHeader = errors.New("header already stored")
 var ErrInvalidHeaderSize = errors.New("invalid header size, must be 80 bytes")
 var ErrParentBlockNotInChain = errors.New("parent block not in chain")
 var ErrBlockIsNotForkHead = errors.New("block is not a fork head")
+var ErrNoHeaders = errors.New("no headers")
+var ErrHeaderNotLinked = errors.New("header doesn't build on the previous header")
+var ErrInvalidAnchor = errors.New("invalid trusted anchor")
+var ErrMissingAncestor = errors.New("ancestor needed for header validation not found")
+var ErrUnknownDeployment = errors.New("unknown deployment")
+var ErrHeightOutOfRange = errors.New("height out of range")
+var ErrInvalidRetention = errors.New("invalid retention policy")
+var ErrTimewarp = errors.New("timewarp attack")
+var ErrStoreUnavailable = errors.New("light client store is unavailable")
+var ErrTooManyForks = errors.New("too many forks")
+var ErrHopelessFork = errors.New("fork can't overtake the best chain before finalization")
+var ErrInvalidAccumulator = errors.New("header accumulator doesn't match the finalized chain")
+var ErrInvalidAncestorProof = errors.New("invalid ancestor proof")
 
 // SPV errors
 var ErrValueIsNotMerkleLeaf = errors.New("value doesn't exist in merkle tree")
 var ErrMerkleDecodeOutbound = errors.New("out-bound of vHash")
 var ErrMerkleDecodeHashNumberInvalid = errors.New("number of hashes reach to limit")
+var ErrMerkleDecodeBitsOutbound = errors.New("out-bound of vBits")
+var ErrMerkleInvalidTxCount = errors.New("invalid number of transactions in merkle tree")
+var ErrMerkleTrailingData = errors.New("trailing data after merkle tree")
+var ErrMerkleUnusedBits = errors.New("merkle tree has unused bits")
+var ErrMerkleUnusedHashes = errors.New("merkle tree has unused hashes")
+var ErrMerkleDuplicateNode = errors.New("merkle tree right node identical to left node")
@@ -0,0 +1,40 @@
+package btclightclient
+
+// The fork choice selects the best tip, the fork head with the most total
+// work. Between heads with the same total work the first one received wins,
+// as in Bitcoin Core, so a competing block with the same work doesn't cause a
+// reorg. The store numbers the blocks in the order it receives them, so the
+// choice only depends on the insertion order: it is the same after removing
+// other blocks as if they had never been inserted.
+
+// betterTip reports whether a is a better tip than b: it has more total work,
+// or the same work and was received first.
+func (s *MemStore) betterTip(a, b *LightBlock) bool {
+	workA := s.totalWorkMap[a.Header.BlockHash()]
+	workB := s.totalWorkMap[b.Header.BlockHash()]
+	switch {
+	case workB == nil:
+		return workA != nil || a.seq < b.seq
+	case workA == nil:
+		return false
+	}
+	if cmp := workA.Cmp(workB); cmp != 0 {
+		return cmp > 0
+	}
+	return a.seq < b.seq
+}
+
+// selectMostDifficultFork recomputes the best tip from the fork heads. Without
+// fork heads, the checkpoint is the tip.
+func (s *MemStore) selectMostDifficultFork() {
+	s.mostDifficultFork = nil
+	for hash := range s.latestBlockHashOfFork {
+		lb := s.lightBlockByHashMap[hash]
+		if lb != nil && (s.mostDifficultFork == nil || s.betterTip(lb, s.mostDifficultFork)) {
+			s.mostDifficultFork = lb
+		}
+	}
+	if s.mostDifficultFork == nil && s.latestcheckpoint != nil && s.lightBlockByHashMap[s.latestcheckpoint.Header.BlockHash()] == s.latestcheckpoint {
+		s.mostDifficultFork = s.latestcheckpoint
+	}
+}
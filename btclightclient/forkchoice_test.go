@@ -0,0 +1,220 @@
+package btclightclient
+
+import (
+	"errors"
+	"math/big"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/gonative-cc/bitcoin-lightclient/chaingen"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"gotest.tools/assert"
+)
+
+// forkTree is a reference model of the fork choice over a tree of blocks.
+type forkTree struct {
+	s        *MemStore
+	blocks   []*LightBlock
+	work     map[chainhash.Hash]*big.Int
+	order    map[chainhash.Hash]int
+	children map[chainhash.Hash]int
+	removed  map[chainhash.Hash]bool
+}
+
+// forkTreeBits are the difficulties of the random blocks, the work of the
+// forks often ties.
+var forkTreeBits = []uint32{0x207fffff, 0x207fffff, 0x2003ffff}
+
+func newForkTree() *forkTree {
+	root := NewLightBlock(0, wire.BlockHeader{Bits: forkTreeBits[0]})
+	t := &forkTree{
+		s:        NewMemStore(),
+		work:     map[chainhash.Hash]*big.Int{},
+		order:    map[chainhash.Hash]int{},
+		children: map[chainhash.Hash]int{},
+		removed:  map[chainhash.Hash]bool{},
+	}
+	t.s.SetLatestCheckPoint(root)
+	t.s.SetLightBlockByHeight(root)
+	t.insert(root)
+	return t
+}
+
+// insert inserts lb as InsertHeader does.
+func (t *forkTree) insert(lb *LightBlock) {
+	hash := lb.Header.BlockHash()
+	parentWork, ok := t.work[lb.Header.PrevBlock]
+	if !ok {
+		parentWork = big.NewInt(0)
+	}
+	t.work[hash] = new(big.Int).Add(parentWork, blockchain.CalcWork(lb.Header.Bits))
+	t.order[hash] = len(t.order)
+	t.children[lb.Header.PrevBlock]++
+
+	t.s.SetBlock(lb, parentWork)
+	t.s.SetIsNotHead(lb.Header.PrevBlock)
+	t.s.SetIsHead(hash)
+	t.blocks = append(t.blocks, lb)
+}
+
+// randomBlock returns a new block on a random stored block.
+func (t *forkTree) randomBlock(r *rand.Rand) *LightBlock {
+	var parent *LightBlock
+	for parent == nil || t.removed[parent.Header.BlockHash()] {
+		parent = t.blocks[r.Intn(len(t.blocks))]
+	}
+	return NewLightBlock(parent.Height+1, wire.BlockHeader{
+		PrevBlock: parent.Header.BlockHash(),
+		Bits:      forkTreeBits[r.Intn(len(forkTreeBits))],
+		Nonce:     r.Uint32(),
+	})
+}
+
+// removeHead removes a random fork head, but not the finalized root.
+func (t *forkTree) removeHead(r *rand.Rand) {
+	heads := t.s.LatestBlockHashOfFork()
+	if len(heads) == 0 {
+		return
+	}
+	hash := heads[r.Intn(len(heads))]
+	if hash == t.blocks[0].Header.BlockHash() {
+		return
+	}
+	t.children[t.s.LightBlockByHash(hash).Header.PrevBlock]--
+	t.s.RemoveBlock(hash)
+	t.removed[hash] = true
+}
+
+// expectedTip returns the block without children with the most work, first
+// inserted among equals. The root is finalized, it is only the tip without
+// other blocks.
+func (t *forkTree) expectedTip() chainhash.Hash {
+	best := t.blocks[0].Header.BlockHash()
+	for _, lb := range t.blocks[1:] {
+		hash := lb.Header.BlockHash()
+		if t.removed[hash] || t.children[hash] > 0 {
+			continue
+		}
+		cmp := t.work[hash].Cmp(t.work[best])
+		if best == t.blocks[0].Header.BlockHash() || cmp > 0 || (cmp == 0 && t.order[hash] < t.order[best]) {
+			best = hash
+		}
+	}
+	return best
+}
+
+func TestForkChoiceRandomTrees(t *testing.T) {
+	for seed := int64(0); seed < 200; seed++ {
+		r := rand.New(rand.NewSource(seed))
+		tree := newForkTree()
+		for i := 0; i < 60; i++ {
+			if r.Intn(4) == 0 {
+				tree.removeHead(r)
+			} else {
+				tree.insert(tree.randomBlock(r))
+			}
+			tip := tree.s.MostDifficultFork().Header.BlockHash()
+			assert.Equal(t, tip, tree.expectedTip(), "seed %d, step %d", seed, i)
+		}
+
+		// the tip is the same as if the removed blocks were never inserted
+		fresh := newForkTree()
+		for _, lb := range tree.blocks[1:] {
+			if !tree.removed[lb.Header.BlockHash()] {
+				fresh.insert(NewLightBlock(lb.Height, lb.Header))
+			}
+		}
+		assert.Equal(t, fresh.s.MostDifficultFork().Header.BlockHash(),
+			tree.s.MostDifficultFork().Header.BlockHash(), "seed %d", seed)
+		assert.Equal(t, len(tree.s.CheckConsistency(false)), 0, "seed %d", seed)
+	}
+}
+
+func TestForkChoiceFirstSeen(t *testing.T) {
+	g := chaingen.New(&chaincfg.RegressionNetParams)
+	main := chaingen.Chain(g.Extend(g.Genesis(), 20)[19])
+	lc := NewBTCLightClientWithData(g.Params(), chaingen.Headers(main), 0)
+	tip := main[20]
+
+	// a fork with the same work doesn't replace the tip
+	sibling := g.NextBlock(main[19])
+	assert.NilError(t, lc.InsertHeader(sibling.Header()))
+	assert.Equal(t, lc.btcStore.MostDifficultFork().Header.BlockHash(), tip.Hash())
+
+	// removing the tip selects the other fork head, then their parent
+	s := lc.btcStore.(*MemStore)
+	s.RemoveBlock(tip.Hash())
+	assert.Equal(t, lc.btcStore.MostDifficultFork().Header.BlockHash(), sibling.Hash())
+	s.RemoveBlock(sibling.Hash())
+	assert.Equal(t, lc.btcStore.MostDifficultFork().Header.BlockHash(), main[19].Hash())
+	assert.Assert(t, lc.btcStore.IsForkHead(main[19].Hash()))
+	assert.Equal(t, len(lc.CheckConsistency(false)), 0)
+}
+
+// TestForkChoiceMinDifficultyFork checks a fork of minimum difficulty blocks,
+// older than the finality depth before it has the most work, becomes the best
+// chain and is finalized.
+func TestForkChoiceMinDifficultyFork(t *testing.T) {
+	params := minDifficultyParams(wire.TestNet3)
+	g := chaingen.New(params)
+	lc := newGeneratedLightClient(g)
+	period := g.Extend(g.Genesis(), 20, chaingen.WithTimeDelta(params.TargetTimePerBlock/4))
+	a := g.NextBlock(period[19])
+	insertBlocks(t, lc, append(period, a))
+	assert.Equal(t, lc.btcStore.LatestCheckPoint().Height, int32(14))
+
+	// b[2] has 4 times the work of the minimum difficulty blocks, the fork
+	// overtakes a at age 9
+	b := g.Extend(period[19], 2, chaingen.WithTimeDelta(time.Hour))
+	b = append(b, g.NextBlock(b[1]))
+	assert.Equal(t, b[2].Header().Bits, a.Header().Bits)
+	insertBlocks(t, lc, b)
+	assert.Equal(t, lc.btcStore.MostDifficultFork().Header.BlockHash(), b[2].Hash())
+	assert.Equal(t, lc.btcStore.LatestCheckPoint().Height, int32(16))
+	assert.Equal(t, lc.btcStore.LightBlockAtHeight(15).Header.BlockHash(), period[14].Hash())
+
+	b = append(b, g.Extend(b[2], 12)...)
+	insertBlocks(t, lc, b[3:])
+	assert.Equal(t, lc.btcStore.MostDifficultFork().Header.BlockHash(), b[14].Hash())
+	assert.Assert(t, lc.btcStore.LightBlockByHash(a.Hash()) == nil)
+	assert.Equal(t, len(lc.CheckConsistency(false)), 0)
+}
+
+// TestForkChoiceRandomWork inserts blocks of varying work on random forks,
+// the fork cleanup never fails and the best chain can always be extended.
+func TestForkChoiceRandomWork(t *testing.T) {
+	params := minDifficultyParams(wire.TestNet3)
+	deltas := []time.Duration{params.TargetTimePerBlock, params.TargetTimePerBlock / 4, time.Hour}
+	for seed := int64(0); seed < 50; seed++ {
+		r := rand.New(rand.NewSource(seed))
+		g := chaingen.New(params)
+		lc := newGeneratedLightClient(g)
+		// the blocks on time have 4 times the work of the minimum
+		// difficulty blocks after the first retarget
+		insertBlocks(t, lc, g.Extend(g.Genesis(), 20, chaingen.WithTimeDelta(params.TargetTimePerBlock/4)))
+		for i := 0; i < 80; i++ {
+			heads := lc.btcStore.LatestBlockHashOfFork()
+			parent := g.Block(heads[r.Intn(len(heads))])
+			if r.Intn(3) == 0 && parent.Parent != nil {
+				parent = parent.Parent
+			}
+			b := g.NextBlock(parent, chaingen.WithTimeDelta(deltas[r.Intn(len(deltas))]))
+			err := lc.InsertHeader(b.Header())
+			if errors.Is(err, ErrForkTooOld) {
+				continue
+			}
+			assert.NilError(t, err, "seed %d, step %d", seed, i)
+			assert.NilError(t, lc.CleanUpFork(), "seed %d, step %d", seed, i)
+
+			tip := g.Block(lc.btcStore.MostDifficultFork().Header.BlockHash())
+			next := g.NextBlock(tip)
+			assert.NilError(t, lc.CheckHeader(tip.Header(), next.Header()), "seed %d, step %d", seed, i)
+		}
+		assert.Equal(t, len(lc.CheckConsistency(false)), 0, "seed %d", seed)
+	}
+}
@@ -0,0 +1,76 @@
+package btclightclient
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gonative-cc/bitcoin-lightclient/chaingen"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"gotest.tools/assert"
+)
+
+func TestGeneratedChain(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	g := chaingen.New(params)
+	main := g.Extend(g.Genesis(), 20, chaingen.WithTxs(2))
+
+	lc := NewBTCLightClientWithData(params, chaingen.Headers(chaingen.Chain(main[9])), 0)
+	for _, b := range main[10:] {
+		assert.NilError(t, lc.InsertHeader(b.Header()))
+		assert.NilError(t, lc.CleanUpFork())
+	}
+	assert.Equal(t, lc.btcStore.LatestFinalizedHeight(), int64(main[19].Height-MaxForkAge+1))
+
+	// fork within the finality window, it becomes the best chain once it
+	// has more work.
+	fork := g.Extend(main[15], 5)
+	for _, b := range fork {
+		assert.NilError(t, lc.InsertHeader(b.Header()))
+	}
+	assert.Equal(t, len(lc.btcStore.LatestBlockHashOfFork()), 2)
+	assert.Equal(t, lc.MainChain()[len(lc.MainChain())-1].Header.BlockHash(), fork[4].Hash())
+
+	// a fork from a finalized block is rejected
+	old := g.NextBlock(main[1])
+	assert.Assert(t, errors.Is(lc.InsertHeader(old.Header()), ErrForkTooOld))
+}
+
+func TestGeneratedSPVProof(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	g := chaingen.New(params)
+	blocks := g.Extend(g.Genesis(), 12, chaingen.WithTxs(4))
+	lc := NewBTCLightClientWithData(params, chaingen.Headers(chaingen.Chain(blocks[11])), 0)
+
+	run := func(t *testing.T, b *chaingen.Block, expected SPVStatus) {
+		for _, txID := range b.TxIDs() {
+			proof, err := b.TxOutProof(txID)
+			assert.NilError(t, err)
+			spvProof, err := SPVProofFromHex(proof, txID.String())
+			assert.NilError(t, err)
+			assert.Equal(t, lc.VerifySPV(*spvProof), expected)
+		}
+	}
+
+	t.Run("finalized block", func(t *testing.T) {
+		run(t, blocks[2], ValidSPVProof)
+	})
+	t.Run("block not finalized", func(t *testing.T) {
+		run(t, blocks[11], PartialValidSPVProof)
+	})
+	t.Run("block not in the light client", func(t *testing.T) {
+		run(t, g.NextBlock(blocks[11], chaingen.WithTxs(1)), InvalidSPVProof)
+	})
+	t.Run("proof of several transactions", func(t *testing.T) {
+		txIDs := blocks[3].TxIDs()
+		proof, err := blocks[3].TxOutProof(txIDs[0], txIDs[4])
+		assert.NilError(t, err)
+		for _, txID := range []string{txIDs[0].String(), txIDs[4].String()} {
+			spvProof, err := SPVProofFromHex(proof, txID)
+			assert.NilError(t, err)
+			assert.Equal(t, lc.VerifySPV(*spvProof), ValidSPVProof)
+		}
+		_, err = SPVProofFromHex(proof, txIDs[2].String())
+		assert.ErrorContains(t, err, ErrValueIsNotMerkleLeaf.Error())
+	})
+}
@@ -0,0 +1,69 @@
+package btclightclient
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// InsertOutcome is how a header was handled by InsertHeaderWithOutcome.
+type InsertOutcome uint8
+
+const (
+	// HeaderRejected is a header failing validation or not connecting to
+	// the stored blocks.
+	HeaderRejected InsertOutcome = iota
+	// HeaderInserted is a header extending a fork head.
+	HeaderInserted
+	// HeaderDuplicate is a header already stored, nothing is changed.
+	HeaderDuplicate
+	// HeaderNewFork is a header creating a fork from a block which already
+	// has children.
+	HeaderNewFork
+)
+
+var insertOutcomeStrings = map[InsertOutcome]string{
+	HeaderRejected:  "rejected",
+	HeaderInserted:  "inserted",
+	HeaderDuplicate: "duplicate",
+	HeaderNewFork:   "new_fork",
+}
+
+func (o InsertOutcome) String() string {
+	if str, ok := insertOutcomeStrings[o]; ok {
+		return str
+	}
+	return fmt.Sprintf("InsertOutcome(%d)", o)
+}
+
+func (o InsertOutcome) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+func (o *InsertOutcome) UnmarshalText(text []byte) error {
+	for outcome, str := range insertOutcomeStrings {
+		if str == string(text) {
+			*o = outcome
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown insert outcome %q", text)
+}
+
+// InsertHeaderWithOutcome inserts header as InsertHeader, and reports whether
+// it extended a fork, created a new one or was already stored.
+// A duplicate header is not an error.
+func (lc *BTCLightClient) InsertHeaderWithOutcome(header wire.BlockHeader) (InsertOutcome, error) {
+	return lc.InsertHeaderFrom("", header)
+}
+
+// InsertHeaderFrom inserts header as InsertHeaderWithOutcome, source
+// identifies the submitter of the header in the audit log.
+func (lc *BTCLightClient) InsertHeaderFrom(source string, header wire.BlockHeader) (InsertOutcome, error) {
+	outcome, err := lc.insert(source, header)
+	if errors.Is(err, ErrDuplicateHeader) {
+		return HeaderDuplicate, nil
+	}
+	return outcome, err
+}
@@ -0,0 +1,47 @@
+package btclightclient
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gonative-cc/bitcoin-lightclient/chaingen"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"gotest.tools/assert"
+)
+
+func TestInsertHeaderWithOutcome(t *testing.T) {
+	g := chaingen.New(&chaincfg.RegressionNetParams)
+	main := chaingen.Chain(g.Extend(g.Genesis(), 20)[19])
+	lc := NewBTCLightClientWithData(g.Params(), chaingen.Headers(main), 0)
+
+	next := g.NextBlock(main[20])
+	outcome, err := lc.InsertHeaderWithOutcome(next.Header())
+	assert.NilError(t, err)
+	assert.Equal(t, outcome, HeaderInserted)
+
+	outcome, err = lc.InsertHeaderWithOutcome(next.Header())
+	assert.NilError(t, err)
+	assert.Equal(t, outcome, HeaderDuplicate)
+
+	fork := g.Extend(main[19], 2)
+	outcome, err = lc.InsertHeaderWithOutcome(fork[0].Header())
+	assert.NilError(t, err)
+	assert.Equal(t, outcome, HeaderNewFork)
+	outcome, err = lc.InsertHeaderWithOutcome(fork[1].Header())
+	assert.NilError(t, err)
+	assert.Equal(t, outcome, HeaderInserted)
+
+	orphan := g.NextBlock(g.NextBlock(next))
+	outcome, err = lc.InsertHeaderWithOutcome(orphan.Header())
+	assert.Assert(t, errors.Is(err, ErrParentBlockNotInChain), err)
+	assert.Equal(t, outcome, HeaderRejected)
+
+	text, err := HeaderNewFork.MarshalText()
+	assert.NilError(t, err)
+	assert.Equal(t, string(text), "new_fork")
+	var decoded InsertOutcome
+	assert.NilError(t, decoded.UnmarshalText(text))
+	assert.Equal(t, decoded, HeaderNewFork)
+	assert.ErrorContains(t, decoded.UnmarshalText([]byte("forked")), "unknown insert outcome")
+}
@@ -4,6 +4,7 @@ import (
 	"math/big"
 
 	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
 )
 
@@ -12,16 +13,32 @@ var _ blockchain.HeaderCtx = (*HeaderContext)(nil)
 type LightBlock struct {
 	Height int32
 	Header wire.BlockHeader
+	// skip is the hash of an ancestor used to walk the chain in O(log n),
+	// nil when it is not in the store.
+	skip *chainhash.Hash
+	// seq numbers the blocks in the order the store received them, it
+	// breaks the fork choice ties.
+	seq uint64
 }
 
 func (lb *LightBlock) CalcWork() *big.Int {
 	return blockchain.CalcWork(lb.Header.Bits)
 }
 
+// HeaderContext is the context of a block descending from the checkpoint,
+// to validate its child.
 type HeaderContext struct {
 	lightBlock *LightBlock
 	store      Store
-	fork       []*LightBlock
+	// missing is shared by the contexts derived from the same header, it
+	// records the ancestors the validation needed but the store doesn't
+	// have.
+	missing *missingAncestor
+}
+
+type missingAncestor struct {
+	height int32
+	found  bool
 }
 
 func (h *HeaderContext) Height() int32 {
@@ -40,18 +57,45 @@ func (h *HeaderContext) Parent() blockchain.HeaderCtx {
 	return h.RelativeAncestorCtx(1)
 }
 
+// RelativeAncestorCtx returns the ancestor distance blocks before the
+// header. Finalized ancestors are looked up by height, the ones above the
+// checkpoint with the skip list. It returns nil before the genesis block and
+// when the ancestor is not in the store, the latter is reported by
+// MissingAncestor.
 func (h *HeaderContext) RelativeAncestorCtx(
 	distance int32) blockchain.HeaderCtx {
-	if distance <= h.Height() {
-		if int(distance) < len(h.fork) && len(h.fork) != 0 {
-			return NewHeaderContext(h.fork[distance], h.store, h.fork[distance:])
+	if distance > h.Height() {
+		return nil
+	}
+
+	ancestorHeight := h.Height() - distance
+	var blockAtHeight *LightBlock
+	if int64(ancestorHeight) <= h.store.LatestFinalizedHeight() {
+		blockAtHeight = h.store.LightBlockAtHeight(int64(ancestorHeight))
+	} else {
+		blockAtHeight = h.lightBlock.ancestor(h.store, ancestorHeight)
+	}
+	if blockAtHeight == nil {
+		if !h.missing.found || ancestorHeight > h.missing.height {
+			h.missing.height, h.missing.found = ancestorHeight, true
 		}
+		return nil
+	}
+	return h.derive(blockAtHeight)
+}
+
+// MissingAncestor returns the height of the most recent ancestor a lookup
+// didn't find in the store.
+func (h *HeaderContext) MissingAncestor() (int32, bool) {
+	return h.missing.height, h.missing.found
+}
 
-		ancestorHeight := h.Height() - distance
-		blockAtHeight := h.store.LightBlockAtHeight(int64(ancestorHeight))
-		return NewHeaderContext(blockAtHeight, h.store, []*LightBlock{})
+func (h *HeaderContext) derive(lightBlock *LightBlock) *HeaderContext {
+	return &HeaderContext{
+		lightBlock: lightBlock,
+		store:      h.store,
+		missing:    h.missing,
 	}
-	return nil
 }
 
 func NewLightBlock(height int32, header wire.BlockHeader) *LightBlock {
@@ -61,10 +105,10 @@ func NewLightBlock(height int32, header wire.BlockHeader) *LightBlock {
 	}
 }
 
-func NewHeaderContext(lightBlock *LightBlock, store Store, fork []*LightBlock) *HeaderContext {
+func NewHeaderContext(lightBlock *LightBlock, store Store) *HeaderContext {
 	return &HeaderContext{
 		lightBlock: lightBlock,
 		store:      store,
-		fork:       fork,
+		missing:    &missingAncestor{},
 	}
 }
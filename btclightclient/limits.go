@@ -0,0 +1,114 @@
+package btclightclient
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// InsertLimits bound the forks accepted by the light client, so that valid
+// but useless headers can't bloat the store. The zero value disables them.
+type InsertLimits struct {
+	// MaxForks is the maximum number of fork heads, including the best
+	// tip. A header creating a fork beyond it is rejected with
+	// ErrTooManyForks.
+	MaxForks int
+	// RejectHopelessForks rejects with ErrHopelessFork the headers of a
+	// fork which can't overtake the best tip before the best chain
+	// finalizes the block after their fork point. The next fork blocks
+	// are assumed to have at most the work of the difficulty the fork can
+	// require, see maxForkBlockWork.
+	RejectHopelessForks bool
+}
+
+func (l InsertLimits) Validate() error {
+	if l.MaxForks < 0 {
+		return fmt.Errorf("max forks must not be negative, got %d", l.MaxForks)
+	}
+	return nil
+}
+
+// WithInsertLimits sets the insertion limits, disabled by default.
+func WithInsertLimits(limits InsertLimits) Option {
+	return func(lc *BTCLightClient) {
+		lc.limits = limits
+	}
+}
+
+// checkLimits checks the insertion of header on top of parent against the
+// limits. It runs before the header validation as it is cheaper.
+func (lc *BTCLightClient) checkLimits(parent *LightBlock, header wire.BlockHeader) error {
+	parentHash := parent.Header.BlockHash()
+	if lc.limits.MaxForks > 0 && !lc.btcStore.IsForkHead(parentHash) {
+		if forks := len(lc.btcStore.LatestBlockHashOfFork()); forks >= lc.limits.MaxForks {
+			return fmt.Errorf("%w: %d fork heads, limit %d", ErrTooManyForks, forks, lc.limits.MaxForks)
+		}
+	}
+
+	if !lc.limits.RejectHopelessForks {
+		return nil
+	}
+	best := lc.btcStore.MostDifficultFork()
+	if best == nil || parentHash == best.Header.BlockHash() {
+		return nil
+	}
+	forkPoint := lc.forkPoint(parent, best)
+	if forkPoint == nil {
+		return nil
+	}
+
+	// the fork can grow until the best chain reaches the height
+	// finalizing the block after the fork point. A fork higher than the
+	// best tip can still get as many blocks as the best chain needs, it
+	// can win after more than finality depth blocks.
+	finalizingHeight := forkPoint.Height + lc.finalityDepth
+	maxBlocks := max(finalizingHeight-parent.Height, finalizingHeight-best.Height, 1)
+	work := lc.maxForkBlockWork(parent, header, parent.Height+maxBlocks)
+	maxWork := new(big.Int).Mul(work, big.NewInt(int64(maxBlocks)))
+	maxWork.Add(maxWork, lc.btcStore.TotalWorkAtBlock(parentHash))
+	// the first block received wins the ties
+	if maxWork.Cmp(lc.btcStore.TotalWorkAtBlock(best.Header.BlockHash())) <= 0 {
+		return fmt.Errorf("%w: fork from height %d", ErrHopelessFork, forkPoint.Height)
+	}
+	return nil
+}
+
+// maxForkBlockWork returns the most work of the fork blocks after parent up
+// to maxHeight, header being the first one. A minimum difficulty block is
+// followed by blocks at the difficulty of the last block before the minimum
+// difficulty blocks, and a retarget can raise the difficulty.
+func (lc *BTCLightClient) maxForkBlockWork(parent *LightBlock, header wire.BlockHeader, maxHeight int32) *big.Int {
+	params := lc.params
+	perRetarget := lc.BlocksPerRetarget()
+	bits := header.Bits
+	if params.ReduceMinDifficulty && bits == params.PowLimitBits && (parent.Height+1)%perRetarget != 0 {
+		for lb := parent; lb != nil; lb = lc.btcStore.LightBlockByHash(lb.Header.PrevBlock) {
+			if lb.Header.Bits != params.PowLimitBits || lb.Height%perRetarget == 0 {
+				bits = lb.Header.Bits
+				break
+			}
+		}
+	}
+	work := blockchain.CalcWork(bits)
+	if !params.PoWNoRetargeting && (parent.Height+1)/perRetarget != maxHeight/perRetarget {
+		work.Mul(work, big.NewInt(params.RetargetAdjustmentFactor))
+	}
+	return work
+}
+
+// forkPoint returns the last block of the chain of lb which is on the chain
+// of tip, nil when there is none in the store.
+func (lc *BTCLightClient) forkPoint(lb, tip *LightBlock) *LightBlock {
+	for lb != nil {
+		if lb.Height <= tip.Height {
+			ancestor := lc.ancestorOf(tip, lb.Height)
+			if ancestor != nil && ancestor.Header.BlockHash() == lb.Header.BlockHash() {
+				return lb
+			}
+		}
+		lb = lc.btcStore.LightBlockByHash(lb.Header.PrevBlock)
+	}
+	return nil
+}
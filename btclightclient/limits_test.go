@@ -0,0 +1,79 @@
+package btclightclient
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gonative-cc/bitcoin-lightclient/chaingen"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gotest.tools/assert"
+)
+
+func TestMaxForks(t *testing.T) {
+	g := chaingen.New(&chaincfg.RegressionNetParams)
+	blocks := chaingen.Chain(g.Extend(g.Genesis(), 20)[19])
+	lc := NewBTCLightClientWithData(g.Params(), chaingen.Headers(blocks), 0,
+		WithInsertLimits(InsertLimits{MaxForks: 2}))
+	m := NewMetrics(prometheus.NewRegistry())
+	lc.SetMetrics(m)
+
+	fork := g.NextBlock(blocks[18])
+	assert.NilError(t, lc.InsertHeader(fork.Header()))
+	err := lc.InsertHeader(g.NextBlock(blocks[17]).Header())
+	assert.Assert(t, errors.Is(err, ErrTooManyForks), err)
+	assert.Equal(t, testutil.ToFloat64(m.insertTotal.WithLabelValues("limited")), float64(1))
+
+	// the fork heads can still be extended
+	assert.NilError(t, lc.InsertHeader(g.NextBlock(fork).Header()))
+	assert.NilError(t, lc.InsertHeader(g.NextBlock(blocks[20]).Header()))
+
+	assert.ErrorContains(t, InsertLimits{MaxForks: -1}.Validate(), "max forks")
+}
+
+func TestHopelessForks(t *testing.T) {
+	params := minDifficultyParams(wire.TestNet3)
+	g := chaingen.New(params)
+	lc := NewBTCLightClientWithData(g.Params(), []wire.BlockHeader{g.Genesis().Header()}, 0,
+		WithInsertLimits(InsertLimits{RejectHopelessForks: true}))
+
+	// the difficulty goes up after the first two periods, the blocks on
+	// time have 16 times the minimum work, more than finality depth
+	// minimum difficulty blocks
+	periods := g.Extend(g.Genesis(), 40, chaingen.WithTimeDelta(params.TargetTimePerBlock/4))
+	insertBlocks(t, lc, periods)
+	forkPoint := g.NextBlock(periods[39])
+	insertBlocks(t, lc, []*chaingen.Block{forkPoint})
+	a := g.NextBlock(forkPoint)
+	insertBlocks(t, lc, []*chaingen.Block{a})
+
+	// a minimum difficulty block is followed by blocks at the difficulty
+	// before it, the fork overtakes a after two blocks
+	b := g.NextBlock(forkPoint, chaingen.WithTimeDelta(time.Hour))
+	assert.Equal(t, b.Header().Bits, params.PowLimitBits)
+	c := g.NextBlock(b)
+	assert.Equal(t, c.Header().Bits, a.Header().Bits)
+	assert.Equal(t, blockchain.CalcWork(c.Header().Bits).Int64(), 16*blockchain.CalcWork(params.PowLimitBits).Int64())
+	insertBlocks(t, lc, []*chaingen.Block{b, c})
+	assert.Equal(t, lc.btcStore.MostDifficultFork().Header.BlockHash(), c.Hash())
+
+	// a fork which wasted 2 minimum difficulty blocks while the best chain
+	// grew 7 blocks can't catch up before the fork point is finalized
+	slow := g.Extend(forkPoint, 2, chaingen.WithTimeDelta(2*time.Hour))
+	insertBlocks(t, lc, slow)
+	best := g.Extend(a, 6)
+	insertBlocks(t, lc, best)
+	assert.Equal(t, lc.btcStore.LatestCheckPoint().Header.BlockHash(), forkPoint.Hash())
+	for _, delta := range []time.Duration{params.TargetTimePerBlock, time.Hour} {
+		err := lc.InsertHeader(g.NextBlock(slow[1], chaingen.WithTimeDelta(delta)).Header())
+		assert.Assert(t, errors.Is(err, ErrHopelessFork), err)
+	}
+
+	// the best chain is always extended
+	assert.NilError(t, lc.InsertHeader(g.NextBlock(best[5], chaingen.WithTimeDelta(time.Hour)).Header()))
+}
@@ -0,0 +1,120 @@
+package btclightclient
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "btclightclient"
+
+// Metrics groups the prometheus collectors updated by the light client.
+// A nil *Metrics is valid and all methods on it are no-op, so the light
+// client works without metrics when none are configured.
+type Metrics struct {
+	tipHeight       prometheus.Gauge
+	finalizedHeight prometheus.Gauge
+	liveForks       prometheus.Gauge
+	reorgDepth      prometheus.Histogram
+	insertLatency   prometheus.Histogram
+	insertTotal     *prometheus.CounterVec
+	spvTotal        *prometheus.CounterVec
+}
+
+// NewMetrics creates the light client collectors and registers them in reg.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		tipHeight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "tip_height",
+			Help:      "Height of the tip of the most difficult fork.",
+		}),
+		finalizedHeight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "finalized_height",
+			Help:      "Height of the latest checkpoint (finalized block).",
+		}),
+		liveForks: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "live_forks",
+			Help:      "Number of fork heads tracked by the light client.",
+		}),
+		reorgDepth: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "reorg_depth",
+			Help:      "Number of blocks disconnected from the best chain on a reorg.",
+			Buckets:   []float64{1, 2, 3, 4, 5, 6, 7, 8},
+		}),
+		insertLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "insert_header_duration_seconds",
+			Help:      "Time spent in InsertHeader.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 14),
+		}),
+		insertTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "insert_header_total",
+			Help:      "Number of InsertHeader calls by result.",
+		}, []string{"result"}),
+		spvTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "spv_verification_total",
+			Help:      "Number of SPV proof verifications by status.",
+		}, []string{"status"}),
+	}
+
+	reg.MustRegister(
+		m.tipHeight,
+		m.finalizedHeight,
+		m.liveForks,
+		m.reorgDepth,
+		m.insertLatency,
+		m.insertTotal,
+		m.spvTotal,
+	)
+	return m
+}
+
+func (m *Metrics) observeInsert(start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	m.insertLatency.Observe(time.Since(start).Seconds())
+	result := "ok"
+	if errors.Is(err, ErrDuplicateHeader) {
+		result = "duplicate"
+	} else if errors.Is(err, ErrTooManyForks) || errors.Is(err, ErrHopelessFork) {
+		result = "limited"
+	} else if err != nil {
+		result = "error"
+	}
+	m.insertTotal.WithLabelValues(result).Inc()
+}
+
+func (m *Metrics) observeReorg(depth int32) {
+	if m == nil || depth <= 0 {
+		return
+	}
+	m.reorgDepth.Observe(float64(depth))
+}
+
+func (m *Metrics) observeSPV(status SPVStatus) {
+	if m == nil {
+		return
+	}
+	m.spvTotal.WithLabelValues(status.String()).Inc()
+}
+
+func (m *Metrics) updateChain(s Store) {
+	if m == nil {
+		return
+	}
+	if tip := s.MostDifficultFork(); tip != nil {
+		m.tipHeight.Set(float64(tip.Height))
+	}
+	if checkpoint := s.LatestCheckPoint(); checkpoint != nil {
+		m.finalizedHeight.Set(float64(checkpoint.Height))
+	}
+	m.liveForks.Set(float64(len(s.LatestBlockHashOfFork())))
+}
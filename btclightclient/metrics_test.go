@@ -0,0 +1,57 @@
+package btclightclient
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gotest.tools/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	tcs := CommonTestCases()
+	lc := initLightClient(t, HEADERS)
+	m := NewMetrics(prometheus.NewRegistry())
+	lc.SetMetrics(m)
+
+	assert.Equal(t, testutil.ToFloat64(m.tipHeight), float64(len(HEADERS)-1))
+	assert.Equal(t, testutil.ToFloat64(m.finalizedHeight), float64(len(HEADERS)-MaxForkAge))
+	assert.Equal(t, testutil.ToFloat64(m.liveForks), float64(1))
+
+	header, err := BlockHeaderFromHex(tcs["Create fork"].header)
+	assert.NilError(t, err)
+	assert.NilError(t, lc.InsertHeader(header))
+	assert.Equal(t, testutil.ToFloat64(m.liveForks), float64(2))
+	assert.Equal(t, testutil.ToFloat64(m.insertTotal.WithLabelValues("ok")), float64(1))
+
+	// inserting the same header again is a no-op
+	assert.Assert(t, errors.Is(lc.InsertHeader(header), ErrDuplicateHeader))
+	assert.Equal(t, testutil.ToFloat64(m.insertTotal.WithLabelValues("duplicate")), float64(1))
+	assert.Equal(t, testutil.ToFloat64(m.insertTotal.WithLabelValues("error")), float64(0))
+
+	header, err = BlockHeaderFromHex(tcs["Append a fork"].header)
+	assert.NilError(t, err)
+	assert.NilError(t, lc.InsertHeader(header))
+	assert.NilError(t, lc.CleanUpFork())
+	assert.Equal(t, testutil.ToFloat64(m.tipHeight), float64(len(HEADERS)))
+	assert.Equal(t, testutil.ToFloat64(m.finalizedHeight), float64(len(HEADERS)-MaxForkAge+1))
+
+	lc.VerifySPV(SPVProof{})
+	assert.Equal(t, testutil.ToFloat64(m.spvTotal.WithLabelValues(InvalidSPVProof.String())), float64(1))
+}
+
+func TestReorgDepth(t *testing.T) {
+	tcs := CommonTestCases()
+	lc := initLightClient(t, HEADERS)
+	oldTip := lc.btcStore.MostDifficultFork()
+
+	header, err := BlockHeaderFromHex(tcs["Create fork"].header)
+	assert.NilError(t, err)
+	assert.NilError(t, lc.InsertHeader(header))
+	fork := lc.btcStore.LightBlockByHash(header.BlockHash())
+
+	assert.Equal(t, lc.reorgDepth(oldTip, oldTip), int32(0))
+	assert.Equal(t, lc.reorgDepth(oldTip, fork), int32(1))
+	assert.Equal(t, lc.reorgDepth(nil, fork), int32(0))
+}
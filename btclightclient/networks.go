@@ -0,0 +1,115 @@
+package btclightclient
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// TestNet4Net is the testnet4 network magic, message start 1c163f28.
+const TestNet4Net wire.BitcoinNet = 0x283f161c
+
+// testNet4GenesisCoinbaseTx is the coinbase of the testnet4 genesis block.
+// Its script commits to a mainnet block hash, the output is unspendable.
+var testNet4GenesisCoinbaseTx = wire.MsgTx{
+	Version: 1,
+	TxIn: []*wire.TxIn{
+		{
+			PreviousOutPoint: wire.OutPoint{
+				Hash:  chainhash.Hash{},
+				Index: 0xffffffff,
+			},
+			SignatureScript: append(
+				[]byte{0x04, 0xff, 0xff, 0x00, 0x1d, 0x01, 0x04, 0x4c, 0x4c},
+				"03/May/2024 000000000000000000001ebd58c244970b3aa9d783bb001011fbe8ea8e98e00e"...,
+			),
+			Sequence: 0xffffffff,
+		},
+	},
+	TxOut: []*wire.TxOut{
+		{
+			Value:    50 * 1e8,
+			PkScript: append(append([]byte{0x21}, make([]byte, 33)...), 0xac),
+		},
+	},
+	LockTime: 0,
+}
+
+var testNet4GenesisBlock = wire.MsgBlock{
+	Header: wire.BlockHeader{
+		Version:    1,
+		PrevBlock:  chainhash.Hash{},
+		MerkleRoot: testNet4GenesisCoinbaseTx.TxHash(), // 7aa0a7ae1e223414cb807e40cd57e667b718e42aaf9306db9102fe28912b7b4e
+		Timestamp:  time.Unix(1714777860, 0),           // 2024-05-03 23:11:00 +0000 UTC
+		Bits:       0x1d00ffff,
+		Nonce:      393743547,
+	},
+	Transactions: []*wire.MsgTx{&testNet4GenesisCoinbaseTx},
+}
+
+var testNet4GenesisHash = testNet4GenesisBlock.BlockHash() // 00000000da84f2bafbbc53dee25a72ae507ff4914b867c565be350b0da8bf043
+
+// TestNet4Params defines the network parameters of the test Bitcoin network
+// version 4 (BIP94). btcd doesn't support it yet.
+var TestNet4Params = chaincfg.Params{
+	Name:        "testnet4",
+	Net:         TestNet4Net,
+	DefaultPort: "48333",
+	DNSSeeds: []chaincfg.DNSSeed{
+		{Host: "seed.testnet4.bitcoin.sprovoost.nl", HasFiltering: true},
+		{Host: "seed.testnet4.wiz.biz", HasFiltering: true},
+	},
+
+	// Chain parameters
+	GenesisBlock:             &testNet4GenesisBlock,
+	GenesisHash:              &testNet4GenesisHash,
+	PowLimit:                 new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 224), big.NewInt(1)),
+	PowLimitBits:             0x1d00ffff,
+	BIP0034Height:            1,
+	BIP0065Height:            1,
+	BIP0066Height:            1,
+	CoinbaseMaturity:         100,
+	SubsidyReductionInterval: 210000,
+	TargetTimespan:           time.Hour * 24 * 14, // 14 days
+	TargetTimePerBlock:       time.Minute * 10,    // 10 minutes
+	RetargetAdjustmentFactor: 4,                   // 25% less, 400% more
+	ReduceMinDifficulty:      true,
+	MinDiffReductionTime:     time.Minute * 20, // TargetTimePerBlock * 2
+	GenerateSupported:        false,
+
+	// Consensus rule change deployments. All soft forks up to taproot
+	// are active from the genesis block on testnet4.
+	RuleChangeActivationThreshold: 1512, // 75% of MinerConfirmationWindow
+	MinerConfirmationWindow:       2016,
+
+	// Mempool parameters
+	RelayNonStdTxs: true,
+
+	// Human-readable part for Bech32 encoded segwit addresses, as defined in
+	// BIP 173.
+	Bech32HRPSegwit: "tb", // always tb for test net
+
+	// Address encoding magics
+	PubKeyHashAddrID:        0x6f, // starts with m or n
+	ScriptHashAddrID:        0xc4, // starts with 2
+	WitnessPubKeyHashAddrID: 0x03, // starts with QW
+	WitnessScriptHashAddrID: 0x28, // starts with T7n
+	PrivateKeyID:            0xef, // starts with 9 (uncompressed) or c (compressed)
+
+	// BIP32 hierarchical deterministic extended key magics
+	HDPrivateKeyID: [4]byte{0x04, 0x35, 0x83, 0x94}, // starts with tprv
+	HDPublicKeyID:  [4]byte{0x04, 0x35, 0x87, 0xcf}, // starts with tpub
+
+	// BIP44 coin type used in the hierarchical deterministic path for
+	// address generation.
+	HDCoinType: 1,
+}
+
+// EnforceBIP94 reports whether the network enforces the BIP94 rules: the
+// timewarp fix and the retarget based on the first block of the period.
+func EnforceBIP94(params *chaincfg.Params) bool {
+	return params.Net == TestNet4Net
+}
@@ -6,8 +6,8 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
-	"math"
 
+	"github.com/btcsuite/btcd/blockchain"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
 )
@@ -71,6 +71,12 @@ type MerkleProof struct {
 
 const maxAllowBytes = 65536
 
+// maxTransactions is the maximum number of transactions of a block: the
+// maximum block weight divided by the weight of the smallest transaction
+// (60 bytes). Bitcoin Core rejects partial merkle trees with more
+// transactions.
+const maxTransactions = blockchain.MaxBlockWeight / (blockchain.WitnessScaleFactor * 60)
+
 // parse merkle tree. Follow encode/decode format:
 // *  - uint32     total_transactions (4 bytes)
 // *  - varint     number of hashes   (1-3 bytes)
@@ -81,10 +87,14 @@ const maxAllowBytes = 65536
 func decodePartialMerkleTreeData(buf []byte) (partialMerkleTreeData, error) {
 	var pmt partialMerkleTreeData
 	r := bytes.NewReader(buf)
-	if _, err := io.ReadFull(r, buf[:4]); err != nil {
+	var nTx [4]byte
+	if _, err := io.ReadFull(r, nTx[:]); err != nil {
 		return pmt, err
 	}
-	numberTransactions := binary.LittleEndian.Uint32(buf[:4])
+	numberTransactions := binary.LittleEndian.Uint32(nTx[:])
+	if numberTransactions == 0 || numberTransactions > maxTransactions {
+		return pmt, fmt.Errorf("%w: %d", ErrMerkleInvalidTxCount, numberTransactions)
+	}
 
 	var pver uint32 //  Protocol version. However, this variable is placeholder only.
 	var vHash []*chainhash.Hash
@@ -92,7 +102,7 @@ func decodePartialMerkleTreeData(buf []byte) (partialMerkleTreeData, error) {
 	if err != nil {
 		return pmt, err
 	}
-	if numberOfHashes*chainhash.HashSize > maxAllowBytes {
+	if numberOfHashes*chainhash.HashSize > maxAllowBytes || numberOfHashes > uint64(numberTransactions) {
 		return pmt, ErrMerkleDecodeHashNumberInvalid
 	}
 
@@ -121,6 +131,13 @@ func decodePartialMerkleTreeData(buf []byte) (partialMerkleTreeData, error) {
 			i++
 		}
 	}
+	// every hash is consumed with at least one bit
+	if len(vBits) < len(vHash) {
+		return pmt, ErrMerkleDecodeHashNumberInvalid
+	}
+	if r.Len() != 0 {
+		return pmt, fmt.Errorf("%w: %d bytes", ErrMerkleTrailingData, r.Len())
+	}
 
 	pmt.numberTransactions = numberTransactions
 	pmt.vBits = vBits
@@ -142,7 +159,7 @@ func (pmtd *partialMerkleTreeData) calcTreeWidth(height uint32) uint32 {
 
 func (pmtd *partialMerkleTreeData) nextBit() (bool, error) {
 	if int(pmtd.nBitUsed) >= len(pmtd.vBits) {
-		return false, fmt.Errorf("out-bound of vBits")
+		return false, ErrMerkleDecodeBitsOutbound
 	}
 	bit := pmtd.vBits[pmtd.nBitUsed]
 	pmtd.nBitUsed++
@@ -151,7 +168,7 @@ func (pmtd *partialMerkleTreeData) nextBit() (bool, error) {
 
 func (pmtd *partialMerkleTreeData) nextHash() (*chainhash.Hash, error) {
 	if int(pmtd.nHashUsed) >= len(pmtd.vHash) {
-		return nil, fmt.Errorf("out-bound of vHash")
+		return nil, ErrMerkleDecodeOutbound
 	}
 	hash := pmtd.vHash[pmtd.nHashUsed]
 	pmtd.nHashUsed++
@@ -160,7 +177,11 @@ func (pmtd *partialMerkleTreeData) nextHash() (*chainhash.Hash, error) {
 
 // returns the minimum height of a Merkele tree to fit `pmt.numberTransactions`.
 func (pmtd *partialMerkleTreeData) height() uint32 {
-	return uint32(math.Ceil(math.Log2(float64(pmtd.numberTransactions))))
+	var height uint32
+	for pmtd.calcTreeWidth(height) > 1 {
+		height++
+	}
+	return height
 }
 
 func (pmtd *partialMerkleTreeData) buildTreeRecursive(height, pos uint32, merkleTree *PartialMerkleTree) (*chainhash.Hash, error) {
@@ -177,6 +198,9 @@ func (pmtd *partialMerkleTreeData) buildTreeRecursive(height, pos uint32, merkle
 			return nil, err
 		}
 		merkleTree.nodesAtHeight[height][pos] = *hash
+		if height == 0 && fParentOfMatch {
+			merkleTree.matches[pos] = struct{}{}
+		}
 		return hash, nil
 	}
 
@@ -194,8 +218,11 @@ func (pmtd *partialMerkleTreeData) buildTreeRecursive(height, pos uint32, merkle
 		if err != nil {
 			return nil, err
 		}
+		// The right node is never identical to the left node, otherwise
+		// two trees with different transactions have the same root
+		// (CVE-2012-2459).
 		if left.IsEqual(right) {
-			return nil, fmt.Errorf("right node never identical with left node")
+			return nil, ErrMerkleDuplicateNode
 		}
 	} else {
 		// Right node doesn't exist, it's assigned value by left node.
@@ -221,11 +248,15 @@ type merkleNodes map[uint32]chainhash.Hash
 type PartialMerkleTree struct {
 	// nodes at level or height.
 	nodesAtHeight []merkleNodes
+	// positions of the matched leaves, the transactions the tree proves.
+	// The other leaves are only siblings of matched leaves.
+	matches map[uint32]struct{}
 }
 
 func (mk PartialMerkleTree) getLeafNodeIndex(txID *chainhash.Hash) (uint32, error) {
 	// TODO(vu): Should we use reverse map to find position of merkle leaf?
-	for leafIndex, leafValue := range mk.nodesAtHeight[0] {
+	for leafIndex := range mk.matches {
+		leafValue := mk.nodesAtHeight[0][leafIndex]
 		if leafValue.IsEqual(txID) {
 			return leafIndex, nil
 		}
@@ -256,8 +287,13 @@ func (mk PartialMerkleTree) GetProof(txID string) (*MerkleProof, error) {
 		var siblingHash chainhash.Hash
 		if position%2 == 0 {
 			// A current node is a left children of parent node
-			// We need the node on the right to compute parent node
-			siblingHash = mk.nodesAtHeight[i][position+1]
+			// We need the node on the right to compute parent node.
+			// The last node of a level with odd width has no right
+			// node, it's hashed with itself.
+			var ok bool
+			if siblingHash, ok = mk.nodesAtHeight[i][position+1]; !ok {
+				siblingHash = mk.nodesAtHeight[i][position]
+			}
 		} else {
 			// A current node is a right children of parent node
 			// We need the node on the left to compute parent node
@@ -289,8 +325,18 @@ func PartialMerkleTreeFromHex(mtData string) (PartialMerkleTree, error) {
 	for i := 0; i <= int(height); i++ {
 		pmt.nodesAtHeight[i] = make(map[uint32]chainhash.Hash)
 	}
+	pmt.matches = make(map[uint32]struct{})
 	if _, err := pmtInfo.buildTreeRecursive(height, 0, &pmt); err != nil {
 		return pmt, err
 	}
+
+	// All the bits, except the padding of the last byte, and all the
+	// hashes must be used by the tree.
+	if (pmtInfo.nBitUsed+7)/8 != (uint32(len(pmtInfo.vBits))+7)/8 {
+		return pmt, ErrMerkleUnusedBits
+	}
+	if pmtInfo.nHashUsed != uint32(len(pmtInfo.vHash)) {
+		return pmt, ErrMerkleUnusedHashes
+	}
 	return pmt, nil
 }
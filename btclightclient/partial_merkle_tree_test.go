@@ -1,8 +1,18 @@
 package btclightclient
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
 	"testing"
 
+	"github.com/gonative-cc/bitcoin-lightclient/chaingen"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
 	"gotest.tools/assert"
 )
 
@@ -18,7 +28,7 @@ func TestPartialMerkleTree(t *testing.T) {
 	run := func(t *testing.T, tc testCase) {
 		pmt, err := PartialMerkleTreeFromHex(tc.txoutproof[160:])
 		if err != nil {
-			assert.Error(t, err, tc.expectedError.Error())
+			assert.Assert(t, errors.Is(err, tc.expectedError), err)
 			return
 		}
 
@@ -57,7 +67,7 @@ func TestPartialMerkleTree(t *testing.T) {
 			name:          "Can't decode txoutproof",
 			txoutproof:    "00e0002000471175ec71a72541c100f21bb79f9da0e5ca98259a000000000000000000004769eae15b51056127304c5edec6d94c7840f8f922c0b65bc32177cb46ce05de9b8c10866d36203175dae051fdc0a00000d625aa7b5510f7c003624338259d21544e61ccb3666792dde9734b7621d2cf80bb81ffa45657310bdc47ad3b3f5e5346c150d4fc1b98a5446cc560c6f38f7156138761aab058be861e51fe52ea7cf7b4914a1e1b159ecebe46b51db0ec5cfd4c2324ae9c132169d1f133981632895c216a8e3c3d3a9cea545fade4c0ab8b626a2791862728b657abbdb06dedcc3faabee9d72ce6b8252b45fc99d6fe0f79cec401e1a431774d8830b962e5dee97fc96f4f85f84a6e50b986a37b35318537a81f3f8c604554e5b4f5ca4b4437caa3b0723896396532c1985d52f42f915084534c6bedb4ded1238781d23be0173b94ca25d7faff2832ac99fa16b2f9b219ff276062f100d4b7ce774ba405fbad36b65165e2e5aece3e0b9718886d7b24708be5ae72d10911e9301811b19fcb218ce7dfee31729f4ef56a3d8f31670865a039b3678b34fcb47f12bd157a064339c3e91a960c5a14b9e8da9c8ce211a02bb94e7165a1668d8a17663e95adcdacdbc8e8ab793e8796fda9b270ca957e67aa33dc95cff158cb2ff6882064942ef545612a8eceb3c60415d677d170f4351ede1f7a8807504ff1f0000e",
 			txID:          "",
-			expectedError: ErrMerkleDecodeOutbound,
+			expectedError: ErrMerkleInvalidTxCount,
 			root:          "",
 		},
 	}
@@ -69,3 +79,161 @@ func TestPartialMerkleTree(t *testing.T) {
 	}
 
 }
+
+// encodePartialMerkleTree serializes a partial merkle tree without checking
+// it's valid.
+func encodePartialMerkleTree(t testing.TB, numberTransactions uint32, hashes []chainhash.Hash, bits []bool) string {
+	var buf bytes.Buffer
+	buf.Write(binary.LittleEndian.AppendUint32(nil, numberTransactions))
+	assert.NilError(t, wire.WriteVarInt(&buf, 0, uint64(len(hashes))))
+	for _, h := range hashes {
+		buf.Write(h[:])
+	}
+	flags := make([]byte, (len(bits)+7)/8)
+	for i, bit := range bits {
+		if bit {
+			flags[i/8] |= 1 << (i % 8)
+		}
+	}
+	assert.NilError(t, wire.WriteVarBytes(&buf, 0, flags))
+	return hex.EncodeToString(buf.Bytes())
+}
+
+func TestPartialMerkleTreeAdversarial(t *testing.T) {
+	a, b := chainhash.DoubleHashH([]byte("a")), chainhash.DoubleHashH([]byte("b"))
+	matchLeft := []bool{true, true, false}
+
+	testCases := []struct {
+		name          string
+		tree          string
+		expectedError error
+	}{
+		{
+			name: "single transaction",
+			tree: encodePartialMerkleTree(t, 1, []chainhash.Hash{a}, []bool{true}),
+		},
+		{
+			name: "two transactions, left matched",
+			tree: encodePartialMerkleTree(t, 2, []chainhash.Hash{a, b}, matchLeft),
+		},
+		{
+			name:          "no transactions",
+			tree:          encodePartialMerkleTree(t, 0, nil, nil),
+			expectedError: ErrMerkleInvalidTxCount,
+		},
+		{
+			name:          "more transactions than a block can have",
+			tree:          encodePartialMerkleTree(t, maxTransactions+1, []chainhash.Hash{a}, []bool{true}),
+			expectedError: ErrMerkleInvalidTxCount,
+		},
+		{
+			name:          "more hashes than transactions",
+			tree:          encodePartialMerkleTree(t, 1, []chainhash.Hash{a, b}, matchLeft),
+			expectedError: ErrMerkleDecodeHashNumberInvalid,
+		},
+		{
+			name:          "less bits than hashes",
+			tree:          encodePartialMerkleTree(t, 16, make([]chainhash.Hash, 9), make([]bool, 8)),
+			expectedError: ErrMerkleDecodeHashNumberInvalid,
+		},
+		{
+			name:          "missing hash",
+			tree:          encodePartialMerkleTree(t, 2, []chainhash.Hash{a}, matchLeft),
+			expectedError: ErrMerkleDecodeOutbound,
+		},
+		{
+			name:          "missing bits",
+			tree:          encodePartialMerkleTree(t, 3, []chainhash.Hash{a, b}, nil),
+			expectedError: ErrMerkleDecodeHashNumberInvalid,
+		},
+		{
+			name:          "unused hash",
+			tree:          encodePartialMerkleTree(t, 2, []chainhash.Hash{a, b}, []bool{false}),
+			expectedError: ErrMerkleUnusedHashes,
+		},
+		{
+			name:          "unused bits",
+			tree:          encodePartialMerkleTree(t, 2, []chainhash.Hash{a, b}, append(matchLeft, make([]bool, 6)...)),
+			expectedError: ErrMerkleUnusedBits,
+		},
+		{
+			name:          "duplicate right node",
+			tree:          encodePartialMerkleTree(t, 2, []chainhash.Hash{a, a}, matchLeft),
+			expectedError: ErrMerkleDuplicateNode,
+		},
+		{
+			name:          "trailing data",
+			tree:          encodePartialMerkleTree(t, 2, []chainhash.Hash{a, b}, matchLeft) + "00",
+			expectedError: ErrMerkleTrailingData,
+		},
+		{
+			name:          "truncated",
+			tree:          encodePartialMerkleTree(t, 2, []chainhash.Hash{a, b}, matchLeft)[:80],
+			expectedError: io.ErrUnexpectedEOF,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pmt, err := PartialMerkleTreeFromHex(tc.tree)
+			if tc.expectedError != nil {
+				assert.Assert(t, errors.Is(err, tc.expectedError), err)
+				return
+			}
+			assert.NilError(t, err)
+			_, err = pmt.GetProof(a.String())
+			assert.NilError(t, err)
+			// b is only the sibling of the matched leaf
+			_, err = pmt.GetProof(b.String())
+			assert.Assert(t, errors.Is(err, ErrValueIsNotMerkleLeaf))
+		})
+	}
+}
+
+func TestDecodePartialMerkleTreeDataKeepsInput(t *testing.T) {
+	buf, err := hex.DecodeString(encodePartialMerkleTree(t, 1, []chainhash.Hash{{1}}, []bool{true}))
+	assert.NilError(t, err)
+	input := bytes.Clone(buf)
+	_, err = decodePartialMerkleTreeData(buf)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, buf, input)
+}
+
+// generatedTxOutProofs returns gettxoutproof proofs of blocks with different
+// number of transactions.
+func generatedTxOutProofs(t testing.TB) (proofs []string, txIDs []chainhash.Hash) {
+	g := chaingen.New(&chaincfg.RegressionNetParams)
+	for _, n := range []int{0, 1, 2, 4, 8} {
+		b := g.NextBlock(g.Genesis(), chaingen.WithTxs(n))
+		ids := b.TxIDs()
+		proof, err := b.TxOutProof(ids[len(ids)-1])
+		assert.NilError(t, err)
+		proofs = append(proofs, proof)
+		txIDs = append(txIDs, ids[len(ids)-1])
+	}
+	return proofs, txIDs
+}
+
+func FuzzPartialMerkleTreeFromHex(f *testing.F) {
+	proofs, _ := generatedTxOutProofs(f)
+	for _, proof := range proofs {
+		tree, err := hex.DecodeString(proof[BTCHeaderSize*2:])
+		assert.NilError(f, err)
+		f.Add(tree)
+	}
+
+	f.Fuzz(func(t *testing.T, tree []byte) {
+		pmt, err := PartialMerkleTreeFromHex(hex.EncodeToString(tree))
+		if err != nil {
+			return
+		}
+		// every matched transaction has a proof to the tree root
+		for pos := range pmt.matches {
+			txID := pmt.nodesAtHeight[0][pos]
+			proof, err := pmt.GetProof(txID.String())
+			assert.NilError(t, err)
+			spvProof := SPVProof{TxIndex: proof.transactionIndex, MerklePath: proof.merklePath}
+			assert.Equal(t, spvProof.MerkleRoot(), proof.merkleRoot)
+		}
+	})
+}
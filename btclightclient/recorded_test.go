@@ -0,0 +1,164 @@
+package btclightclient
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"gotest.tools/assert"
+)
+
+// recordedNetworks are the networks of the recorded header files.
+var recordedNetworks = map[string]*chaincfg.Params{
+	"mainnet":  &chaincfg.MainNetParams,
+	"testnet3": &chaincfg.TestNet3Params,
+	"testnet4": &TestNet4Params,
+}
+
+// recordedHeaders reads a header file of testdata, recorded from a real
+// network. It returns the network, the height of the first header and the
+// headers.
+func recordedHeaders(t *testing.T, name string) (*chaincfg.Params, int32, []wire.BlockHeader) {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join("testdata", name))
+	assert.NilError(t, err)
+	var file struct {
+		Network      string   `json:"network"`
+		StartHeight  int32    `json:"start_height"`
+		BlockHeaders []string `json:"blockheaders"`
+	}
+	assert.NilError(t, json.Unmarshal(content, &file))
+	params, ok := recordedNetworks[file.Network]
+	assert.Assert(t, ok, "unknown network %s", file.Network)
+
+	headers := make([]wire.BlockHeader, len(file.BlockHeaders))
+	for i, headerHex := range file.BlockHeaders {
+		headers[i], err = BlockHeaderFromHex(headerHex)
+		assert.NilError(t, err)
+	}
+	return params, file.StartHeight, headers
+}
+
+// insertHeaders inserts headers as the RPC server does.
+func insertHeaders(t *testing.T, lc *BTCLightClient, headers []wire.BlockHeader) {
+	t.Helper()
+	for _, h := range headers {
+		assert.NilError(t, lc.InsertHeader(h), "block %s", h.BlockHash())
+		assert.NilError(t, lc.CleanUpFork())
+	}
+}
+
+func TestMainnetRetarget(t *testing.T) {
+	params, start, headers := recordedHeaders(t, "mainnet-10080.json")
+	// index of the first block of the period starting at the retarget
+	retarget := 12096 - start
+	assert.Equal(t, (start+retarget)%blocksPerRetarget(params), int32(0))
+
+	// bootstrapped from the first block of the period, the chain crosses the
+	// retarget and it is finalized
+	lc := NewBTCLightClientWithData(params, headers[:retarget-5], int(start))
+	insertHeaders(t, lc, headers[retarget-5:])
+	tip := headers[len(headers)-1].BlockHash()
+	assert.Equal(t, lc.btcStore.MostDifficultFork().Header.BlockHash(), tip)
+	assert.Assert(t, lc.LatestFinalizedBlockHeight() > int64(start+retarget))
+
+	// forks at the retarget, from the block before it on the best chain:
+	// their difficulty is computed from the recorded period
+	lc = NewBTCLightClientWithData(params, headers[:retarget+3], int(start))
+	assert.NilError(t, lc.CheckHeader(headers[retarget-1], headers[retarget]))
+	fork := headers[retarget]
+	fork.Nonce++
+	invalid := fork
+	invalid.Bits = 0x1c7fffff
+	assertRuleError(t, lc.InsertHeader(invalid), blockchain.ErrUnexpectedDifficulty)
+	// the expected difficulty, only the proof of work is missing
+	assertRuleError(t, lc.InsertHeader(fork), blockchain.ErrHighHash)
+
+	// without the first block of the period the retarget can't be checked
+	lc = NewBTCLightClientWithData(params, headers[1:retarget], int(start)+1)
+	err := lc.InsertHeader(headers[retarget])
+	assert.Assert(t, errors.Is(err, ErrMissingAncestor), err)
+}
+
+func TestTestNet3Recorded(t *testing.T) {
+	for _, name := range recordedFiles(t, "testnet3") {
+		t.Run(name, func(t *testing.T) {
+			params, start, headers := recordedHeaders(t, name)
+			checkMinDifficultyRange(t, params, start, headers)
+		})
+	}
+}
+
+func TestTestNet4Recorded(t *testing.T) {
+	for _, name := range recordedFiles(t, "testnet4") {
+		t.Run(name, func(t *testing.T) {
+			params, start, headers := recordedHeaders(t, name)
+			checkMinDifficultyRange(t, params, start, headers)
+		})
+	}
+}
+
+// recordedFiles returns the names of the header files of testdata recorded
+// from network, it skips the test when there is none.
+func recordedFiles(t *testing.T, network string) []string {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join("testdata", network+"-*.json"))
+	assert.NilError(t, err)
+	if len(paths) == 0 {
+		t.Skipf("no %s headers in testdata, record them with scripts/record-headers.sh", network)
+	}
+	names := make([]string, len(paths))
+	for i, path := range paths {
+		names[i] = filepath.Base(path)
+	}
+	return names
+}
+
+// checkMinDifficultyRange inserts the headers of a network with minimum
+// difficulty blocks, starting at a retarget and crossing the next one, then
+// checks a minimum difficulty fork from the oldest block which can be forked.
+func checkMinDifficultyRange(t *testing.T, params *chaincfg.Params, start int32, headers []wire.BlockHeader) {
+	t.Helper()
+	perRetarget := blocksPerRetarget(params)
+	assert.Equal(t, start%perRetarget, int32(0), "the range must start at a retarget")
+	assert.Assert(t, int32(len(headers)) > perRetarget, "the range must cross a retarget")
+	// a block after a minimum difficulty block, in the same period, has the
+	// difficulty of the last block before them
+	walkback := false
+	for i := 1; i < len(headers); i++ {
+		if (start+int32(i))%perRetarget != 0 && headers[i-1].Bits == params.PowLimitBits &&
+			headers[i].Bits != params.PowLimitBits {
+			walkback = true
+			break
+		}
+	}
+	assert.Assert(t, walkback, "the range must have a block after minimum difficulty blocks")
+
+	lc := NewBTCLightClientWithData(params, headers[:1], int(start))
+	insertHeaders(t, lc, headers[1:])
+	tip := headers[len(headers)-1]
+	assert.Equal(t, lc.btcStore.MostDifficultFork().Header.BlockHash(), tip.BlockHash())
+	assert.Equal(t, len(lc.CheckConsistency(false)), 0)
+
+	// a block can be at minimum difficulty when it is more than twice the
+	// target time after its parent
+	parent := headers[len(headers)-int(lc.finalityDepth)]
+	fork := wire.BlockHeader{
+		Version:   parent.Version,
+		PrevBlock: parent.BlockHash(),
+		Timestamp: parent.Timestamp.Add(params.MinDiffReductionTime + time.Second),
+		Bits:      params.PowLimitBits,
+	}
+	// only the proof of work is missing, mining it takes too long
+	assertRuleError(t, lc.CheckHeader(parent, fork), blockchain.ErrHighHash)
+	if parent.Bits != params.PowLimitBits {
+		fork.Timestamp = parent.Timestamp.Add(time.Second)
+		assertRuleError(t, lc.CheckHeader(parent, fork), blockchain.ErrUnexpectedDifficulty)
+	}
+}
@@ -0,0 +1,127 @@
+package btclightclient
+
+import (
+	"fmt"
+)
+
+// RetentionMode selects the finalized blocks kept in the store.
+type RetentionMode int
+
+const (
+	// RetainAll keeps every finalized block.
+	RetainAll RetentionMode = iota
+	// RetainLastFinalized keeps the last KeepFinalized finalized blocks.
+	RetainLastFinalized
+	// RetainRetargetAnchors keeps the first block of each retarget period
+	// and drops the other old finalized blocks.
+	RetainRetargetAnchors
+)
+
+var retentionModeStrings = map[RetentionMode]string{
+	RetainAll:             "all",
+	RetainLastFinalized:   "last",
+	RetainRetargetAnchors: "anchors",
+}
+
+func (m RetentionMode) String() string {
+	if s, ok := retentionModeStrings[m]; ok {
+		return s
+	}
+	return fmt.Sprintf("RetentionMode(%d)", m)
+}
+
+// ParseRetentionMode parses the name of a retention mode: all, last or
+// anchors.
+func ParseRetentionMode(s string) (RetentionMode, error) {
+	for m, name := range retentionModeStrings {
+		if name == s {
+			return m, nil
+		}
+	}
+	return RetainAll, fmt.Errorf("%w: unknown retention mode %q", ErrInvalidRetention, s)
+}
+
+// RetentionPolicy selects the old finalized blocks pruned from the store.
+// Whatever the policy, the blocks needed to validate new headers are kept:
+// the last finalized blocks used by the median time, the first block of the
+// current retarget period and the blocks of the current version bits
+// window.
+type RetentionPolicy struct {
+	Mode RetentionMode
+	// KeepFinalized is the number of finalized blocks kept by
+	// RetainLastFinalized, including the latest checkpoint.
+	KeepFinalized int32
+}
+
+func (p RetentionPolicy) Validate() error {
+	if p.Mode == RetainLastFinalized && p.KeepFinalized <= 0 {
+		return fmt.Errorf("%w: number of finalized blocks to keep must be positive, got %d",
+			ErrInvalidRetention, p.KeepFinalized)
+	}
+	if _, ok := retentionModeStrings[p.Mode]; !ok {
+		return fmt.Errorf("%w: %s", ErrInvalidRetention, p.Mode)
+	}
+	return nil
+}
+
+// WithRetention sets the retention policy, RetainAll by default.
+func WithRetention(policy RetentionPolicy) Option {
+	return func(lc *BTCLightClient) {
+		lc.retention = policy
+	}
+}
+
+// requiredHeight returns the height of the oldest finalized block needed to
+// validate headers on top of the checkpoint, and to track the deployments.
+func (lc *BTCLightClient) requiredHeight() int32 {
+	checkpoint := lc.btcStore.LatestCheckPoint().Height
+	// the median time uses 11 blocks, btcd also looks up the parent of
+	// the oldest one.
+	required := checkpoint - 11
+	// a fork can reach the next retarget, its difficulty is computed from
+	// the first block of the checkpoint period.
+	if bpr := lc.BlocksPerRetarget(); bpr > 0 {
+		required = min(required, checkpoint-checkpoint%bpr)
+	}
+	// the current version bits window is counted when it is finalized
+	if window := int32(lc.params.MinerConfirmationWindow); window > 0 {
+		required = min(required, (checkpoint+1)-(checkpoint+1)%window)
+	}
+	return max(required, 0)
+}
+
+// prune removes the finalized blocks the retention policy doesn't keep.
+func (lc *BTCLightClient) prune() {
+	var cutoff int32
+	switch lc.retention.Mode {
+	case RetainLastFinalized:
+		checkpoint := lc.btcStore.LatestCheckPoint().Height
+		cutoff = min(lc.requiredHeight(), checkpoint-lc.retention.KeepFinalized+1)
+	case RetainRetargetAnchors:
+		cutoff = lc.requiredHeight()
+	default:
+		return
+	}
+
+	bpr := lc.BlocksPerRetarget()
+	for height := lc.prunedHeight; height < cutoff; height++ {
+		if lc.retention.Mode == RetainRetargetAnchors && bpr > 0 && height%bpr == 0 {
+			continue
+		}
+		if lb := lc.btcStore.LightBlockAtHeight(int64(height)); lb != nil {
+			lc.auditPrune(lb, PruneRetention)
+			lc.btcStore.RemoveBlock(lb.Header.BlockHash())
+		}
+	}
+	lc.prunedHeight = max(lc.prunedHeight, cutoff)
+}
+
+// finalized updates the state derived from the finalized chain after the
+// checkpoint moved, then prunes the old blocks. The accumulator error is
+// returned after the update.
+func (lc *BTCLightClient) finalized() error {
+	err := lc.accumulate()
+	lc.versionBits.update(lc.btcStore)
+	lc.prune()
+	return err
+}
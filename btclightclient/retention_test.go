@@ -0,0 +1,176 @@
+package btclightclient
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gonative-cc/bitcoin-lightclient/chaingen"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"gotest.tools/assert"
+)
+
+// retentionParams returns params with 20 blocks retarget periods and 10
+// blocks version bits windows.
+func retentionParams() *chaincfg.Params {
+	params := minDifficultyParams(wire.TestNet3)
+	params.MinerConfirmationWindow = 10
+	params.RuleChangeActivationThreshold = 8
+	return params
+}
+
+// assertIndexesConsistent checks every MemStore index only refers to stored
+// blocks.
+func assertIndexesConsistent(t *testing.T, s *MemStore) {
+	t.Helper()
+	assert.Equal(t, len(s.totalWorkMap), len(s.lightBlockByHashMap))
+	for hash, lb := range s.lightBlockByHashMap {
+		assert.Assert(t, s.totalWorkMap[hash] != nil, "no work for %s", hash)
+		assert.Equal(t, lb.Header.BlockHash(), hash)
+	}
+	for height, lb := range s.lightblockMap {
+		assert.Equal(t, s.lightBlockByHashMap[lb.Header.BlockHash()], lb, "height %d", height)
+	}
+	for hash := range s.latestBlockHashOfFork {
+		assert.Assert(t, s.lightBlockByHashMap[hash] != nil, "fork head %s", hash)
+	}
+}
+
+func TestRemoveBlock(t *testing.T) {
+	g := chaingen.New(&chaincfg.RegressionNetParams)
+	blocks := chaingen.Chain(g.Extend(g.Genesis(), 2)[1])
+	lc := NewBTCLightClientWithData(g.Params(), chaingen.Headers(blocks), 0, WithFinalityDepth(1))
+	s := lc.btcStore.(*MemStore)
+	assert.Equal(t, len(s.lightblockMap), 3)
+
+	for _, b := range blocks {
+		s.RemoveBlock(b.Hash())
+		assert.Assert(t, s.LightBlockByHash(b.Hash()) == nil)
+		assert.Assert(t, s.TotalWorkAtBlock(b.Hash()) == nil)
+		assert.Assert(t, s.LightBlockAtHeight(int64(b.Height)) == nil)
+		assert.Assert(t, !s.IsForkHead(b.Hash()))
+		assertIndexesConsistent(t, s)
+	}
+	// removing an unknown block is a no-op
+	s.RemoveBlock(blocks[0].Hash())
+}
+
+func TestRetainLastFinalized(t *testing.T) {
+	params := retentionParams()
+	g := chaingen.New(params)
+	blocks := chaingen.Chain(g.Extend(g.Genesis(), 195)[194])
+	policy := RetentionPolicy{Mode: RetainLastFinalized, KeepFinalized: 5}
+
+	// the bootstrap prunes while streaming the headers
+	lc := NewBTCLightClientWithData(params, chaingen.Headers(blocks), 0, WithRetention(policy))
+	checkpoint := lc.btcStore.LatestCheckPoint().Height
+	assert.Equal(t, checkpoint, int32(188))
+	// the median time needs 177, the retarget 180 and the version bits
+	// window 180
+	assert.Equal(t, lc.requiredHeight(), int32(177))
+	for _, b := range blocks {
+		kept := lc.btcStore.LightBlockByHash(b.Hash()) != nil
+		assert.Equal(t, kept, b.Height >= 177, "block %d", b.Height)
+	}
+	assertIndexesConsistent(t, lc.btcStore.(*MemStore))
+
+	// the fork crosses the retarget at height 200, the first block of the
+	// period is still there
+	fork := g.Extend(blocks[checkpoint], 20, chaingen.WithTimeDelta(params.TargetTimePerBlock/4))
+	insertBlocks(t, lc, fork)
+	assert.Equal(t, lc.btcStore.MostDifficultFork().Header.BlockHash(), fork[19].Hash())
+	assert.Assert(t, fork[11].Header().Bits != params.PowLimitBits)
+
+	checkpoint = lc.btcStore.LatestCheckPoint().Height
+	assert.Equal(t, checkpoint, int32(201))
+	assert.Equal(t, lc.requiredHeight(), int32(190))
+	for h := int32(0); h <= checkpoint; h++ {
+		kept := lc.btcStore.LightBlockAtHeight(int64(h)) != nil
+		assert.Equal(t, kept, h >= 190, "block %d", h)
+	}
+	// the losing chain is removed by the fork cleanup
+	for _, b := range blocks[189:] {
+		assert.Assert(t, lc.btcStore.LightBlockByHash(b.Hash()) == nil)
+	}
+	assertIndexesConsistent(t, lc.btcStore.(*MemStore))
+	_, _, err := lc.DeploymentInfo()
+	assert.NilError(t, err)
+}
+
+func TestRetainRetargetAnchors(t *testing.T) {
+	params := retentionParams()
+	g := chaingen.New(params)
+	blocks := chaingen.Chain(g.Extend(g.Genesis(), 100)[99])
+	lc := NewBTCLightClientWithData(params, chaingen.Headers(blocks[:90]), 0,
+		WithRetention(RetentionPolicy{Mode: RetainRetargetAnchors}))
+	insertBlocks(t, lc, blocks[90:])
+
+	required := lc.requiredHeight()
+	assert.Equal(t, required, int32(80))
+	for _, b := range blocks {
+		kept := lc.btcStore.LightBlockByHash(b.Hash()) != nil
+		assert.Equal(t, kept, b.Height >= required || b.Height%20 == 0, "block %d", b.Height)
+	}
+	assertIndexesConsistent(t, lc.btcStore.(*MemStore))
+
+	// the anchors are still found by height, the pruned blocks are skipped
+	// by the block locator
+	anchor, err := lc.AncestorAt(blocks[100].Hash(), 40)
+	assert.NilError(t, err)
+	assert.Equal(t, anchor.Header.BlockHash(), blocks[40].Hash())
+	_, err = lc.AncestorAt(blocks[100].Hash(), 41)
+	assert.Assert(t, errors.Is(err, ErrMissingAncestor), err)
+
+	locator, err := lc.BlockLocator(blocks[100].Hash())
+	assert.NilError(t, err)
+	for _, hash := range locator {
+		assert.Assert(t, lc.btcStore.LightBlockByHash(*hash) != nil, "%s", hash)
+	}
+	assert.Equal(t, *locator[len(locator)-1], *params.GenesisHash)
+	// 100 to 89, 87, 83 and the genesis block, 75, 59 and 27 are pruned
+	assert.Equal(t, len(locator), 15)
+
+	// the saved chain starts after the last gap
+	chain := lc.MainChain()
+	assert.Equal(t, chain[0].Height, required)
+}
+
+func TestRetentionPolicy(t *testing.T) {
+	for _, name := range []string{"all", "last", "anchors"} {
+		mode, err := ParseRetentionMode(name)
+		assert.NilError(t, err)
+		assert.Equal(t, mode.String(), name)
+	}
+	_, err := ParseRetentionMode("some")
+	assert.Assert(t, errors.Is(err, ErrInvalidRetention), err)
+
+	assert.NilError(t, RetentionPolicy{}.Validate())
+	assert.NilError(t, RetentionPolicy{Mode: RetainLastFinalized, KeepFinalized: 1}.Validate())
+	err = RetentionPolicy{Mode: RetainLastFinalized}.Validate()
+	assert.Assert(t, errors.Is(err, ErrInvalidRetention), err)
+	err = RetentionPolicy{Mode: 5}.Validate()
+	assert.Assert(t, errors.Is(err, ErrInvalidRetention), err)
+}
+
+func TestCleanUpStaleForksSharingBlocks(t *testing.T) {
+	g := chaingen.New(&chaincfg.RegressionNetParams)
+	main := chaingen.Chain(g.Extend(g.Genesis(), 20)[19])
+	lc := NewBTCLightClientWithData(g.Params(), chaingen.Headers(main), 0)
+	checkpoint := lc.btcStore.LatestCheckPoint().Height
+
+	// two forks sharing their first block, from the checkpoint
+	stale := g.Extend(main[checkpoint], 2)
+	sibling := g.NextBlock(stale[0])
+	for _, b := range append(stale, sibling) {
+		assert.NilError(t, lc.InsertHeader(b.Header()))
+	}
+	assert.Equal(t, len(lc.btcStore.LatestBlockHashOfFork()), 3)
+
+	insertBlocks(t, lc, g.Extend(main[20], 3))
+	for _, b := range append(stale, sibling) {
+		assert.Assert(t, lc.btcStore.LightBlockByHash(b.Hash()) == nil, "block %d", b.Height)
+	}
+	assert.Equal(t, len(lc.btcStore.LatestBlockHashOfFork()), 1)
+	assertIndexesConsistent(t, lc.btcStore.(*MemStore))
+}
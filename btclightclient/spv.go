@@ -24,14 +24,30 @@ const (
 	ValidSPVProof
 )
 
+func (s SPVStatus) String() string {
+	switch s {
+	case InvalidSPVProof:
+		return "invalid"
+	case PartialValidSPVProof:
+		return "partial_valid"
+	case ValidSPVProof:
+		return "valid"
+	default:
+		return "unknown"
+	}
+}
+
 // Get SPV proof from gettxoutproof Bitcoin API.
 func SPVProofFromHex(txoutProof string, txID string) (*SPVProof, error) {
-	blockheader, err := BlockHeaderFromHex(txoutProof[:160])
+	if len(txoutProof) < BTCHeaderSize*2 {
+		return nil, ErrInvalidHeaderSize
+	}
+	blockheader, err := BlockHeaderFromHex(txoutProof[:BTCHeaderSize*2])
 	if err != nil {
 		return nil, err
 	}
 
-	pmt, err := PartialMerkleTreeFromHex(txoutProof[160:])
+	pmt, err := PartialMerkleTreeFromHex(txoutProof[BTCHeaderSize*2:])
 	if err != nil {
 		return nil, err
 	}
@@ -64,6 +80,12 @@ func (spvProof SPVProof) MerkleRoot() chainhash.Hash {
 }
 
 func (lc *BTCLightClient) VerifySPV(spvProof SPVProof) SPVStatus {
+	status := lc.verifySPV(spvProof)
+	lc.metrics.observeSPV(status)
+	return status
+}
+
+func (lc *BTCLightClient) verifySPV(spvProof SPVProof) SPVStatus {
 	lightBlock := lc.btcStore.LightBlockByHash(spvProof.BlockHash)
 
 	// light block not found in database
@@ -1,6 +1,8 @@
 package btclightclient
 
 import (
+	"bytes"
+	"encoding/hex"
 	"testing"
 
 	"github.com/btcsuite/btcd/chaincfg"
@@ -164,3 +166,25 @@ func TestBunchSPV(t *testing.T) {
 		runMutipleSPV(t, data)
 	})
 }
+
+func FuzzSPVProofFromHex(f *testing.F) {
+	proofs, txIDs := generatedTxOutProofs(f)
+	for i, proof := range proofs {
+		b, err := hex.DecodeString(proof)
+		assert.NilError(f, err)
+		f.Add(b, txIDs[i].String())
+	}
+
+	f.Fuzz(func(t *testing.T, proof []byte, txID string) {
+		spvProof, err := SPVProofFromHex(hex.EncodeToString(proof), txID)
+		if err != nil {
+			return
+		}
+		var header wire.BlockHeader
+		assert.NilError(t, header.Deserialize(bytes.NewReader(proof)))
+		assert.Equal(t, spvProof.BlockHash, header.BlockHash())
+		txHash, err := chainhash.NewHashFromStr(txID)
+		assert.NilError(t, err)
+		assert.Equal(t, spvProof.MerklePath[0], *txHash)
+	})
+}
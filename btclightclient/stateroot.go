@@ -0,0 +1,89 @@
+package btclightclient
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// stateRootVersion is the version of the StateRoot encoding.
+const stateRootVersion = 1
+
+// StateRoot returns a commitment to the light client state, which can be
+// compared across nodes: two light clients have the same root when they
+// have the same checkpoint, best tip and fork heads.
+//
+// The root is the double SHA-256 of the version byte, the network genesis
+// hash, the checkpoint, the best tip hash, the number of fork heads as a 4
+// bytes little endian integer and the fork heads sorted by hash. The blocks
+// are encoded as their hash, 4 bytes little endian height and 32 bytes big
+// endian total work.
+func (lc *BTCLightClient) StateRoot() (chainhash.Hash, error) {
+	if err := lc.CheckStore(); err != nil {
+		return chainhash.Hash{}, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(stateRootVersion)
+	buf.Write(lc.params.GenesisHash[:])
+	checkpoint := lc.btcStore.LatestCheckPoint()
+	lc.writeStateBlock(&buf, checkpoint.Header.BlockHash(), checkpoint)
+	tipHash := lc.btcStore.MostDifficultFork().Header.BlockHash()
+	buf.Write(tipHash[:])
+
+	heads := lc.btcStore.LatestBlockHashOfFork()
+	buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(heads))))
+	for _, hash := range heads {
+		lc.writeStateBlock(&buf, hash, lc.btcStore.LightBlockByHash(hash))
+	}
+	return chainhash.DoubleHashH(buf.Bytes()), nil
+}
+
+// writeStateBlock writes the block hash, height and total work. A fork head
+// missing from an inconsistent store has a zero height and work.
+func (lc *BTCLightClient) writeStateBlock(buf *bytes.Buffer, hash chainhash.Hash, lb *LightBlock) {
+	var height int32
+	if lb != nil {
+		height = lb.Height
+	}
+	buf.Write(hash[:])
+	buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(height)))
+	work := lc.btcStore.TotalWorkAtBlock(hash)
+	if work == nil {
+		work = new(big.Int)
+	}
+	buf.Write(work.FillBytes(make([]byte, 32)))
+}
+
+// StoreHash returns a commitment to the stored blocks, from which the light
+// client state can be rebuilt: two light clients loaded with the same headers
+// have the same hash, while their state roots only match on the fork heads.
+//
+// The hash is the double SHA-256 of the version byte, the network genesis
+// hash, the height and total work of the parent of the first stored block of
+// the best chain, as 4 bytes little endian and 32 bytes big endian integers,
+// the headers of the best chain and the headers of the forks not on it,
+// ordered by height.
+func (lc *BTCLightClient) StoreHash() (chainhash.Hash, error) {
+	forks, err := lc.ForkBlocks()
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+	chain := lc.MainChain()
+	first := chain[0]
+	baseWork := new(big.Int).Sub(lc.btcStore.TotalWorkAtBlock(first.Header.BlockHash()), first.CalcWork())
+
+	var buf bytes.Buffer
+	buf.WriteByte(stateRootVersion)
+	buf.Write(lc.params.GenesisHash[:])
+	buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(first.Height-1)))
+	buf.Write(baseWork.FillBytes(make([]byte, 32)))
+	for _, lb := range append(chain, forks...) {
+		if err := lb.Header.Serialize(&buf); err != nil {
+			return chainhash.Hash{}, err
+		}
+	}
+	return chainhash.DoubleHashH(buf.Bytes()), nil
+}
@@ -0,0 +1,66 @@
+package btclightclient
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/gonative-cc/bitcoin-lightclient/chaingen"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"gotest.tools/assert"
+)
+
+func TestStateRoot(t *testing.T) {
+	g := chaingen.New(&chaincfg.RegressionNetParams)
+	blocks := chaingen.Chain(g.Extend(g.Genesis(), 20)[19])
+	newLightClient := func(inserted ...*chaingen.Block) *BTCLightClient {
+		lc := NewBTCLightClientWithData(g.Params(), chaingen.Headers(blocks), 0)
+		insertBlocks(t, lc, inserted)
+		return lc
+	}
+	stateRoot := func(lc *BTCLightClient) chainhash.Hash {
+		root, err := lc.StateRoot()
+		assert.NilError(t, err)
+		return root
+	}
+
+	// the forks are committed to whatever their insertion order
+	long := g.Extend(blocks[17], 4)
+	short := g.Extend(blocks[18], 1)
+	lc := newLightClient(append(long, short...)...)
+	root := stateRoot(lc)
+	assert.Equal(t, stateRoot(newLightClient(append(short, long...)...)), root)
+	assert.Assert(t, slices.IsSortedFunc(lc.btcStore.LatestBlockHashOfFork(), func(a, b chainhash.Hash) int {
+		return bytes.Compare(a[:], b[:])
+	}))
+	status, err := lc.ChainStatus()
+	assert.NilError(t, err)
+	assert.Equal(t, status.StateRoot, root)
+
+	// the stored blocks are committed to, not only the fork heads
+	storeHash := func(lc *BTCLightClient) chainhash.Hash {
+		hash, err := lc.StoreHash()
+		assert.NilError(t, err)
+		return hash
+	}
+	assert.Equal(t, storeHash(newLightClient(append(short, long...)...)), storeHash(lc))
+	partial, err := NewBTCLightClientFromIterator(g.Params(), NewHeaderSliceIterator(chaingen.Headers(blocks[1:])), 1,
+		lc.TotalWorkAtBlock(blocks[0].Hash()))
+	assert.NilError(t, err)
+	insertBlocks(t, partial, append(long, short...))
+	assert.Equal(t, stateRoot(partial), root)
+	assert.Assert(t, storeHash(partial) != storeHash(lc))
+
+	// the first fork received wins a tie
+	a, b := g.NextBlock(blocks[20]), g.NextBlock(blocks[20])
+	assert.Assert(t, a.Hash() != b.Hash())
+	assert.Assert(t, stateRoot(newLightClient(a, b)) != stateRoot(newLightClient(b, a)))
+
+	assert.Assert(t, stateRoot(newLightClient(append(long, g.NextBlock(long[3]))...)) != root)
+
+	_, err = NewBTCLightClient(g.Params()).StateRoot()
+	assert.Assert(t, errors.Is(err, ErrStoreUnavailable), err)
+}
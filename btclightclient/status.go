@@ -0,0 +1,117 @@
+package btclightclient
+
+import (
+	"bytes"
+	"sort"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// ChainStatus is a snapshot of the light client state.
+type ChainStatus struct {
+	Network         string         `json:"network"`
+	TipHeight       int32          `json:"tip_height"`
+	TipHash         chainhash.Hash `json:"tip_hash"`
+	TipTimestamp    time.Time      `json:"tip_timestamp"`
+	FinalizedHeight int32          `json:"finalized_height"`
+	FinalizedHash   chainhash.Hash `json:"finalized_hash"`
+	Forks           int            `json:"forks"`
+	StateRoot       chainhash.Hash `json:"state_root"`
+	// AccumulatorRoot is the HeaderAccumulator root the ancestor proofs
+	// are checked against.
+	AccumulatorRoot chainhash.Hash `json:"accumulator_root"`
+}
+
+// CheckStore returns an error when the store can't serve the light client,
+// i.e. it has no checkpoint or best tip.
+func (lc *BTCLightClient) CheckStore() error {
+	if lc.btcStore == nil || lc.btcStore.LatestCheckPoint() == nil || lc.btcStore.MostDifficultFork() == nil {
+		return ErrStoreUnavailable
+	}
+	return nil
+}
+
+// ChainStatus returns the current best tip, checkpoint, number of forks,
+// state root and header accumulator root.
+func (lc *BTCLightClient) ChainStatus() (ChainStatus, error) {
+	if err := lc.CheckStore(); err != nil {
+		return ChainStatus{}, err
+	}
+
+	stateRoot, err := lc.StateRoot()
+	if err != nil {
+		return ChainStatus{}, err
+	}
+	tip := lc.btcStore.MostDifficultFork()
+	checkpoint := lc.btcStore.LatestCheckPoint()
+	var accRoot chainhash.Hash
+	if lc.headerAcc != nil {
+		accRoot = lc.headerAcc.Root()
+	}
+	return ChainStatus{
+		Network:         lc.params.Name,
+		TipHeight:       tip.Height,
+		TipHash:         tip.Header.BlockHash(),
+		TipTimestamp:    tip.Header.Timestamp,
+		FinalizedHeight: checkpoint.Height,
+		FinalizedHash:   checkpoint.Header.BlockHash(),
+		Forks:           len(lc.btcStore.LatestBlockHashOfFork()),
+		StateRoot:       stateRoot,
+		AccumulatorRoot: accRoot,
+	}, nil
+}
+
+// ForkHeads returns the heads of the forks, the best tip first, then by
+// decreasing work.
+func (lc *BTCLightClient) ForkHeads() ([]*LightBlock, error) {
+	if err := lc.CheckStore(); err != nil {
+		return nil, err
+	}
+
+	tip := lc.btcStore.MostDifficultFork()
+	heads := []*LightBlock{}
+	for _, hash := range lc.btcStore.LatestBlockHashOfFork() {
+		if lb := lc.btcStore.LightBlockByHash(hash); lb != nil {
+			heads = append(heads, lb)
+		}
+	}
+	sort.Slice(heads, func(i, j int) bool {
+		a, b := heads[i], heads[j]
+		if (a == tip) != (b == tip) {
+			return a == tip
+		}
+		hashA, hashB := a.Header.BlockHash(), b.Header.BlockHash()
+		if cmp := lc.btcStore.TotalWorkAtBlock(hashA).Cmp(lc.btcStore.TotalWorkAtBlock(hashB)); cmp != 0 {
+			return cmp > 0
+		}
+		return bytes.Compare(hashA[:], hashB[:]) < 0
+	})
+	return heads, nil
+}
+
+// ForkBlocks returns the blocks of the forks which are not on the best chain,
+// ordered by height so that the parents come first. With the best chain,
+// they are the whole state of the light client.
+func (lc *BTCLightClient) ForkBlocks() ([]*LightBlock, error) {
+	heads, err := lc.ForkHeads()
+	if err != nil {
+		return nil, err
+	}
+	tip := heads[0]
+	blocks := []*LightBlock{}
+	seen := map[chainhash.Hash]bool{}
+	for _, head := range heads[1:] {
+		for lb := head; lb != nil && !seen[lb.Header.BlockHash()]; lb = lc.btcStore.LightBlockByHash(lb.Header.PrevBlock) {
+			if lb.Height <= tip.Height {
+				if ancestor := lc.ancestorOf(tip, lb.Height); ancestor != nil && ancestor.Header.BlockHash() == lb.Header.BlockHash() {
+					break
+				}
+			}
+			seen[lb.Header.BlockHash()] = true
+			blocks = append(blocks, lb)
+		}
+	}
+	sort.SliceStable(blocks, func(i, j int) bool { return blocks[i].Height < blocks[j].Height })
+	return blocks, nil
+}
@@ -0,0 +1,397 @@
+package btclightclient
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+const (
+	// vbTopBits and vbTopMask select the blocks using BIP9 version bits.
+	vbTopBits = 0x20000000
+	vbTopMask = 0xe0000000
+)
+
+// DeploymentState is the BIP9 state of a soft fork deployment. All the blocks
+// of a confirmation window have the same state.
+type DeploymentState uint8
+
+const (
+	// DeploymentUnknown is the state of a deployment which started before
+	// the first block of the light client, its history can't be replayed.
+	DeploymentUnknown DeploymentState = iota
+	DeploymentDefined
+	DeploymentStarted
+	DeploymentLockedIn
+	DeploymentActive
+	DeploymentFailed
+)
+
+var deploymentStateStrings = map[DeploymentState]string{
+	DeploymentUnknown:  "unknown",
+	DeploymentDefined:  "defined",
+	DeploymentStarted:  "started",
+	DeploymentLockedIn: "locked_in",
+	DeploymentActive:   "active",
+	DeploymentFailed:   "failed",
+}
+
+func (s DeploymentState) String() string {
+	if str, ok := deploymentStateStrings[s]; ok {
+		return str
+	}
+	return fmt.Sprintf("DeploymentState(%d)", s)
+}
+
+func (s DeploymentState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *DeploymentState) UnmarshalText(text []byte) error {
+	for state, str := range deploymentStateStrings {
+		if str == string(text) {
+			*s = state
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown deployment state %q", text)
+}
+
+// DeploymentNames are the names of the chaincfg deployments, by deployment
+// ID.
+var DeploymentNames = [chaincfg.DefinedDeployments]string{
+	chaincfg.DeploymentTestDummy:              "testdummy",
+	chaincfg.DeploymentTestDummyMinActivation: "testdummy_min_activation",
+	chaincfg.DeploymentCSV:                    "csv",
+	chaincfg.DeploymentSegwit:                 "segwit",
+	chaincfg.DeploymentTaproot:                "taproot",
+}
+
+// DeploymentStats are the signalling statistics of a started deployment in
+// the current confirmation window.
+type DeploymentStats struct {
+	Period    uint32 `json:"period"`
+	Threshold uint32 `json:"threshold"`
+	// Elapsed is the number of finalized blocks of the window.
+	Elapsed uint32 `json:"elapsed"`
+	// Count is the number of finalized blocks of the window signalling.
+	Count uint32 `json:"count"`
+	// Possible reports whether the threshold can still be reached in the
+	// window.
+	Possible bool `json:"possible"`
+}
+
+// DeploymentInfo is the state of a deployment for the block after the last
+// finalized block.
+type DeploymentInfo struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Bit  uint8  `json:"bit"`
+	// StartTime and Timeout are unix times, zero means always started and
+	// never expiring.
+	StartTime           int64           `json:"start_time"`
+	Timeout             int64           `json:"timeout"`
+	MinActivationHeight uint32          `json:"min_activation_height"`
+	State               DeploymentState `json:"state"`
+	// Since is the height of the first block with the current state, or
+	// of the first window the light client knows.
+	Since      int32            `json:"since"`
+	Statistics *DeploymentStats `json:"statistics,omitempty"`
+}
+
+// versionBitsTracker computes the deployment states over the finalized
+// chain. Finalized blocks don't change, so the state of each confirmation
+// window is computed once, when the window is finalized.
+type versionBitsTracker struct {
+	params *chaincfg.Params
+	// firstWindow is the index of the first window with a known state.
+	firstWindow int32
+	// states[i] are the deployment states of the window firstWindow+i.
+	states [][chaincfg.DefinedDeployments]DeploymentState
+}
+
+func newVersionBitsTracker(params *chaincfg.Params) *versionBitsTracker {
+	return &versionBitsTracker{params: params}
+}
+
+// deployed reports whether the deployment is defined on the network. Zero
+// valued deployments, e.g. on testnet4 where all soft forks are buried, are
+// not.
+func deployed(d *chaincfg.ConsensusDeployment) bool {
+	return d.DeploymentStarter != nil && d.DeploymentEnder != nil
+}
+
+func deploymentStartTime(d *chaincfg.ConsensusDeployment) time.Time {
+	if s, ok := d.DeploymentStarter.(interface{ StartTime() time.Time }); ok {
+		return s.StartTime()
+	}
+	return time.Time{}
+}
+
+func deploymentEndTime(d *chaincfg.ConsensusDeployment) time.Time {
+	if e, ok := d.DeploymentEnder.(interface{ EndTime() time.Time }); ok {
+		return e.EndTime()
+	}
+	return time.Time{}
+}
+
+func (t *versionBitsTracker) window() int32 {
+	return int32(t.params.MinerConfirmationWindow)
+}
+
+func (t *versionBitsTracker) threshold(d *chaincfg.ConsensusDeployment) uint32 {
+	if d.CustomActivationThreshold != 0 {
+		return d.CustomActivationThreshold
+	}
+	return t.params.RuleChangeActivationThreshold
+}
+
+// signals reports whether the block version signals the deployment.
+func signals(version int32, d *chaincfg.ConsensusDeployment) bool {
+	v := uint32(version)
+	return v&vbTopMask == vbTopBits && v&(uint32(1)<<d.BitNumber) != 0
+}
+
+// medianTimePast returns the median time of the 11 blocks ending at height,
+// as btcd does for the deployment start and end times.
+func medianTimePast(store Store, height int32) (time.Time, bool) {
+	timestamps := make([]int64, 0, 11)
+	for h := height; h >= 0 && h > height-11; h-- {
+		lb := store.LightBlockAtHeight(int64(h))
+		if lb == nil {
+			return time.Time{}, false
+		}
+		timestamps = append(timestamps, lb.Header.Timestamp.Unix())
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+	return time.Unix(timestamps[len(timestamps)/2], 0), true
+}
+
+// update computes the states of the windows finalized since the last call.
+func (t *versionBitsTracker) update(store Store) {
+	window := t.window()
+	if window <= 0 {
+		return
+	}
+	finalized := int32(store.LatestFinalizedHeight())
+
+	if t.states == nil {
+		first := finalized
+		for first > 0 && store.LightBlockAtHeight(int64(first-1)) != nil {
+			first--
+		}
+		// The genesis window is defined for all the deployments.
+		// Otherwise the first known window is the first one with a
+		// median time past for the block before it.
+		if first == 0 {
+			t.firstWindow = 0
+		} else {
+			t.firstWindow = (first + 10 + window) / window
+		}
+		if t.firstWindow*window-1 > finalized {
+			return
+		}
+		var states [chaincfg.DefinedDeployments]DeploymentState
+		for id := range states {
+			states[id] = DeploymentDefined
+			if t.firstWindow != 0 {
+				states[id] = t.initialState(store, id, t.firstWindow*window-1)
+			}
+		}
+		t.states = append(t.states, states)
+	}
+
+	for {
+		next := t.firstWindow + int32(len(t.states))
+		last := next*window - 1
+		if last > finalized {
+			return
+		}
+		prev := t.states[len(t.states)-1]
+		var states [chaincfg.DefinedDeployments]DeploymentState
+		for id := range states {
+			states[id] = t.transition(store, id, prev[id], last)
+		}
+		t.states = append(t.states, states)
+	}
+}
+
+// initialState is the state of the window after the block at height, when
+// the previous states are unknown. A deployment not started yet is defined,
+// otherwise we can't tell.
+func (t *versionBitsTracker) initialState(store Store, id int, height int32) DeploymentState {
+	d := &t.params.Deployments[id]
+	if !deployed(d) {
+		return DeploymentDefined
+	}
+	mtp, ok := medianTimePast(store, height)
+	if ok && !t.hasStarted(d, mtp) {
+		return DeploymentDefined
+	}
+	return DeploymentUnknown
+}
+
+func (t *versionBitsTracker) hasStarted(d *chaincfg.ConsensusDeployment, mtp time.Time) bool {
+	start := deploymentStartTime(d)
+	return start.IsZero() || !mtp.Before(start)
+}
+
+func (t *versionBitsTracker) hasEnded(d *chaincfg.ConsensusDeployment, mtp time.Time) bool {
+	end := deploymentEndTime(d)
+	return !end.IsZero() && !mtp.Before(end)
+}
+
+// transition is btcd thresholdStateTransition: it returns the state of the
+// window after the block at height, the last block of a window with the
+// given state.
+func (t *versionBitsTracker) transition(store Store, id int, state DeploymentState, height int32) DeploymentState {
+	d := &t.params.Deployments[id]
+	if !deployed(d) {
+		return DeploymentDefined
+	}
+	mtp, ok := medianTimePast(store, height)
+	if !ok {
+		return DeploymentUnknown
+	}
+	// speedy trial deployments only fail at the end of a window.
+	speedy := d.MinActivationHeight != 0 || d.CustomActivationThreshold != 0
+
+	switch state {
+	case DeploymentUnknown:
+		if !t.hasStarted(d, mtp) {
+			return DeploymentDefined
+		}
+	case DeploymentDefined:
+		if !speedy && t.hasEnded(d, mtp) {
+			return DeploymentFailed
+		}
+		if t.hasStarted(d, mtp) {
+			return DeploymentStarted
+		}
+	case DeploymentStarted:
+		if !speedy && t.hasEnded(d, mtp) {
+			return DeploymentFailed
+		}
+		count, _ := t.count(store, d, height-t.window()+1, height)
+		switch {
+		case count >= t.threshold(d):
+			return DeploymentLockedIn
+		case speedy && t.hasEnded(d, mtp):
+			return DeploymentFailed
+		}
+	case DeploymentLockedIn:
+		if uint32(height)+1 >= d.MinActivationHeight {
+			return DeploymentActive
+		}
+	}
+	return state
+}
+
+// count returns the number of blocks between from and to signalling the
+// deployment, and the number of blocks counted.
+func (t *versionBitsTracker) count(store Store, d *chaincfg.ConsensusDeployment, from, to int32) (uint32, uint32) {
+	var count, elapsed uint32
+	for h := from; h <= to; h++ {
+		lb := store.LightBlockAtHeight(int64(h))
+		if lb == nil {
+			continue
+		}
+		elapsed++
+		if signals(lb.Header.Version, d) {
+			count++
+		}
+	}
+	return count, elapsed
+}
+
+// stateAt returns the state of the deployment at height.
+func (t *versionBitsTracker) stateAt(id int, height int32) (DeploymentState, bool) {
+	i := height/t.window() - t.firstWindow
+	if height < 0 || i < 0 || int(i) >= len(t.states) {
+		return DeploymentUnknown, false
+	}
+	return t.states[i][id], true
+}
+
+// info returns the state of the deployments for the block after the last
+// finalized block.
+func (t *versionBitsTracker) info(store Store) []DeploymentInfo {
+	t.update(store)
+	next := int32(store.LatestFinalizedHeight()) + 1
+	window := t.window()
+
+	infos := make([]DeploymentInfo, 0, len(t.params.Deployments))
+	for id := range t.params.Deployments {
+		d := &t.params.Deployments[id]
+		if !deployed(d) {
+			continue
+		}
+		info := DeploymentInfo{
+			ID:                  id,
+			Name:                DeploymentNames[id],
+			Bit:                 d.BitNumber,
+			MinActivationHeight: d.MinActivationHeight,
+			Since:               -1,
+		}
+		if start := deploymentStartTime(d); !start.IsZero() {
+			info.StartTime = start.Unix()
+		}
+		if end := deploymentEndTime(d); !end.IsZero() {
+			info.Timeout = end.Unix()
+		}
+
+		state, ok := t.stateAt(id, next)
+		info.State = state
+		if ok {
+			i := next/window - t.firstWindow
+			for i > 0 && t.states[i-1][id] == state {
+				i--
+			}
+			info.Since = (t.firstWindow + i) * window
+		}
+		if state == DeploymentStarted {
+			windowStart := next - next%window
+			threshold := t.threshold(d)
+			count, elapsed := t.count(store, d, windowStart, next-1)
+			info.Statistics = &DeploymentStats{
+				Period:    uint32(window),
+				Threshold: threshold,
+				Elapsed:   elapsed,
+				Count:     count,
+				Possible:  count+uint32(window)-elapsed >= threshold,
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// DeploymentInfo returns the BIP9 state of the network deployments for the
+// block after the latest finalized block, and that block's hash.
+func (lc *BTCLightClient) DeploymentInfo() (chainhash.Hash, []DeploymentInfo, error) {
+	if err := lc.CheckStore(); err != nil {
+		return chainhash.Hash{}, nil, err
+	}
+	return lc.LatestFinalizedBlockHash(), lc.versionBits.info(lc.btcStore), nil
+}
+
+// DeploymentState returns the BIP9 state of the deployment for the finalized
+// block at height. Only the state of the windows up to the block after the
+// latest finalized block is known.
+func (lc *BTCLightClient) DeploymentState(id int, height int32) (DeploymentState, error) {
+	if id < 0 || id >= len(lc.params.Deployments) {
+		return DeploymentUnknown, fmt.Errorf("%w: %d", ErrUnknownDeployment, id)
+	}
+	if err := lc.CheckStore(); err != nil {
+		return DeploymentUnknown, err
+	}
+	lc.versionBits.update(lc.btcStore)
+	state, ok := lc.versionBits.stateAt(id, height)
+	if !ok {
+		return DeploymentUnknown, fmt.Errorf("%w: no deployment state at height %d", ErrBlockNotInChain, height)
+	}
+	return state, nil
+}
@@ -0,0 +1,202 @@
+package btclightclient
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gonative-cc/bitcoin-lightclient/chaingen"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"gotest.tools/assert"
+)
+
+// versionBitsParams returns regtest params with 10 blocks confirmation
+// windows and deployments starting and ending relative to the genesis
+// timestamp:
+//   - testdummy starts at 100 minutes and locks in from bit 28 votes
+//   - csv is always started and times out at 200 minutes without votes
+//   - taproot is a speedy trial deployment with a minimum activation height
+//   - testdummy_min_activation starts in the far future
+//   - segwit is not deployed
+func versionBitsParams() *chaincfg.Params {
+	params := chaincfg.RegressionNetParams
+	params.MinerConfirmationWindow = 10
+	params.RuleChangeActivationThreshold = 8
+	genesisTime := params.GenesisBlock.Header.Timestamp
+
+	params.Deployments = [chaincfg.DefinedDeployments]chaincfg.ConsensusDeployment{}
+	params.Deployments[chaincfg.DeploymentTestDummy] = chaincfg.ConsensusDeployment{
+		BitNumber:         28,
+		DeploymentStarter: chaincfg.NewMedianTimeDeploymentStarter(genesisTime.Add(100 * time.Minute)),
+		DeploymentEnder:   chaincfg.NewMedianTimeDeploymentEnder(time.Time{}),
+	}
+	params.Deployments[chaincfg.DeploymentTestDummyMinActivation] = chaincfg.ConsensusDeployment{
+		BitNumber:         22,
+		DeploymentStarter: chaincfg.NewMedianTimeDeploymentStarter(genesisTime.Add(24 * time.Hour)),
+		DeploymentEnder:   chaincfg.NewMedianTimeDeploymentEnder(time.Time{}),
+	}
+	params.Deployments[chaincfg.DeploymentCSV] = chaincfg.ConsensusDeployment{
+		BitNumber:         0,
+		DeploymentStarter: chaincfg.NewMedianTimeDeploymentStarter(time.Time{}),
+		DeploymentEnder:   chaincfg.NewMedianTimeDeploymentEnder(genesisTime.Add(200 * time.Minute)),
+	}
+	params.Deployments[chaincfg.DeploymentTaproot] = chaincfg.ConsensusDeployment{
+		BitNumber:                 2,
+		CustomActivationThreshold: 6,
+		MinActivationHeight:       60,
+		DeploymentStarter:         chaincfg.NewMedianTimeDeploymentStarter(time.Time{}),
+		DeploymentEnder:           chaincfg.NewMedianTimeDeploymentEnder(time.Time{}),
+	}
+	return &params
+}
+
+// versionBitsChain generates 75 blocks, 6 blocks of the second window signal
+// taproot and the third window signals testdummy.
+func versionBitsChain(g *chaingen.Generator) []*chaingen.Block {
+	blocks := []*chaingen.Block{g.Genesis()}
+	for height := 1; height <= 75; height++ {
+		version := int32(chaingen.BlockVersion)
+		switch {
+		case height >= 10 && height < 16:
+			version |= 1 << 2
+		case height >= 20 && height < 30:
+			version |= 1 << 28
+		}
+		blocks = append(blocks, g.NextBlock(blocks[height-1], chaingen.WithVersion(version)))
+	}
+	return blocks
+}
+
+func TestDeploymentStates(t *testing.T) {
+	params := versionBitsParams()
+	g := chaingen.New(params)
+	blocks := versionBitsChain(g)
+	lc := newGeneratedLightClient(g)
+	insertBlocks(t, lc, blocks[1:])
+	assert.Equal(t, lc.LatestFinalizedBlockHeight(), int64(75-MaxForkAge+1))
+
+	// states by window
+	expected := map[int][]DeploymentState{
+		chaincfg.DeploymentTestDummy: {
+			DeploymentDefined, DeploymentDefined, DeploymentStarted, DeploymentLockedIn,
+			DeploymentActive, DeploymentActive, DeploymentActive,
+		},
+		chaincfg.DeploymentTestDummyMinActivation: {
+			DeploymentDefined, DeploymentDefined, DeploymentDefined, DeploymentDefined,
+			DeploymentDefined, DeploymentDefined, DeploymentDefined,
+		},
+		chaincfg.DeploymentCSV: {
+			DeploymentDefined, DeploymentStarted, DeploymentStarted, DeploymentFailed,
+			DeploymentFailed, DeploymentFailed, DeploymentFailed,
+		},
+		chaincfg.DeploymentTaproot: {
+			DeploymentDefined, DeploymentStarted, DeploymentLockedIn, DeploymentLockedIn,
+			DeploymentLockedIn, DeploymentLockedIn, DeploymentActive,
+		},
+	}
+	for id, states := range expected {
+		for window, state := range states {
+			for _, height := range []int32{int32(window) * 10, int32(window)*10 + 9} {
+				got, err := lc.DeploymentState(id, height)
+				assert.NilError(t, err)
+				assert.Equal(t, got, state, "%s at height %d", DeploymentNames[id], height)
+			}
+		}
+	}
+
+	_, err := lc.DeploymentState(chaincfg.DeploymentCSV, 70)
+	assert.Assert(t, err != nil)
+	_, err = lc.DeploymentState(chaincfg.DefinedDeployments, 0)
+	assert.Assert(t, errors.Is(err, ErrUnknownDeployment), err)
+
+	hash, infos, err := lc.DeploymentInfo()
+	assert.NilError(t, err)
+	assert.Equal(t, hash, blocks[68].Hash())
+	// segwit is not deployed
+	assert.Equal(t, len(infos), 4)
+	byName := map[string]DeploymentInfo{}
+	for _, info := range infos {
+		byName[info.Name] = info
+	}
+	assert.Equal(t, byName["testdummy"].State, DeploymentActive)
+	assert.Equal(t, byName["testdummy"].Since, int32(40))
+	assert.Equal(t, byName["csv"].State, DeploymentFailed)
+	assert.Equal(t, byName["csv"].Since, int32(30))
+	assert.Equal(t, byName["csv"].Timeout, params.GenesisBlock.Header.Timestamp.Add(200*time.Minute).Unix())
+	assert.Equal(t, byName["taproot"].State, DeploymentActive)
+	assert.Equal(t, byName["taproot"].Since, int32(60))
+	assert.Equal(t, byName["taproot"].MinActivationHeight, uint32(60))
+	assert.Equal(t, byName["testdummy_min_activation"].State, DeploymentDefined)
+	assert.Equal(t, byName["testdummy_min_activation"].Since, int32(0))
+	assert.Assert(t, byName["testdummy"].Statistics == nil)
+
+	out, err := json.Marshal(byName["taproot"])
+	assert.NilError(t, err)
+	var decoded DeploymentInfo
+	assert.NilError(t, json.Unmarshal(out, &decoded))
+	assert.DeepEqual(t, decoded, byName["taproot"])
+}
+
+func TestDeploymentStatistics(t *testing.T) {
+	params := versionBitsParams()
+	g := chaingen.New(params)
+	blocks := versionBitsChain(g)
+	lc := newGeneratedLightClient(g)
+	// finalize up to height 14, in the taproot signalling window
+	insertBlocks(t, lc, blocks[1:22])
+	assert.Equal(t, lc.LatestFinalizedBlockHeight(), int64(14))
+
+	_, infos, err := lc.DeploymentInfo()
+	assert.NilError(t, err)
+	for _, info := range infos {
+		if info.Name != "taproot" {
+			continue
+		}
+		assert.Equal(t, info.State, DeploymentStarted)
+		assert.DeepEqual(t, info.Statistics, &DeploymentStats{
+			Period:    10,
+			Threshold: 6,
+			Elapsed:   5,
+			Count:     5,
+			Possible:  true,
+		})
+	}
+}
+
+func TestDeploymentStatesFromCheckpoint(t *testing.T) {
+	params := versionBitsParams()
+	g := chaingen.New(params)
+	blocks := versionBitsChain(g)
+
+	// the light client starts after csv, taproot and testdummy started,
+	// their history can't be replayed. testdummy_min_activation starts later.
+	lc := NewBTCLightClientWithData(params, chaingen.Headers(blocks[5:60]), 5)
+	lc.versionBits.update(lc.btcStore)
+	assert.Equal(t, lc.versionBits.firstWindow, int32(2))
+
+	for id, state := range map[int]DeploymentState{
+		chaincfg.DeploymentTestDummy:              DeploymentUnknown,
+		chaincfg.DeploymentTestDummyMinActivation: DeploymentDefined,
+		chaincfg.DeploymentCSV:                    DeploymentUnknown,
+		chaincfg.DeploymentTaproot:                DeploymentUnknown,
+	} {
+		for _, height := range []int32{20, 49} {
+			got, err := lc.DeploymentState(id, height)
+			assert.NilError(t, err)
+			assert.Equal(t, got, state, "%s at height %d", DeploymentNames[id], height)
+		}
+	}
+	_, err := lc.DeploymentState(chaincfg.DeploymentTestDummy, 19)
+	assert.Assert(t, err != nil)
+}
+
+func TestDeploymentsNotDefined(t *testing.T) {
+	// all testnet4 soft forks are buried
+	lc := NewBTCLightClientWithData(&TestNet4Params, []wire.BlockHeader{TestNet4Params.GenesisBlock.Header}, 0)
+	_, infos, err := lc.DeploymentInfo()
+	assert.NilError(t, err)
+	assert.Equal(t, len(infos), 0)
+}
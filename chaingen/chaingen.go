@@ -0,0 +1,367 @@
+// Package chaingen generates deterministic chains of real proof of work
+// blocks for tests. Blocks have valid difficulty bits, strictly increasing
+// timestamps and merkle roots computed from real coinbase and payment
+// transactions, so the headers pass full header validation and the
+// transactions can be proven with gettxoutproof style proofs.
+package chaingen
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// BlockVersion is the version of generated blocks, the version bits top bits
+// with no deployment signaled.
+const BlockVersion = 0x20000000
+
+// Block is a generated block and its position in the block tree.
+type Block struct {
+	Height   int32
+	MsgBlock *wire.MsgBlock
+	Parent   *Block
+}
+
+// Hash returns the block hash.
+func (b *Block) Hash() chainhash.Hash {
+	return b.MsgBlock.Header.BlockHash()
+}
+
+// Header returns the block header.
+func (b *Block) Header() wire.BlockHeader {
+	return b.MsgBlock.Header
+}
+
+// TxIDs returns the IDs of the block transactions, starting with the
+// coinbase.
+func (b *Block) TxIDs() []chainhash.Hash {
+	ids := make([]chainhash.Hash, len(b.MsgBlock.Transactions))
+	for i, tx := range b.MsgBlock.Transactions {
+		ids[i] = tx.TxHash()
+	}
+	return ids
+}
+
+// Ancestor returns the ancestor of b at height, or nil if height is not
+// between the generator genesis and b.
+func (b *Block) Ancestor(height int32) *Block {
+	for ; b != nil && b.Height > height; b = b.Parent {
+	}
+	if b == nil || b.Height != height {
+		return nil
+	}
+	return b
+}
+
+// Generator builds a block tree on top of a genesis block. Generated blocks
+// only depend on their parent, the options and the number of blocks
+// generated before, so the same sequence of calls always builds the same
+// tree.
+type Generator struct {
+	params  *chaincfg.Params
+	genesis *Block
+	blocks  map[chainhash.Hash]*Block
+	// extraNonce is put in every coinbase, so siblings built with the same
+	// options are different blocks.
+	extraNonce int64
+
+	// BIP94 computes the retarget from the first block of the period, as
+	// testnet4 does.
+	BIP94 bool
+}
+
+// New creates a generator starting at the network genesis block.
+func New(params *chaincfg.Params) *Generator {
+	return NewFromBlock(params, params.GenesisBlock, 0)
+}
+
+// NewFromBlock creates a generator starting at block. The block is trusted,
+// it is neither validated nor mined again.
+func NewFromBlock(params *chaincfg.Params, block *wire.MsgBlock, height int32) *Generator {
+	genesis := &Block{Height: height, MsgBlock: block}
+	return &Generator{
+		params:  params,
+		genesis: genesis,
+		blocks:  map[chainhash.Hash]*Block{genesis.Hash(): genesis},
+	}
+}
+
+// Params returns the network params of the generated blocks.
+func (g *Generator) Params() *chaincfg.Params {
+	return g.params
+}
+
+// Genesis returns the first block of the tree.
+func (g *Generator) Genesis() *Block {
+	return g.genesis
+}
+
+// Block returns the generated block with the given hash, or nil.
+func (g *Generator) Block(hash chainhash.Hash) *Block {
+	return g.blocks[hash]
+}
+
+type blockConfig struct {
+	timestamp time.Time
+	timeDelta time.Duration
+	version   int32
+	bits      uint32
+	numTxs    int
+}
+
+// BlockOption customizes a generated block.
+type BlockOption func(*blockConfig)
+
+// WithTimestamp sets the block timestamp. It takes precedence over
+// WithTimeDelta.
+func WithTimestamp(t time.Time) BlockOption {
+	return func(c *blockConfig) {
+		c.timestamp = t
+	}
+}
+
+// WithTimeDelta sets the time between the parent and the block. It defaults
+// to the network target time per block.
+func WithTimeDelta(d time.Duration) BlockOption {
+	return func(c *blockConfig) {
+		c.timeDelta = d
+	}
+}
+
+// WithVersion sets the block version.
+func WithVersion(version int32) BlockOption {
+	return func(c *blockConfig) {
+		c.version = version
+	}
+}
+
+// WithBits overrides the difficulty bits computed from the network rules.
+// The block is mined for the given bits, it is useful to build invalid
+// blocks.
+func WithBits(bits uint32) BlockOption {
+	return func(c *blockConfig) {
+		c.bits = bits
+	}
+}
+
+// WithTxs adds n payment transactions after the coinbase.
+func WithTxs(n int) BlockOption {
+	return func(c *blockConfig) {
+		c.numTxs = n
+	}
+}
+
+// NextBlock mines a block on top of parent. parent can be any generated
+// block, building on a block that already has children creates a fork.
+func (g *Generator) NextBlock(parent *Block, opts ...BlockOption) *Block {
+	if g.blocks[parent.Hash()] != parent {
+		panic(fmt.Sprintf("chaingen: parent %s is not part of the generator tree", parent.Hash()))
+	}
+
+	cfg := blockConfig{
+		timeDelta: g.params.TargetTimePerBlock,
+		version:   BlockVersion,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	timestamp := cfg.timestamp
+	if timestamp.IsZero() {
+		timestamp = parent.MsgBlock.Header.Timestamp.Add(cfg.timeDelta)
+	}
+	bits := cfg.bits
+	if bits == 0 {
+		bits = g.nextRequiredBits(parent, timestamp)
+	}
+
+	height := parent.Height + 1
+	g.extraNonce++
+	coinbase := g.coinbaseTx(height, g.extraNonce)
+	txs := []*wire.MsgTx{coinbase}
+	for i := 0; i < cfg.numTxs; i++ {
+		txs = append(txs, spendTx(txs[i], g.extraNonce, i))
+	}
+
+	block := &wire.MsgBlock{
+		Header: wire.BlockHeader{
+			Version:    cfg.version,
+			PrevBlock:  parent.Hash(),
+			MerkleRoot: calcMerkleRoot(txs),
+			Timestamp:  timestamp,
+			Bits:       bits,
+		},
+		Transactions: txs,
+	}
+	solve(&block.Header)
+
+	b := &Block{Height: height, MsgBlock: block, Parent: parent}
+	g.blocks[b.Hash()] = b
+	return b
+}
+
+// Extend mines n blocks on top of parent and returns them ordered by height.
+func (g *Generator) Extend(parent *Block, n int, opts ...BlockOption) []*Block {
+	blocks := make([]*Block, n)
+	for i := range blocks {
+		parent = g.NextBlock(parent, opts...)
+		blocks[i] = parent
+	}
+	return blocks
+}
+
+// Headers returns the headers of blocks.
+func Headers(blocks []*Block) []wire.BlockHeader {
+	headers := make([]wire.BlockHeader, len(blocks))
+	for i, b := range blocks {
+		headers[i] = b.Header()
+	}
+	return headers
+}
+
+// Chain returns the blocks from the generator genesis up to tip, ordered by
+// height.
+func Chain(tip *Block) []*Block {
+	var blocks []*Block
+	for b := tip; b != nil; b = b.Parent {
+		blocks = append(blocks, b)
+	}
+	for i, j := 0, len(blocks)-1; i < j; i, j = i+1, j-1 {
+		blocks[i], blocks[j] = blocks[j], blocks[i]
+	}
+	return blocks
+}
+
+// RegtestChain generates the chain of the RPC tests: a 20 blocks regtest
+// chain ordered by height, the block at height 5 has 3 transactions, and the
+// next 3 blocks on its tip.
+func RegtestChain() (g *Generator, blocks []*Block, next []*Block) {
+	g = New(&chaincfg.RegressionNetParams)
+	withTxs := g.NextBlock(g.Extend(g.Genesis(), 4)[3], WithTxs(3))
+	blocks = Chain(g.Extend(withTxs, 15)[14])
+	return g, blocks, g.Extend(blocks[20], 3)
+}
+
+// coinbaseTx creates a coinbase paying the block subsidy to an anyone can
+// spend output. The script starts with the height as required by BIP34.
+func (g *Generator) coinbaseTx(height int32, extraNonce int64) *wire.MsgTx {
+	sigScript, err := txscript.NewScriptBuilder().
+		AddInt64(int64(height)).
+		AddInt64(extraNonce).
+		Script()
+	if err != nil {
+		panic(err)
+	}
+
+	tx := wire.NewMsgTx(1)
+	tx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: *wire.NewOutPoint(&chainhash.Hash{}, wire.MaxPrevOutIndex),
+		SignatureScript:  sigScript,
+		Sequence:         wire.MaxTxInSequenceNum,
+	})
+	tx.AddTxOut(wire.NewTxOut(blockchain.CalcBlockSubsidy(height, g.params), []byte{txscript.OP_TRUE}))
+	return tx
+}
+
+// spendTx creates a transaction spending the first output of prev.
+func spendTx(prev *wire.MsgTx, extraNonce int64, i int) *wire.MsgTx {
+	prevHash := prev.TxHash()
+	sigScript, err := txscript.NewScriptBuilder().
+		AddInt64(extraNonce).
+		AddInt64(int64(i)).
+		Script()
+	if err != nil {
+		panic(err)
+	}
+
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: *wire.NewOutPoint(&prevHash, 0),
+		SignatureScript:  sigScript,
+		Sequence:         wire.MaxTxInSequenceNum,
+	})
+	// pay a 1000 sat fee
+	tx.AddTxOut(wire.NewTxOut(prev.TxOut[0].Value-1000, []byte{txscript.OP_TRUE}))
+	return tx
+}
+
+func calcMerkleRoot(txs []*wire.MsgTx) chainhash.Hash {
+	utilTxs := make([]*btcutil.Tx, len(txs))
+	for i, tx := range txs {
+		utilTxs[i] = btcutil.NewTx(tx)
+	}
+	return blockchain.CalcMerkleRoot(utilTxs, false)
+}
+
+// solve searches the nonce making the header hash meet its target. When all
+// nonces are exhausted the timestamp is bumped by a second.
+func solve(header *wire.BlockHeader) {
+	target := blockchain.CompactToBig(header.Bits)
+	for {
+		for nonce := uint32(0); ; nonce++ {
+			header.Nonce = nonce
+			hash := header.BlockHash()
+			if blockchain.HashToBig(&hash).Cmp(target) <= 0 {
+				return
+			}
+			if nonce == ^uint32(0) {
+				break
+			}
+		}
+		header.Timestamp = header.Timestamp.Add(time.Second)
+	}
+}
+
+// nextRequiredBits computes the difficulty of a block built on parent at
+// timestamp, following the network consensus rules.
+func (g *Generator) nextRequiredBits(parent *Block, timestamp time.Time) uint32 {
+	params := g.params
+	if params.PoWNoRetargeting {
+		return params.PowLimitBits
+	}
+
+	blocksPerRetarget := int32(params.TargetTimespan / params.TargetTimePerBlock)
+	if (parent.Height+1)%blocksPerRetarget != 0 {
+		if !params.ReduceMinDifficulty {
+			return parent.MsgBlock.Header.Bits
+		}
+		// testnet special rule: a block more than MinDiffReductionTime
+		// after its parent can be mined at the minimum difficulty.
+		if timestamp.After(parent.MsgBlock.Header.Timestamp.Add(params.MinDiffReductionTime)) {
+			return params.PowLimitBits
+		}
+		b := parent
+		for b.Parent != nil && b.Height%blocksPerRetarget != 0 && b.MsgBlock.Header.Bits == params.PowLimitBits {
+			b = b.Parent
+		}
+		return b.MsgBlock.Header.Bits
+	}
+
+	first := parent.Ancestor(parent.Height - (blocksPerRetarget - 1))
+	if first == nil {
+		panic(fmt.Sprintf("chaingen: retarget at height %d needs block %d, before the generator genesis",
+			parent.Height+1, parent.Height-(blocksPerRetarget-1)))
+	}
+
+	targetTimespan := int64(params.TargetTimespan / time.Second)
+	timespan := parent.MsgBlock.Header.Timestamp.Unix() - first.MsgBlock.Header.Timestamp.Unix()
+	timespan = max(timespan, targetTimespan/params.RetargetAdjustmentFactor)
+	timespan = min(timespan, targetTimespan*params.RetargetAdjustmentFactor)
+
+	target := blockchain.CompactToBig(parent.MsgBlock.Header.Bits)
+	if g.BIP94 {
+		target = blockchain.CompactToBig(first.MsgBlock.Header.Bits)
+	}
+	target.Mul(target, big.NewInt(timespan))
+	target.Div(target, big.NewInt(targetTimespan))
+	if target.Cmp(params.PowLimit) > 0 {
+		target.Set(params.PowLimit)
+	}
+	return blockchain.BigToCompact(target)
+}
@@ -0,0 +1,109 @@
+package chaingen
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"gotest.tools/assert"
+)
+
+func TestNextBlock(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	g := New(params)
+	blocks := g.Extend(g.Genesis(), 5, WithTxs(3))
+
+	parent := g.Genesis()
+	for i, b := range blocks {
+		header := b.Header()
+		assert.Equal(t, b.Height, int32(i+1))
+		assert.Equal(t, header.PrevBlock, parent.Hash())
+		assert.Equal(t, header.Bits, params.PowLimitBits)
+		assert.Equal(t, header.Timestamp, parent.Header().Timestamp.Add(params.TargetTimePerBlock))
+		assert.Equal(t, len(b.MsgBlock.Transactions), 4)
+		assert.Assert(t, blockchain.IsCoinBaseTx(b.MsgBlock.Transactions[0]))
+
+		block := btcutil.NewBlock(b.MsgBlock)
+		block.SetHeight(b.Height)
+		assert.NilError(t, blockchain.CheckBlockSanity(block, params.PowLimit, blockchain.NewMedianTime()))
+		assert.NilError(t, blockchain.CheckSerializedHeight(block.Transactions()[0], b.Height))
+		assert.Equal(t, g.Block(b.Hash()), b)
+		parent = b
+	}
+	assert.DeepEqual(t, Chain(parent)[1:], blocks)
+	assert.Equal(t, parent.Ancestor(2), blocks[1])
+	assert.Assert(t, parent.Ancestor(6) == nil)
+}
+
+func TestDeterministic(t *testing.T) {
+	build := func() []*Block {
+		g := New(&chaincfg.RegressionNetParams)
+		main := g.Extend(g.Genesis(), 3)
+		return append(main, g.Extend(main[0], 3, WithTxs(2))...)
+	}
+	a, b := build(), build()
+	for i := range a {
+		assert.Equal(t, a[i].Hash(), b[i].Hash())
+	}
+}
+
+func TestFork(t *testing.T) {
+	g := New(&chaincfg.RegressionNetParams)
+	main := g.Extend(g.Genesis(), 4)
+	fork := g.Extend(main[1], 4)
+
+	assert.Equal(t, fork[0].Header().PrevBlock, main[1].Hash())
+	assert.Equal(t, fork[0].Height, main[2].Height)
+	assert.Assert(t, fork[0].Hash() != main[2].Hash())
+	assert.Equal(t, fork[3].Ancestor(main[1].Height), main[1])
+}
+
+func TestRetarget(t *testing.T) {
+	params := chaincfg.RegressionNetParams
+	params.PoWNoRetargeting = false
+	params.TargetTimespan = 20 * params.TargetTimePerBlock
+
+	g := New(&params)
+	// blocks are twice faster than the target, the difficulty doubles at
+	// the retarget.
+	blocks := g.Extend(g.Genesis(), 20, WithTimeDelta(params.TargetTimePerBlock/2))
+	parent, retarget := blocks[18].Header(), blocks[19].Header()
+	assert.Equal(t, parent.Bits, params.PowLimitBits)
+
+	timespan := int64(parent.Timestamp.Sub(g.Genesis().Header().Timestamp) / time.Second)
+	expected := blockchain.CompactToBig(parent.Bits)
+	expected.Mul(expected, big.NewInt(timespan))
+	expected.Div(expected, big.NewInt(int64(params.TargetTimespan/time.Second)))
+	assert.Equal(t, retarget.Bits, blockchain.BigToCompact(expected))
+	assert.Assert(t, retarget.Bits != parent.Bits)
+}
+
+func TestReduceMinDifficulty(t *testing.T) {
+	params := chaincfg.RegressionNetParams
+	params.PoWNoRetargeting = false
+	params.ReduceMinDifficulty = true
+	params.MinDiffReductionTime = 2 * params.TargetTimePerBlock
+
+	g := New(&params)
+	b := g.NextBlock(g.Genesis(), WithBits(0x1f7fffff))
+	slow := g.NextBlock(b, WithTimeDelta(time.Hour))
+	assert.Equal(t, slow.Header().Bits, params.PowLimitBits)
+	// the next block on time walks back to the last block not mined at
+	// the minimum difficulty
+	assert.Equal(t, g.NextBlock(slow).Header().Bits, uint32(0x1f7fffff))
+}
+
+func TestRegtestChain(t *testing.T) {
+	g, blocks, next := RegtestChain()
+	assert.Equal(t, len(blocks), 21)
+	for i, b := range blocks {
+		assert.Equal(t, b.Height, int32(i))
+	}
+	assert.Equal(t, blocks[0], g.Genesis())
+	assert.Equal(t, len(blocks[5].TxIDs()), 4)
+	assert.Equal(t, len(next), 3)
+	assert.Equal(t, next[0].Parent, blocks[20])
+}
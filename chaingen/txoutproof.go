@@ -0,0 +1,113 @@
+package chaingen
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// TxOutProof returns the proof that txIDs are part of the block, hex encoded
+// as returned by bitcoind gettxoutproof: the block header followed by the
+// partial merkle tree matching txIDs.
+func (b *Block) TxOutProof(txIDs ...chainhash.Hash) (string, error) {
+	leaves := b.TxIDs()
+	matches := make([]bool, len(leaves))
+	for _, id := range txIDs {
+		found := false
+		for i, leaf := range leaves {
+			if leaf == id {
+				matches[i], found = true, true
+			}
+		}
+		if !found {
+			return "", fmt.Errorf("transaction %s not found in block %s", id, b.Hash())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := b.MsgBlock.Header.Serialize(&buf); err != nil {
+		return "", err
+	}
+	if err := newPartialMerkleTree(leaves, matches).serialize(&buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf.Bytes()), nil
+}
+
+// partialMerkleTree is the encoder counterpart of the light client partial
+// merkle tree decoder, built as Bitcoin Core CPartialMerkleTree does.
+type partialMerkleTree struct {
+	leaves []chainhash.Hash
+	bits   []bool
+	hashes []chainhash.Hash
+}
+
+func newPartialMerkleTree(leaves []chainhash.Hash, matches []bool) *partialMerkleTree {
+	pmt := &partialMerkleTree{leaves: leaves}
+	height := uint32(0)
+	for pmt.width(height) > 1 {
+		height++
+	}
+	pmt.traverseAndBuild(height, 0, matches)
+	return pmt
+}
+
+// width returns the number of nodes at height.
+func (pmt *partialMerkleTree) width(height uint32) uint32 {
+	return (uint32(len(pmt.leaves)) + (1 << height) - 1) >> height
+}
+
+// hash computes the merkle node at height and pos.
+func (pmt *partialMerkleTree) hash(height, pos uint32) chainhash.Hash {
+	if height == 0 {
+		return pmt.leaves[pos]
+	}
+	left := pmt.hash(height-1, pos*2)
+	right := left
+	if pos*2+1 < pmt.width(height-1) {
+		right = pmt.hash(height-1, pos*2+1)
+	}
+	return chainhash.DoubleHashH(append(left[:], right[:]...))
+}
+
+// traverseAndBuild walks the tree depth first, storing one bit per node
+// telling whether a matched leaf is below it, and the hashes of the nodes
+// with no matched leaf below and of the leaves.
+func (pmt *partialMerkleTree) traverseAndBuild(height, pos uint32, matches []bool) {
+	parentOfMatch := false
+	for p := pos << height; p < (pos+1)<<height && p < uint32(len(pmt.leaves)); p++ {
+		parentOfMatch = parentOfMatch || matches[p]
+	}
+	pmt.bits = append(pmt.bits, parentOfMatch)
+
+	if height == 0 || !parentOfMatch {
+		pmt.hashes = append(pmt.hashes, pmt.hash(height, pos))
+		return
+	}
+	pmt.traverseAndBuild(height-1, pos*2, matches)
+	if pos*2+1 < pmt.width(height-1) {
+		pmt.traverseAndBuild(height-1, pos*2+1, matches)
+	}
+}
+
+func (pmt *partialMerkleTree) serialize(buf *bytes.Buffer) error {
+	buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(pmt.leaves))))
+	if err := wire.WriteVarInt(buf, 0, uint64(len(pmt.hashes))); err != nil {
+		return err
+	}
+	for _, h := range pmt.hashes {
+		buf.Write(h[:])
+	}
+
+	flags := make([]byte, (len(pmt.bits)+7)/8)
+	for i, bit := range pmt.bits {
+		if bit {
+			flags[i/8] |= 1 << (i % 8)
+		}
+	}
+	return wire.WriteVarBytes(buf, 0, flags)
+}
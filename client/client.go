@@ -0,0 +1,239 @@
+// Package client is a Go client of the light client JSON-RPC API served by
+// rpcserver. It shares the request and response types of rpcserver and
+// btclightclient.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
+	"github.com/gonative-cc/bitcoin-lightclient/rpcserver"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/filecoin-project/go-jsonrpc"
+)
+
+// namespace is the name the RPC handler is registered with on the server.
+const namespace = "RPCServerHandler"
+
+// api is filled by go-jsonrpc with the methods of rpcserver.RPCServerHandler.
+type api struct {
+	Ping                func(ctx context.Context, in int) (int, error)
+	InsertHeaders       func(ctx context.Context, headers []*wire.BlockHeader) error
+	InsertHeadersHex    func(ctx context.Context, headers []string) ([]rpcserver.HeaderResult, error)
+	InsertHeadersBase64 func(ctx context.Context, headers []string) ([]rpcserver.HeaderResult, error)
+	ContainsBTCBlock    func(ctx context.Context, hash *chainhash.Hash) (bool, error)
+	GetHeaderChainTip   func(ctx context.Context) (rpcserver.Block, error)
+	GetAncestor         func(ctx context.Context, hash *chainhash.Hash, height int64) (rpcserver.Block, error)
+	GetBlockLocator     func(ctx context.Context, hash *chainhash.Hash) ([]*chainhash.Hash, error)
+	GetAncestorProof    func(ctx context.Context, height int64) (rpcserver.AncestorProof, error)
+	VerifySPV           func(ctx context.Context, proof *btclightclient.SPVProof) (btclightclient.SPVStatus, error)
+	VerifySPVs          func(ctx context.Context, proofs []btclightclient.SPVProof) ([]btclightclient.SPVStatus, error)
+	GetStatus           func(ctx context.Context) (rpcserver.Status, error)
+	GetDeploymentInfo   func(ctx context.Context) (rpcserver.DeploymentInfo, error)
+}
+
+// Client calls the light client JSON-RPC API over HTTP. Calls failing to
+// reach the server are retried, errors returned by the server are not.
+type Client struct {
+	api    api
+	closer jsonrpc.ClientCloser
+	opts   options
+}
+
+type options struct {
+	attempts   int
+	backoff    time.Duration
+	timeout    time.Duration
+	header     http.Header
+	httpClient *http.Client
+	network    string
+}
+
+// Option configures a Client.
+type Option func(*options)
+
+// WithRetry sets the number of attempts of a call failing to reach the
+// server, 3 by default, and the delay before the first retry, doubled after
+// each attempt, 100ms by default.
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.attempts = max(attempts, 1)
+		o.backoff = backoff
+	}
+}
+
+// WithTimeout sets the timeout of each request, 30s by default.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithHeader sets HTTP headers sent with each request, e.g. Authorization.
+func WithHeader(header http.Header) Option {
+	return func(o *options) {
+		o.header = header
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to send the requests.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = httpClient
+	}
+}
+
+// WithNetwork selects the network of a server hosting several networks, the
+// requests are sent under its path prefix. The server default network is
+// used otherwise.
+func WithNetwork(name string) Option {
+	return func(o *options) {
+		o.network = name
+	}
+}
+
+// New creates a client of the light client RPC server at addr, an http or
+// https URL such as http://localhost:9797.
+func New(ctx context.Context, addr string, opts ...Option) (*Client, error) {
+	c := &Client{
+		opts: options{
+			attempts: 3,
+			backoff:  100 * time.Millisecond,
+			timeout:  30 * time.Second,
+		},
+	}
+	for _, opt := range opts {
+		opt(&c.opts)
+	}
+
+	rpcOpts := []jsonrpc.Option{jsonrpc.WithTimeout(c.opts.timeout)}
+	if c.opts.httpClient != nil {
+		rpcOpts = append(rpcOpts, jsonrpc.WithHTTPClient(c.opts.httpClient))
+	}
+	if c.opts.network != "" {
+		addr = strings.TrimSuffix(addr, "/") + "/" + c.opts.network + "/"
+	}
+	closer, err := jsonrpc.NewMergeClient(ctx, addr, namespace, []interface{}{&c.api}, c.opts.header, rpcOpts...)
+	if err != nil {
+		return nil, err
+	}
+	c.closer = closer
+	return c, nil
+}
+
+// Close releases the client resources.
+func (c *Client) Close() {
+	c.closer()
+}
+
+// retry calls f until it reaches the server, the attempts are exhausted or
+// the context is done.
+func retry[T any](ctx context.Context, c *Client, f func() (T, error)) (T, error) {
+	backoff := c.opts.backoff
+	for attempt := 1; ; attempt++ {
+		res, err := f()
+		var connErr *jsonrpc.RPCConnectionError
+		if err == nil || !errors.As(err, &connErr) || attempt >= c.opts.attempts {
+			return res, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return res, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// Ping returns in, it checks the server is reachable.
+func (c *Client) Ping(ctx context.Context, in int) (int, error) {
+	return retry(ctx, c, func() (int, error) { return c.api.Ping(ctx, in) })
+}
+
+// InsertHeaders inserts the headers in order, it stops at the first invalid
+// header. It is retried: the headers already stored are skipped.
+func (c *Client) InsertHeaders(ctx context.Context, headers []wire.BlockHeader) error {
+	ptrs := make([]*wire.BlockHeader, len(headers))
+	for i := range headers {
+		ptrs[i] = &headers[i]
+	}
+	_, err := retry(ctx, c, func() (struct{}, error) { return struct{}{}, c.api.InsertHeaders(ctx, ptrs) })
+	return err
+}
+
+// InsertHeadersHex inserts 160 characters hex encoded headers in order and
+// returns the outcome of each header. It is retried: the headers already
+// inserted are reported as duplicates.
+func (c *Client) InsertHeadersHex(ctx context.Context, headers []string) ([]rpcserver.HeaderResult, error) {
+	return retry(ctx, c, func() ([]rpcserver.HeaderResult, error) { return c.api.InsertHeadersHex(ctx, headers) })
+}
+
+// InsertHeadersBase64 inserts the headers, sent base64 encoded, in order and
+// returns the outcome of each header. It is retried as InsertHeadersHex.
+func (c *Client) InsertHeadersBase64(ctx context.Context, headers []wire.BlockHeader) ([]rpcserver.HeaderResult, error) {
+	encoded := make([]string, len(headers))
+	for i, header := range headers {
+		var buf bytes.Buffer
+		if err := header.Serialize(&buf); err != nil {
+			return nil, err
+		}
+		encoded[i] = base64.StdEncoding.EncodeToString(buf.Bytes())
+	}
+	return retry(ctx, c, func() ([]rpcserver.HeaderResult, error) { return c.api.InsertHeadersBase64(ctx, encoded) })
+}
+
+// ContainsBlock reports whether the light client stores the block.
+func (c *Client) ContainsBlock(ctx context.Context, hash chainhash.Hash) (bool, error) {
+	return retry(ctx, c, func() (bool, error) { return c.api.ContainsBTCBlock(ctx, &hash) })
+}
+
+// GetHeaderChainTip returns the latest finalized block.
+func (c *Client) GetHeaderChainTip(ctx context.Context) (rpcserver.Block, error) {
+	return retry(ctx, c, func() (rpcserver.Block, error) { return c.api.GetHeaderChainTip(ctx) })
+}
+
+// GetAncestor returns the ancestor at height of the block.
+func (c *Client) GetAncestor(ctx context.Context, hash chainhash.Hash, height int64) (rpcserver.Block, error) {
+	return retry(ctx, c, func() (rpcserver.Block, error) { return c.api.GetAncestor(ctx, &hash, height) })
+}
+
+// GetBlockLocator returns the block locator of the block.
+func (c *Client) GetBlockLocator(ctx context.Context, hash chainhash.Hash) ([]*chainhash.Hash, error) {
+	return retry(ctx, c, func() ([]*chainhash.Hash, error) { return c.api.GetBlockLocator(ctx, &hash) })
+}
+
+// GetAncestorProof returns the proof that the finalized block at height is an
+// ancestor of the latest finalized block.
+func (c *Client) GetAncestorProof(ctx context.Context, height int64) (rpcserver.AncestorProof, error) {
+	return retry(ctx, c, func() (rpcserver.AncestorProof, error) { return c.api.GetAncestorProof(ctx, height) })
+}
+
+// VerifySPV verifies the transaction inclusion proof.
+func (c *Client) VerifySPV(ctx context.Context, proof btclightclient.SPVProof) (btclightclient.SPVStatus, error) {
+	return retry(ctx, c, func() (btclightclient.SPVStatus, error) { return c.api.VerifySPV(ctx, &proof) })
+}
+
+// VerifySPVs verifies a batch of transaction inclusion proofs.
+func (c *Client) VerifySPVs(ctx context.Context, proofs []btclightclient.SPVProof) ([]btclightclient.SPVStatus, error) {
+	return retry(ctx, c, func() ([]btclightclient.SPVStatus, error) { return c.api.VerifySPVs(ctx, proofs) })
+}
+
+// GetStatus returns the light client status and readiness.
+func (c *Client) GetStatus(ctx context.Context) (rpcserver.Status, error) {
+	return retry(ctx, c, func() (rpcserver.Status, error) { return c.api.GetStatus(ctx) })
+}
+
+// GetDeploymentInfo returns the BIP9 deployment states over the finalized
+// chain.
+func (c *Client) GetDeploymentInfo(ctx context.Context) (rpcserver.DeploymentInfo, error) {
+	return retry(ctx, c, func() (rpcserver.DeploymentInfo, error) { return c.api.GetDeploymentInfo(ctx) })
+}
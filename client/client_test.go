@@ -0,0 +1,206 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
+	"github.com/gonative-cc/bitcoin-lightclient/chaingen"
+	"github.com/gonative-cc/bitcoin-lightclient/client"
+	"github.com/gonative-cc/bitcoin-lightclient/client/clienttest"
+	"github.com/gonative-cc/bitcoin-lightclient/rpcserver"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/filecoin-project/go-jsonrpc"
+	"gotest.tools/assert"
+)
+
+// newTestChain returns a light client with the chaingen.RegtestChain blocks
+// and the next 3 blocks.
+func newTestChain(t *testing.T) (*btclightclient.BTCLightClient, *chaingen.Generator, []*chaingen.Block, []*chaingen.Block) {
+	t.Helper()
+	g, blocks, next := chaingen.RegtestChain()
+	return btclightclient.NewBTCLightClientWithData(g.Params(), chaingen.Headers(blocks), 0), g, blocks, next
+}
+
+func TestClient(t *testing.T) {
+	lc, _, blocks, next := newTestChain(t)
+	c := clienttest.NewServer(t, lc).Client
+	ctx := context.Background()
+
+	pong, err := c.Ping(ctx, 7)
+	assert.NilError(t, err)
+	assert.Equal(t, pong, 7)
+
+	tip, err := c.GetHeaderChainTip(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, tip.Height, int64(13))
+	assert.Equal(t, *tip.Hash, blocks[13].Hash())
+
+	ok, err := c.ContainsBlock(ctx, blocks[20].Hash())
+	assert.NilError(t, err)
+	assert.Assert(t, ok)
+	ok, err = c.ContainsBlock(ctx, next[0].Hash())
+	assert.NilError(t, err)
+	assert.Assert(t, !ok)
+
+	ancestor, err := c.GetAncestor(ctx, blocks[20].Hash(), 3)
+	assert.NilError(t, err)
+	assert.Equal(t, *ancestor.Hash, blocks[3].Hash())
+	_, err = c.GetAncestor(ctx, blocks[20].Hash(), 21)
+	assert.ErrorContains(t, err, btclightclient.ErrHeightOutOfRange.Error())
+
+	locator, err := c.GetBlockLocator(ctx, blocks[20].Hash())
+	assert.NilError(t, err)
+	assert.Equal(t, *locator[0], blocks[20].Hash())
+	assert.Equal(t, *locator[len(locator)-1], blocks[0].Hash())
+
+	ancestorProof, err := c.GetAncestorProof(ctx, 3)
+	assert.NilError(t, err)
+	assert.Equal(t, ancestorProof.Hash, blocks[3].Hash())
+	assert.NilError(t, btclightclient.VerifyAncestorProof(ancestorProof.Root, ancestorProof.Hash, ancestorProof.Proof))
+	_, err = c.GetAncestorProof(ctx, 14)
+	assert.ErrorContains(t, err, btclightclient.ErrHeightOutOfRange.Error())
+
+	assert.NilError(t, c.InsertHeaders(ctx, chaingen.Headers(next)))
+	status, err := c.GetStatus(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, status.TipHash, next[2].Hash())
+	assert.Equal(t, status.FinalizedHeight, int32(16))
+	// the headers already stored are skipped
+	assert.NilError(t, c.InsertHeaders(ctx, chaingen.Headers(next)))
+
+	txID := blocks[5].TxIDs()[2]
+	proofHex, err := blocks[5].TxOutProof(txID)
+	assert.NilError(t, err)
+	proof, err := btclightclient.SPVProofFromHex(proofHex, txID.String())
+	assert.NilError(t, err)
+	spvStatus, err := c.VerifySPV(ctx, *proof)
+	assert.NilError(t, err)
+	assert.Equal(t, spvStatus, btclightclient.ValidSPVProof)
+	statuses, err := c.VerifySPVs(ctx, []btclightclient.SPVProof{*proof})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, statuses, []btclightclient.SPVStatus{btclightclient.ValidSPVProof})
+
+	info, err := c.GetDeploymentInfo(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, info.Height, int64(16))
+	assert.Equal(t, len(info.Deployments), int(chaincfg.DefinedDeployments))
+}
+
+func TestClientInsertHeadersEncoded(t *testing.T) {
+	lc, _, _, next := newTestChain(t)
+	c := clienttest.NewServer(t, lc).Client
+	ctx := context.Background()
+
+	results, err := c.InsertHeadersBase64(ctx, chaingen.Headers(next[:2]))
+	assert.NilError(t, err)
+	assert.Equal(t, len(results), 2)
+	assert.Equal(t, *results[1].Hash, next[1].Hash())
+	assert.Equal(t, results[1].Outcome, btclightclient.HeaderInserted)
+
+	encoded := []string{}
+	for _, b := range next {
+		header, err := btclightclient.BlockHeaderToHex(b.Header())
+		assert.NilError(t, err)
+		encoded = append(encoded, header)
+	}
+	results, err = c.InsertHeadersHex(ctx, encoded)
+	assert.NilError(t, err)
+	outcomes := []btclightclient.InsertOutcome{}
+	for _, r := range results {
+		outcomes = append(outcomes, r.Outcome)
+	}
+	assert.DeepEqual(t, outcomes, []btclightclient.InsertOutcome{
+		btclightclient.HeaderDuplicate, btclightclient.HeaderDuplicate, btclightclient.HeaderInserted,
+	})
+}
+
+// flakyTransport fails the first requests before reaching the server.
+type flakyTransport struct {
+	failures int
+	requests int
+}
+
+func (f *flakyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.requests++
+	if f.requests <= f.failures {
+		return nil, errors.New("connection refused")
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestClientRetry(t *testing.T) {
+	lc, _, blocks, _ := newTestChain(t)
+	transport := &flakyTransport{failures: 2}
+	c := clienttest.NewServer(t, lc,
+		client.WithRetry(3, time.Millisecond),
+		client.WithHTTPClient(&http.Client{Transport: transport})).Client
+	ctx := context.Background()
+
+	// the connection errors are retried
+	_, err := c.Ping(ctx, 1)
+	assert.NilError(t, err)
+	assert.Equal(t, transport.requests, 3)
+
+	transport.requests, transport.failures = 0, 5
+	_, err = c.Ping(ctx, 1)
+	var connErr *jsonrpc.RPCConnectionError
+	assert.Assert(t, errors.As(err, &connErr), err)
+	assert.Equal(t, transport.requests, 3)
+
+	// the server errors are not
+	transport.requests, transport.failures = 0, 0
+	_, err = c.GetAncestor(ctx, blocks[20].Hash(), 21)
+	assert.ErrorContains(t, err, btclightclient.ErrHeightOutOfRange.Error())
+	assert.Equal(t, transport.requests, 1)
+
+	// the insertions are, the headers already stored are skipped
+	transport.requests, transport.failures = 0, 1
+	assert.NilError(t, c.InsertHeaders(ctx, chaingen.Headers(blocks[20:])))
+	assert.Equal(t, transport.requests, 2)
+
+	// the retries stop with the context
+	transport.requests, transport.failures = 0, 5
+	c = clienttest.NewServer(t, lc,
+		client.WithRetry(5, time.Hour),
+		client.WithHTTPClient(&http.Client{Transport: transport})).Client
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = c.Ping(ctx, 1)
+	assert.Assert(t, errors.Is(err, context.DeadlineExceeded), err)
+	assert.Equal(t, transport.requests, 1)
+}
+
+func TestClientNetwork(t *testing.T) {
+	lcA, _, _, _ := newTestChain(t)
+	lcB, _, blocks, next := newTestChain(t)
+	server, err := rpcserver.NewMulti([]rpcserver.Network{
+		{Name: "a", LightClient: lcA},
+		{Name: "b", LightClient: lcB},
+	}, rpcserver.Config{})
+	assert.NilError(t, err)
+	ts := httptest.NewServer(server.HTTP.Handler)
+	defer ts.Close()
+	ctx := context.Background()
+
+	c, err := client.New(ctx, ts.URL, client.WithNetwork("b"))
+	assert.NilError(t, err)
+	defer c.Close()
+	assert.NilError(t, c.InsertHeaders(ctx, chaingen.Headers(next)))
+	status, err := c.GetStatus(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, status.TipHash, next[2].Hash())
+
+	// the default network is unchanged
+	c, err = client.New(ctx, ts.URL)
+	assert.NilError(t, err)
+	defer c.Close()
+	status, err = c.GetStatus(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, status.TipHash, blocks[20].Hash())
+}
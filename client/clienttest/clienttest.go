@@ -0,0 +1,57 @@
+// Package clienttest runs an in-process light client RPC server for tests.
+package clienttest
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
+	"github.com/gonative-cc/bitcoin-lightclient/client"
+	"github.com/gonative-cc/bitcoin-lightclient/rpcserver"
+)
+
+// Server is an RPC server listening on a random local port.
+type Server struct {
+	// URL is the base URL of the server, http://127.0.0.1:<port>.
+	URL    string
+	Client *client.Client
+	server *http.Server
+}
+
+// NewServer starts an RPC server serving btcLC and a client connected to
+// it. They are closed at the end of the test.
+func NewServer(t testing.TB, btcLC *btclightclient.BTCLightClient, opts ...client.Option) *Server {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	cfg := rpcserver.DefaultConfig()
+	cfg.Addr = ln.Addr().String()
+	s := &Server{
+		URL:    "http://" + cfg.Addr,
+		server: rpcserver.NewServer(btcLC, cfg),
+	}
+	go func() {
+		if err := s.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			t.Errorf("serve: %v", err)
+		}
+	}()
+	t.Cleanup(s.Close)
+
+	s.Client, err = client.New(context.Background(), s.URL, opts...)
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	t.Cleanup(s.Client.Close)
+	return s
+}
+
+// Close stops the server.
+func (s *Server) Close() {
+	_ = s.server.Close()
+}
@@ -0,0 +1,625 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/gonative-cc/bitcoin-lightclient/audit"
+	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
+	"github.com/gonative-cc/bitcoin-lightclient/fetcher"
+	"github.com/gonative-cc/bitcoin-lightclient/rpcserver"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/rs/zerolog/log"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{"serve", "start the JSON-RPC server", runServe},
+	{"init", "initialize the data dir from a header file", runInit},
+	{"import-headers", "insert headers from a header file into the stored state", runImportHeaders},
+	{"export-state", "write the stored best chain as a header file", runExportState},
+	{"verify-proof", "verify a gettxoutproof proof against the stored state", runVerifyProof},
+	{"status", "print the status of the stored state", runStatus},
+	{"fetch-headers", "fetch a range of headers from a configured source", runFetchHeaders},
+	{"store", "check the stored state indexes: store verify [-repair]", runStore},
+	{"replay-audit", "rebuild the state from the initial state and the audit log", runReplayAudit},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: bitcoin-lightclient <command> [flags]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", c.name, c.usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun 'bitcoin-lightclient <command> -h' for the command flags.\n")
+}
+
+// commonFlags registers the flags shared by all commands. Flags override
+// the values read from the config file.
+type commonFlags struct {
+	configFile      string
+	dataDir         string
+	network         string
+	signetChallenge string
+	finalityDepth   int
+	retention       string
+	keepFinalized   int
+}
+
+func newFlagSet(name string) (*flag.FlagSet, *commonFlags) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	cf := &commonFlags{}
+	fs.StringVar(&cf.configFile, "config", "", "config file (default <data-dir>/"+configFileName+")")
+	fs.StringVar(&cf.dataDir, "data-dir", DefaultConfig().DataDir, "light client data directory")
+	fs.StringVar(&cf.network, "network", "", "bitcoin network: mainnet, testnet3, testnet4, simnet, signet, regressionnet")
+	fs.StringVar(&cf.signetChallenge, "signet-challenge", "", "hex encoded challenge script of a custom signet")
+	fs.IntVar(&cf.finalityDepth, "finality-depth", 0, "number of blocks on top of a block to finalize it")
+	fs.StringVar(&cf.retention, "retention", "", "old finalized headers kept: all, last or anchors")
+	fs.IntVar(&cf.keepFinalized, "keep-finalized", 0, "number of finalized headers kept with -retention last")
+	return fs, cf
+}
+
+// config loads the config file and applies the flags set on the command line.
+func (cf *commonFlags) config(fs *flag.FlagSet) (Config, error) {
+	cfg := DefaultConfig()
+	cfgFile := cf.configFile
+	if cfgFile == "" {
+		cfgFile = filepath.Join(cf.dataDir, configFileName)
+		if _, err := os.Stat(cfgFile); err != nil {
+			cfgFile = ""
+		}
+	}
+	if cfgFile != "" {
+		var err error
+		if cfg, err = LoadConfig(cfgFile); err != nil {
+			return cfg, err
+		}
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "data-dir":
+			cfg.DataDir = cf.dataDir
+		case "network":
+			cfg.Network = cf.network
+		case "signet-challenge":
+			cfg.SignetChallenge = cf.signetChallenge
+		case "finality-depth":
+			cfg.FinalityDepth = int32(cf.finalityDepth)
+		case "retention":
+			cfg.Retention.Mode = cf.retention
+		case "keep-finalized":
+			cfg.Retention.KeepFinalized = int32(cf.keepFinalized)
+		}
+	})
+	return cfg, cfg.Validate()
+}
+
+func runServe(args []string) error {
+	fs, cf := newFlagSet("serve")
+	dataFile := fs.String("data-file", "", "header file used to bootstrap the light client when the data dir has no state")
+	anchorFile := fs.String("anchor", "", "trusted anchor file used to bootstrap the light client when the data dir has no state")
+	rpcAddr := fs.String("rpc-addr", "", "RPC server listen address")
+	maxTipAge := fs.Duration("max-tip-age", 0, "best tip age after which the light client is not ready")
+	grpcAddr := fs.String("grpc-addr", "", "gRPC server listen address, empty to disable it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg, err := cf.config(fs)
+	if err != nil {
+		return err
+	}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "rpc-addr":
+			cfg.RPC.Addr = *rpcAddr
+		case "max-tip-age":
+			cfg.RPC.MaxTipAge = *maxTipAge
+		case "grpc-addr":
+			cfg.RPC.GRPCAddr = *grpcAddr
+		}
+	})
+
+	btcLC, err := loadOrBootstrap(cfg, *dataFile, *anchorFile)
+	if err != nil {
+		return err
+	}
+	closeAudit, err := openAuditLog(cfg, btcLC)
+	if err != nil {
+		return err
+	}
+	defer closeAudit()
+
+	networks := []rpcserver.Network{{Name: cfg.Network, LightClient: btcLC}}
+	// the configs of the networks to save
+	netCfgs := []Config{cfg}
+	for _, n := range cfg.Networks {
+		ncfg := cfg.ForNetwork(n)
+		lc, err := loadOrBootstrap(ncfg, n.DataFile, n.AnchorFile)
+		if err != nil {
+			return fmt.Errorf("network %s: %w", n.NetworkName(), err)
+		}
+		closeAudit, err := openAuditLog(ncfg, lc)
+		if err != nil {
+			return fmt.Errorf("network %s: %w", n.NetworkName(), err)
+		}
+		defer closeAudit()
+		networks = append(networks, rpcserver.Network{Name: n.NetworkName(), LightClient: lc})
+		netCfgs = append(netCfgs, ncfg)
+	}
+
+	server, err := rpcserver.NewMulti(networks, cfg.RPCServerConfig())
+	if err != nil {
+		return err
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Info().Msgf("RPC server running at: %s", server.HTTP.Addr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	// the state roots of the last saved states, the unchanged states are
+	// not saved again
+	saved := make([]chainhash.Hash, len(networks))
+	save := func() error {
+		var errs []error
+		for i, n := range networks {
+			err := server.View(n.Name, func(lc *btclightclient.BTCLightClient) error {
+				root, err := lc.StateRoot()
+				if err != nil || root == saved[i] {
+					return err
+				}
+				if err := saveState(netCfgs[i], lc); err != nil {
+					return fmt.Errorf("network %s: save state: %w", n.Name, err)
+				}
+				saved[i] = root
+				return nil
+			})
+			errs = append(errs, err)
+		}
+		return errors.Join(errs...)
+	}
+	var tick <-chan time.Time
+	if cfg.SaveInterval > 0 {
+		ticker := time.NewTicker(cfg.SaveInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	var runErr error
+wait:
+	for {
+		select {
+		case runErr = <-serverErr:
+			break wait
+		case s := <-sig:
+			log.Info().Msgf("Received %s, shutting down", s)
+			break wait
+		case <-tick:
+			if err := save(); err != nil {
+				log.Err(err).Msg("Failed to save the light client state")
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Err(err).Msg("Failed to shut down RPC server")
+	}
+	// the state is saved even when the server failed
+	return errors.Join(runErr, save())
+}
+
+// loadOrBootstrap loads the light client state of cfg, or bootstraps it when
+// the data dir has no state.
+func loadOrBootstrap(cfg Config, dataFile, anchorFile string) (*btclightclient.BTCLightClient, error) {
+	if _, err := os.Stat(cfg.StateFile()); err == nil {
+		return loadState(cfg)
+	}
+	return bootstrap(cfg, dataFile, anchorFile)
+}
+
+// bootstrap creates a light client from either a header file or a trusted
+// anchor file.
+func bootstrap(cfg Config, dataFile, anchorFile string) (*btclightclient.BTCLightClient, error) {
+	switch {
+	case dataFile != "" && anchorFile != "":
+		return nil, errors.New("-data-file and -anchor are mutually exclusive")
+	case dataFile != "":
+		return loadLightClient(cfg, dataFile)
+	case anchorFile != "":
+		return loadAnchor(cfg, anchorFile)
+	default:
+		return nil, fmt.Errorf("no light client state in %s, -data-file or -anchor is required", cfg.DataDir)
+	}
+}
+
+// openAuditLog records the state transitions of btcLC in the audit log of
+// cfg, when enabled. The returned function closes the log.
+func openAuditLog(cfg Config, btcLC *btclightclient.BTCLightClient) (func(), error) {
+	path := cfg.AuditLogFile()
+	if path == "" {
+		return func() {}, nil
+	}
+	l, err := audit.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	btcLC.SetAuditLogger(l)
+	return func() {
+		btcLC.SetAuditLogger(nil)
+		if err := l.Close(); err != nil {
+			log.Err(err).Msgf("Failed to close audit log %s", path)
+		}
+	}, nil
+}
+
+func runInit(args []string) error {
+	fs, cf := newFlagSet("init")
+	dataFile := fs.String("data-file", "", "header file with the initial trusted headers")
+	anchorFile := fs.String("anchor", "", "trusted anchor file to start from, instead of a header file")
+	force := fs.Bool("force", false, "overwrite an existing state")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg, err := cf.config(fs)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(cfg.StateFile()); err == nil && !*force {
+		return fmt.Errorf("%s already exists, use -force to overwrite it", cfg.StateFile())
+	}
+
+	btcLC, err := bootstrap(cfg, *dataFile, *anchorFile)
+	if err != nil {
+		return err
+	}
+	// the log starts with the initial state
+	closeAudit, err := openAuditLog(cfg, btcLC)
+	if err != nil {
+		return err
+	}
+	closeAudit()
+	if err := saveState(cfg, btcLC); err != nil {
+		return err
+	}
+
+	cfgFile := filepath.Join(cfg.DataDir, configFileName)
+	if _, err := os.Stat(cfgFile); os.IsNotExist(err) {
+		if err := WriteConfig(cfgFile, cfg); err != nil {
+			return err
+		}
+	}
+	log.Info().Msgf("Initialized %s light client in %s", cfg.Network, cfg.DataDir)
+	return nil
+}
+
+func runImportHeaders(args []string) error {
+	fs, cf := newFlagSet("import-headers")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: bitcoin-lightclient import-headers [flags] <header_file>\n")
+		fmt.Fprintf(fs.Output(), "The header file is either in JSON or binary format.\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("missing header file")
+	}
+	cfg, err := cf.config(fs)
+	if err != nil {
+		return err
+	}
+
+	btcLC, err := loadState(cfg)
+	if err != nil {
+		return err
+	}
+	hf, err := openHeadersFile(cfg, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer hf.Close()
+	closeAudit, err := openAuditLog(cfg, btcLC)
+	if err != nil {
+		return err
+	}
+	defer closeAudit()
+
+	inserted, insertErr := insertHeaders(btcLC, "import:"+fs.Arg(0), hf.headers)
+	log.Info().Msgf("Inserted %d headers", inserted)
+	if err := saveState(cfg, btcLC); err != nil {
+		return err
+	}
+	return insertErr
+}
+
+func runExportState(args []string) error {
+	fs, cf := newFlagSet("export-state")
+	out := fs.String("out", "", "output file (default stdout)")
+	format := fs.String("format", formatJSON, "output format: json or binary")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg, err := cf.config(fs)
+	if err != nil {
+		return err
+	}
+
+	btcLC, err := loadState(cfg)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		return encodeState(os.Stdout, btcLC, *format)
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := encodeState(f, btcLC, *format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func runVerifyProof(args []string) error {
+	fs, cf := newFlagSet("verify-proof")
+	proof := fs.String("proof", "", "hex encoded proof returned by bitcoind gettxoutproof (required)")
+	txID := fs.String("txid", "", "transaction ID (required)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *proof == "" || *txID == "" {
+		return errors.New("-proof and -txid are required")
+	}
+	cfg, err := cf.config(fs)
+	if err != nil {
+		return err
+	}
+
+	btcLC, err := loadState(cfg)
+	if err != nil {
+		return err
+	}
+	spvProof, err := btclightclient.SPVProofFromHex(*proof, *txID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(btcLC.VerifySPV(*spvProof))
+	return nil
+}
+
+func runStatus(args []string) error {
+	fs, cf := newFlagSet("status")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg, err := cf.config(fs)
+	if err != nil {
+		return err
+	}
+
+	btcLC, err := loadState(cfg)
+	if err != nil {
+		return err
+	}
+	status, err := btcLC.ChainStatus()
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(status)
+}
+
+func runStore(args []string) error {
+	if len(args) == 0 || args[0] != "verify" {
+		return errors.New("usage: store verify [flags]")
+	}
+	fs, cf := newFlagSet("store verify")
+	repair := fs.Bool("repair", false, "rebuild the inconsistent indexes and save the state")
+	jsonOut := fs.Bool("json", false, "print the inconsistencies as JSON")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	cfg, err := cf.config(fs)
+	if err != nil {
+		return err
+	}
+
+	btcLC, err := loadState(cfg)
+	if err != nil {
+		return err
+	}
+	issues := btcLC.CheckConsistency(*repair)
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(issues); err != nil {
+			return err
+		}
+	} else {
+		for _, issue := range issues {
+			fmt.Println(issue)
+		}
+	}
+
+	unrepaired := 0
+	for _, issue := range issues {
+		if !issue.Repaired {
+			unrepaired++
+		}
+	}
+	if unrepaired > 0 {
+		return fmt.Errorf("%d inconsistencies in the stored state", unrepaired)
+	}
+	if *repair && len(issues) > 0 {
+		return saveState(cfg, btcLC)
+	}
+	return nil
+}
+
+func runFetchHeaders(args []string) error {
+	fs, cf := newFlagSet("fetch-headers")
+	from := fs.Int64("from", 0, "first block height")
+	to := fs.Int64("to", 0, "last block height")
+	sourceIdx := fs.Int("source", 0, "index of the source in the config sources list")
+	out := fs.String("out", "", "output header file (default stdout)")
+	format := fs.String("format", formatJSON, "output format: json or binary")
+	doImport := fs.Bool("import", false, "insert the fetched headers into the stored state instead of writing them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg, err := cf.config(fs)
+	if err != nil {
+		return err
+	}
+	params, err := cfg.Params()
+	if err != nil {
+		return err
+	}
+	if *sourceIdx < 0 || *sourceIdx >= len(cfg.Sources) {
+		return fmt.Errorf("source %d not configured, %d sources available", *sourceIdx, len(cfg.Sources))
+	}
+
+	src, err := fetcher.NewSource(cfg.Sources[*sourceIdx])
+	if err != nil {
+		return err
+	}
+	headers, err := fetcher.FetchHeaders(context.Background(), src, *from, *to)
+	if err != nil {
+		return err
+	}
+
+	if *doImport {
+		btcLC, err := loadState(cfg)
+		if err != nil {
+			return err
+		}
+		closeAudit, err := openAuditLog(cfg, btcLC)
+		if err != nil {
+			return err
+		}
+		defer closeAudit()
+		source := "fetch:" + cfg.Sources[*sourceIdx].URL
+		inserted, insertErr := insertHeaders(btcLC, source, btclightclient.NewHeaderSliceIterator(headers))
+		log.Info().Msgf("Inserted %d of %d headers", inserted, len(headers))
+		if err := saveState(cfg, btcLC); err != nil {
+			return err
+		}
+		return insertErr
+	}
+
+	if *out == "" {
+		return writeHeaders(os.Stdout, params, *from, big.NewInt(0), headers, *format)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := writeHeaders(f, params, *from, big.NewInt(0), headers, *format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func runReplayAudit(args []string) error {
+	fs, cf := newFlagSet("replay-audit")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: bitcoin-lightclient replay-audit [flags] [audit_log]\n")
+		fmt.Fprintf(fs.Output(), "The audit log is the configured one by default.\n")
+		fs.PrintDefaults()
+	}
+	dataFile := fs.String("data-file", "", "header file with the initial state of the audit log, e.g. a copy of the state file")
+	anchorFile := fs.String("anchor", "", "trusted anchor file with the initial state of the audit log")
+	forksFile := fs.String("forks", "", "fork header file of the initial state, e.g. a copy of the forks file saved with the state")
+	out := fs.String("out", "", "output state file, in binary format (default no output)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg, err := cf.config(fs)
+	if err != nil {
+		return err
+	}
+	path := cfg.AuditLogFile()
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+	if path == "" {
+		return errors.New("no audit log configured")
+	}
+
+	btcLC, err := bootstrap(cfg, *dataFile, *anchorFile)
+	if err != nil {
+		return err
+	}
+	if *forksFile != "" {
+		if _, err := os.Stat(*forksFile); err != nil {
+			return err
+		}
+		if err := loadForks(*forksFile, btcLC); err != nil {
+			return fmt.Errorf("load forks: %w", err)
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	btcLC, stats, err := audit.Replay(btcLC, f, func(lc *btclightclient.BTCLightClient) (*btclightclient.BTCLightClient, error) {
+		return reloadState(cfg, lc)
+	})
+	log.Info().Msgf("Replayed %d events, %d of %d headers inserted, %d restarts",
+		stats.Events, stats.Inserted, stats.Inserts, stats.Reopens)
+	if err != nil {
+		return err
+	}
+
+	status, err := btcLC.ChainStatus()
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(status); err != nil {
+		return err
+	}
+	if *out == "" {
+		return nil
+	}
+	w, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := encodeState(w, btcLC, formatBinary); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
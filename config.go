@@ -0,0 +1,320 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
+	"github.com/gonative-cc/bitcoin-lightclient/data"
+	"github.com/gonative-cc/bitcoin-lightclient/fetcher"
+	"github.com/gonative-cc/bitcoin-lightclient/rpcserver"
+
+	"github.com/BurntSushi/toml"
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+const (
+	configFileName = "config.toml"
+	stateFileName  = "headers.bin"
+	// accumulatorFileName is the header accumulator saved with the
+	// state, it keeps the pruned blocks provable.
+	accumulatorFileName = "accumulator.bin"
+	// forksFileName holds the headers of the forks other than the best
+	// chain, restored on top of the state.
+	forksFileName = "forks.bin"
+)
+
+// Config is the light client configuration, read from a TOML file.
+type Config struct {
+	Network string `toml:"network"`
+	// SignetChallenge is the hex encoded challenge script of a custom
+	// signet, network must be signet.
+	SignetChallenge string `toml:"signet_challenge,omitempty"`
+	// DataDir stores the config file and the light client state.
+	DataDir       string                 `toml:"data_dir"`
+	FinalityDepth int32                  `toml:"finality_depth"`
+	Retention     RetentionConfig        `toml:"retention"`
+	Limits        LimitsConfig           `toml:"limits"`
+	RPC           RPCConfig              `toml:"rpc"`
+	Sources       []fetcher.SourceConfig `toml:"sources"`
+	// AuditLog is the JSON lines file recording the state transitions,
+	// relative to DataDir. Empty to disable it.
+	AuditLog string `toml:"audit_log,omitempty"`
+	// SaveInterval is how often serve saves the states which changed, 0 to
+	// only save them on shutdown.
+	SaveInterval time.Duration `toml:"save_interval"`
+	// Networks are the light clients served by serve next to the one of
+	// the config, which is the default network named after Network.
+	Networks []NetworkConfig `toml:"networks,omitempty"`
+}
+
+// NetworkConfig is an additional light client served by serve, with its
+// own state. The unset settings are those of the main config.
+type NetworkConfig struct {
+	// Name selects the network in the RPC requests, Network by default.
+	Name            string `toml:"name,omitempty"`
+	Network         string `toml:"network"`
+	SignetChallenge string `toml:"signet_challenge,omitempty"`
+	DataDir         string `toml:"data_dir"`
+	FinalityDepth   int32  `toml:"finality_depth,omitempty"`
+	// Retention is the retention policy of the main config when unset.
+	Retention *RetentionConfig `toml:"retention,omitempty"`
+	// DataFile and AnchorFile bootstrap the light client when the data
+	// dir has no state, see the -data-file and -anchor flags.
+	DataFile   string `toml:"data_file,omitempty"`
+	AnchorFile string `toml:"anchor_file,omitempty"`
+	// AuditLog is the audit log of the main config when unset, in the
+	// network data dir when relative.
+	AuditLog string `toml:"audit_log,omitempty"`
+}
+
+// NetworkName returns the name of the network in the RPC requests.
+func (n NetworkConfig) NetworkName() string {
+	if n.Name != "" {
+		return n.Name
+	}
+	return n.Network
+}
+
+// RetentionConfig selects the old finalized headers kept by the light
+// client, see btclightclient.RetentionPolicy.
+type RetentionConfig struct {
+	// Mode is all, last or anchors.
+	Mode string `toml:"mode"`
+	// KeepFinalized is the number of finalized headers kept in last mode.
+	KeepFinalized int32 `toml:"keep_finalized,omitempty"`
+}
+
+// LimitsConfig bounds the forks accepted by the light client, see
+// btclightclient.InsertLimits.
+type LimitsConfig struct {
+	// MaxForks is the maximum number of fork heads, 0 for no limit.
+	MaxForks            int  `toml:"max_forks"`
+	RejectHopelessForks bool `toml:"reject_hopeless_forks"`
+}
+
+type RPCConfig struct {
+	Addr      string        `toml:"addr"`
+	MaxTipAge time.Duration `toml:"max_tip_age"`
+	GRPCAddr  string        `toml:"grpc_addr"`
+	// MaxHeadersPerRequest is the maximum number of headers of an
+	// insertion request, 0 for no limit.
+	MaxHeadersPerRequest int `toml:"max_headers_per_request"`
+	// RateLimit is the number of requests per second of each client IP
+	// address, 0 to disable the rate limiting.
+	RateLimit float64 `toml:"rate_limit"`
+	RateBurst int     `toml:"rate_burst"`
+}
+
+func DefaultConfig() Config {
+	rpcCfg := rpcserver.DefaultConfig()
+	dataDir := ".bitcoin-lightclient"
+	if home, err := os.UserHomeDir(); err == nil {
+		dataDir = filepath.Join(home, dataDir)
+	}
+
+	return Config{
+		Network:       "mainnet",
+		DataDir:       dataDir,
+		FinalityDepth: btclightclient.MaxForkAge,
+		Retention:     RetentionConfig{Mode: btclightclient.RetainAll.String()},
+		Limits:        LimitsConfig{MaxForks: 16},
+		SaveInterval:  time.Minute,
+		RPC: RPCConfig{
+			Addr:                 rpcCfg.Addr,
+			MaxTipAge:            rpcCfg.MaxTipAge,
+			GRPCAddr:             rpcCfg.GRPCAddr,
+			MaxHeadersPerRequest: rpcCfg.MaxHeadersPerRequest,
+			RateLimit:            rpcCfg.RateLimit,
+			RateBurst:            rpcCfg.RateBurst,
+		},
+	}
+}
+
+// LoadConfig reads the TOML file at path on top of the default config.
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+	if _, err := toml.DecodeFile(path, &cfg); err != nil {
+		return cfg, fmt.Errorf("read config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// WriteConfig writes cfg to path in TOML format.
+func WriteConfig(path string, cfg Config) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := toml.NewEncoder(f).Encode(cfg); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (cfg Config) Validate() error {
+	if _, err := cfg.Params(); err != nil {
+		return err
+	}
+	if cfg.FinalityDepth <= 0 {
+		return fmt.Errorf("finality depth must be positive, got %d", cfg.FinalityDepth)
+	}
+	if _, err := cfg.RetentionPolicy(); err != nil {
+		return err
+	}
+	if err := cfg.InsertLimits().Validate(); err != nil {
+		return err
+	}
+	if cfg.SaveInterval < 0 {
+		return fmt.Errorf("save interval must not be negative, got %s", cfg.SaveInterval)
+	}
+	if cfg.RPC.MaxHeadersPerRequest < 0 {
+		return fmt.Errorf("max headers per request must not be negative, got %d", cfg.RPC.MaxHeadersPerRequest)
+	}
+	if cfg.RPC.RateLimit > 0 && cfg.RPC.RateBurst <= 0 {
+		return fmt.Errorf("rate burst must be positive with a rate limit, got %d", cfg.RPC.RateBurst)
+	}
+	for _, src := range cfg.Sources {
+		if _, err := fetcher.NewSource(src); err != nil {
+			return err
+		}
+	}
+	return cfg.validateNetworks()
+}
+
+// validateNetworks checks the additional networks, their names and data
+// dirs must be distinct from the other networks.
+func (cfg Config) validateNetworks() error {
+	if len(cfg.Networks) == 0 {
+		return nil
+	}
+	names := map[string]bool{cfg.Network: true}
+	dataDirs := map[string]bool{filepath.Clean(cfg.DataDir): true}
+	auditLogs := map[string]bool{}
+	if auditLog := cfg.AuditLogFile(); auditLog != "" {
+		auditLogs[filepath.Clean(auditLog)] = true
+	}
+	if err := rpcserver.ValidateNetworkName(cfg.Network); err != nil {
+		return err
+	}
+	for _, n := range cfg.Networks {
+		name := n.NetworkName()
+		if err := rpcserver.ValidateNetworkName(name); err != nil {
+			return err
+		}
+		if names[name] {
+			return fmt.Errorf("duplicated network name %q", name)
+		}
+		names[name] = true
+
+		if n.DataDir == "" {
+			return fmt.Errorf("network %s: data dir is required", name)
+		}
+		dataDir := filepath.Clean(n.DataDir)
+		if dataDirs[dataDir] {
+			return fmt.Errorf("network %s: data dir %s used by another network", name, n.DataDir)
+		}
+		dataDirs[dataDir] = true
+
+		ncfg := cfg.ForNetwork(n)
+		if auditLog := ncfg.AuditLogFile(); auditLog != "" {
+			if auditLogs[filepath.Clean(auditLog)] {
+				return fmt.Errorf("network %s: audit log %s used by another network", name, auditLog)
+			}
+			auditLogs[filepath.Clean(auditLog)] = true
+		}
+		if err := ncfg.Validate(); err != nil {
+			return fmt.Errorf("network %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ForNetwork returns the config of the light client of n.
+func (cfg Config) ForNetwork(n NetworkConfig) Config {
+	ncfg := cfg
+	ncfg.Network = n.Network
+	ncfg.SignetChallenge = n.SignetChallenge
+	ncfg.DataDir = n.DataDir
+	if n.FinalityDepth != 0 {
+		ncfg.FinalityDepth = n.FinalityDepth
+	}
+	if n.Retention != nil {
+		ncfg.Retention = *n.Retention
+	}
+	if n.AuditLog != "" {
+		ncfg.AuditLog = n.AuditLog
+	}
+	// the sources are those of the main network
+	ncfg.Sources = nil
+	ncfg.Networks = nil
+	return ncfg
+}
+
+// Params returns the params of the configured network.
+func (cfg Config) Params() (*chaincfg.Params, error) {
+	return data.NetworkParams(cfg.Network, cfg.SignetChallenge)
+}
+
+// RetentionPolicy returns the configured retention policy.
+func (cfg Config) RetentionPolicy() (btclightclient.RetentionPolicy, error) {
+	mode, err := btclightclient.ParseRetentionMode(cfg.Retention.Mode)
+	if err != nil {
+		return btclightclient.RetentionPolicy{}, err
+	}
+	policy := btclightclient.RetentionPolicy{Mode: mode, KeepFinalized: cfg.Retention.KeepFinalized}
+	return policy, policy.Validate()
+}
+
+// InsertLimits returns the configured insertion limits.
+func (cfg Config) InsertLimits() btclightclient.InsertLimits {
+	return btclightclient.InsertLimits{
+		MaxForks:            cfg.Limits.MaxForks,
+		RejectHopelessForks: cfg.Limits.RejectHopelessForks,
+	}
+}
+
+// LightClientOptions returns the light client options of the config.
+func (cfg Config) LightClientOptions() []btclightclient.Option {
+	// the retention policy is checked by Validate
+	policy, _ := cfg.RetentionPolicy()
+	return []btclightclient.Option{
+		btclightclient.WithFinalityDepth(cfg.FinalityDepth),
+		btclightclient.WithRetention(policy),
+		btclightclient.WithInsertLimits(cfg.InsertLimits()),
+	}
+}
+
+func (cfg Config) RPCServerConfig() rpcserver.Config {
+	return rpcserver.Config{
+		Addr:                 cfg.RPC.Addr,
+		MaxTipAge:            cfg.RPC.MaxTipAge,
+		GRPCAddr:             cfg.RPC.GRPCAddr,
+		MaxHeadersPerRequest: cfg.RPC.MaxHeadersPerRequest,
+		RateLimit:            cfg.RPC.RateLimit,
+		RateBurst:            cfg.RPC.RateBurst,
+	}
+}
+
+func (cfg Config) StateFile() string {
+	return filepath.Join(cfg.DataDir, stateFileName)
+}
+
+func (cfg Config) AccumulatorFile() string {
+	return filepath.Join(cfg.DataDir, accumulatorFileName)
+}
+
+func (cfg Config) ForksFile() string {
+	return filepath.Join(cfg.DataDir, forksFileName)
+}
+
+// AuditLogFile returns the path of the audit log, empty when disabled.
+func (cfg Config) AuditLogFile() string {
+	if cfg.AuditLog == "" || filepath.IsAbs(cfg.AuditLog) {
+		return cfg.AuditLog
+	}
+	return filepath.Join(cfg.DataDir, cfg.AuditLog)
+}
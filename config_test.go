@@ -0,0 +1,174 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
+	"github.com/gonative-cc/bitcoin-lightclient/data"
+	"github.com/gonative-cc/bitcoin-lightclient/fetcher"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"gotest.tools/assert"
+)
+
+func TestLoadConfig(t *testing.T) {
+	cfg, err := LoadConfig("config.example.toml")
+	assert.NilError(t, err)
+	assert.NilError(t, cfg.Validate())
+	assert.Equal(t, cfg.Network, "mainnet")
+	assert.Equal(t, cfg.FinalityDepth, int32(8))
+	assert.Equal(t, cfg.RPC.MaxTipAge, 2*time.Hour)
+	assert.Equal(t, cfg.SaveInterval, time.Minute)
+	assert.Equal(t, len(cfg.Sources), 2)
+	assert.Equal(t, cfg.Sources[1].Type, fetcher.SourceBitcoind)
+	assert.Equal(t, cfg.InsertLimits(), btclightclient.InsertLimits{MaxForks: 16})
+	assert.Equal(t, cfg.RPCServerConfig().MaxHeadersPerRequest, 2000)
+	assert.Equal(t, cfg.RPCServerConfig().RateLimit, 20.0)
+
+	// unset values keep their defaults
+	path := filepath.Join(t.TempDir(), configFileName)
+	assert.NilError(t, os.WriteFile(path, []byte("network = \"testnet3\"\n"), 0o644))
+	cfg, err = LoadConfig(path)
+	assert.NilError(t, err)
+	assert.Equal(t, cfg.Network, "testnet3")
+	assert.Equal(t, cfg.RPC.Addr, DefaultConfig().RPC.Addr)
+
+	cfg.Network = "litecoin"
+	assert.ErrorContains(t, cfg.Validate(), "network litecoin not found")
+	cfg.Network = "mainnet"
+	cfg.FinalityDepth = 0
+	assert.ErrorContains(t, cfg.Validate(), "finality depth")
+	cfg.FinalityDepth = 8
+	cfg.SaveInterval = -time.Second
+	assert.ErrorContains(t, cfg.Validate(), "save interval")
+	cfg.SaveInterval = 0
+	cfg.Limits.MaxForks = -1
+	assert.ErrorContains(t, cfg.Validate(), "max forks")
+	cfg.Limits.MaxForks = 0
+	cfg.RPC.RateBurst = 0
+	assert.ErrorContains(t, cfg.Validate(), "rate burst")
+	cfg.RPC.RateLimit = 0
+	assert.NilError(t, cfg.Validate())
+}
+
+func TestRetentionConfig(t *testing.T) {
+	fs, cf := newFlagSet("test")
+	assert.NilError(t, fs.Parse([]string{"-data-dir", t.TempDir(), "-retention", "last", "-keep-finalized", "100"}))
+	cfg, err := cf.config(fs)
+	assert.NilError(t, err)
+	policy, err := cfg.RetentionPolicy()
+	assert.NilError(t, err)
+	assert.Equal(t, policy, btclightclient.RetentionPolicy{Mode: btclightclient.RetainLastFinalized, KeepFinalized: 100})
+
+	cfg.Retention.KeepFinalized = 0
+	assert.ErrorContains(t, cfg.Validate(), "number of finalized blocks to keep must be positive")
+	cfg.Retention = RetentionConfig{Mode: "some"}
+	assert.ErrorContains(t, cfg.Validate(), "unknown retention mode")
+
+	policy, err = DefaultConfig().RetentionPolicy()
+	assert.NilError(t, err)
+	assert.Equal(t, policy.Mode, btclightclient.RetainAll)
+}
+
+func TestSignetChallenge(t *testing.T) {
+	fs, cf := newFlagSet("test")
+	assert.NilError(t, fs.Parse([]string{"-data-dir", t.TempDir(), "-network", "signet", "-signet-challenge", "51"}))
+	cfg, err := cf.config(fs)
+	assert.NilError(t, err)
+	params, err := cfg.Params()
+	assert.NilError(t, err)
+	assert.Equal(t, data.SignetChallenge(params), "51")
+	assert.Assert(t, params.Net != chaincfg.SigNetParams.Net)
+
+	cfg.Network = "mainnet"
+	assert.ErrorContains(t, cfg.Validate(), "signet challenge set for network mainnet")
+}
+
+func TestNetworksConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), configFileName)
+	assert.NilError(t, os.WriteFile(path, []byte(`
+network = "mainnet"
+data_dir = "/tmp/mainnet"
+finality_depth = 8
+
+[[networks]]
+  network = "testnet4"
+  data_dir = "/tmp/testnet4"
+
+[[networks]]
+  name = "mutinynet"
+  network = "signet"
+  signet_challenge = "51"
+  data_dir = "/tmp/mutinynet"
+  finality_depth = 3
+  [networks.retention]
+    mode = "anchors"
+`), 0o644))
+	cfg, err := LoadConfig(path)
+	assert.NilError(t, err)
+	assert.NilError(t, cfg.Validate())
+	assert.Equal(t, len(cfg.Networks), 2)
+	assert.Equal(t, cfg.Networks[0].NetworkName(), "testnet4")
+	assert.Equal(t, cfg.Networks[1].NetworkName(), "mutinynet")
+
+	// the unset settings are those of the main config
+	ncfg := cfg.ForNetwork(cfg.Networks[0])
+	assert.Equal(t, ncfg.StateFile(), filepath.Join("/tmp/testnet4", stateFileName))
+	assert.Equal(t, ncfg.FinalityDepth, int32(8))
+	assert.Equal(t, ncfg.Retention.Mode, "all")
+	ncfg = cfg.ForNetwork(cfg.Networks[1])
+	assert.Equal(t, ncfg.FinalityDepth, int32(3))
+	assert.Equal(t, ncfg.Retention.Mode, "anchors")
+	assert.Equal(t, ncfg.RPC, cfg.RPC)
+
+	invalid := cfg
+	invalid.Networks = []NetworkConfig{{Network: "mainnet", DataDir: "/tmp/other"}}
+	assert.ErrorContains(t, invalid.Validate(), `duplicated network name "mainnet"`)
+	invalid.Networks = []NetworkConfig{{Network: "testnet4", DataDir: "/tmp/mainnet/"}}
+	assert.ErrorContains(t, invalid.Validate(), "used by another network")
+	invalid.Networks = []NetworkConfig{{Name: "metrics", Network: "testnet4", DataDir: "/tmp/other"}}
+	assert.ErrorContains(t, invalid.Validate(), "reserved path")
+	invalid.Networks = []NetworkConfig{{Network: "testnet4"}}
+	assert.ErrorContains(t, invalid.Validate(), "data dir is required")
+	invalid.Networks = []NetworkConfig{{Network: "litecoin", DataDir: "/tmp/other"}}
+	assert.ErrorContains(t, invalid.Validate(), "network litecoin: network litecoin not found")
+
+	// a relative audit log is in the data dir of each network
+	audited := cfg
+	audited.AuditLog = "audit.jsonl"
+	assert.NilError(t, audited.Validate())
+	assert.Equal(t, audited.AuditLogFile(), filepath.Join("/tmp/mainnet", "audit.jsonl"))
+	assert.Equal(t, audited.ForNetwork(cfg.Networks[0]).AuditLogFile(), filepath.Join("/tmp/testnet4", "audit.jsonl"))
+	audited.AuditLog = "/var/log/audit.jsonl"
+	assert.ErrorContains(t, audited.Validate(), "audit log /var/log/audit.jsonl used by another network")
+	audited.Networks = []NetworkConfig{{Network: "testnet4", DataDir: "/tmp/testnet4", AuditLog: "/var/log/testnet4.jsonl"}}
+	assert.NilError(t, audited.Validate())
+}
+
+func TestConfigFlags(t *testing.T) {
+	dataDir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.Network = "signet"
+	cfg.FinalityDepth = 6
+	assert.NilError(t, WriteConfig(filepath.Join(dataDir, configFileName), cfg))
+
+	// config file is read from the data dir
+	fs, cf := newFlagSet("test")
+	assert.NilError(t, fs.Parse([]string{"-data-dir", dataDir}))
+	cfg, err := cf.config(fs)
+	assert.NilError(t, err)
+	assert.Equal(t, cfg.Network, "signet")
+	assert.Equal(t, cfg.FinalityDepth, int32(6))
+	assert.Equal(t, cfg.DataDir, dataDir)
+
+	// flags override the config file
+	fs, cf = newFlagSet("test")
+	assert.NilError(t, fs.Parse([]string{"-data-dir", dataDir, "-network", "regressionnet", "-finality-depth", "3"}))
+	cfg, err = cf.config(fs)
+	assert.NilError(t, err)
+	assert.Equal(t, cfg.Network, "regressionnet")
+	assert.Equal(t, cfg.FinalityDepth, int32(3))
+}
@@ -0,0 +1,87 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Binary header accumulator file format. Integers are little endian.
+//
+//	magic          [4]byte "BTCA"
+//	version        uint8
+//	network magic  uint32 (wire.BitcoinNet)
+//	start height   uint32
+//	hashes         32 bytes each, the accumulated block hashes ordered by
+//	               height until the end of the stream.
+const (
+	AccumulatorFileVersion  = 1
+	accumulatorFilePreamble = 13
+)
+
+var accumulatorFileMagic = []byte("BTCA")
+
+var ErrInvalidAccumulatorFile = errors.New("invalid header accumulator file")
+
+// WriteAccumulatorFile encodes the header accumulator of the params network.
+func WriteAccumulatorFile(w io.Writer, params *chaincfg.Params, acc *btclightclient.HeaderAccumulator) error {
+	if acc.StartHeight() < 0 {
+		return fmt.Errorf("start height %d out of range", acc.StartHeight())
+	}
+
+	bw := bufio.NewWriter(w)
+	preamble := make([]byte, 0, accumulatorFilePreamble)
+	preamble = append(preamble, accumulatorFileMagic...)
+	preamble = append(preamble, AccumulatorFileVersion)
+	preamble = binary.LittleEndian.AppendUint32(preamble, uint32(params.Net))
+	preamble = binary.LittleEndian.AppendUint32(preamble, uint32(acc.StartHeight()))
+	if _, err := bw.Write(preamble); err != nil {
+		return err
+	}
+	for _, hash := range acc.Leaves() {
+		if _, err := bw.Write(hash[:]); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadAccumulatorFile decodes a header accumulator of the params network.
+func ReadAccumulatorFile(r io.Reader, params *chaincfg.Params) (*btclightclient.HeaderAccumulator, error) {
+	br := bufio.NewReader(r)
+	var preamble [accumulatorFilePreamble]byte
+	if _, err := io.ReadFull(br, preamble[:]); err != nil {
+		return nil, fmt.Errorf("%w: read preamble: %w", ErrInvalidAccumulatorFile, err)
+	}
+	if !bytes.Equal(preamble[:4], accumulatorFileMagic) {
+		return nil, fmt.Errorf("%w: bad magic %x", ErrInvalidAccumulatorFile, preamble[:4])
+	}
+	if version := preamble[4]; version != AccumulatorFileVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidAccumulatorFile, version)
+	}
+	if net := wire.BitcoinNet(binary.LittleEndian.Uint32(preamble[5:9])); net != params.Net {
+		return nil, fmt.Errorf("%w: network %s, expected %s", ErrInvalidAccumulatorFile, net, params.Net)
+	}
+
+	acc := btclightclient.NewHeaderAccumulator(int32(binary.LittleEndian.Uint32(preamble[9:13])), nil)
+	for {
+		var hash chainhash.Hash
+		n, err := io.ReadFull(br, hash[:])
+		if err == io.EOF {
+			return acc, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: truncated hash (%d bytes): %w", ErrInvalidAccumulatorFile, n, err)
+		}
+		acc.Append(hash)
+	}
+}
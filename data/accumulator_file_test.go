@@ -0,0 +1,42 @@
+package data
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"gotest.tools/assert"
+)
+
+func TestAccumulatorFile(t *testing.T) {
+	acc := btclightclient.NewHeaderAccumulator(840000, []chainhash.Hash{
+		*chaincfg.MainNetParams.GenesisHash,
+		*chaincfg.TestNet3Params.GenesisHash,
+		*chaincfg.RegressionNetParams.GenesisHash,
+	})
+	var buf bytes.Buffer
+	assert.NilError(t, WriteAccumulatorFile(&buf, &chaincfg.MainNetParams, acc))
+	assert.Equal(t, buf.Len(), accumulatorFilePreamble+3*chainhash.HashSize)
+	valid := buf.Bytes()
+
+	got, err := ReadAccumulatorFile(bytes.NewReader(valid), &chaincfg.MainNetParams)
+	assert.NilError(t, err)
+	assert.Equal(t, got.StartHeight(), acc.StartHeight())
+	assert.DeepEqual(t, got.Leaves(), acc.Leaves())
+	assert.Equal(t, got.Root(), acc.Root())
+
+	_, err = ReadAccumulatorFile(bytes.NewReader(valid), &chaincfg.RegressionNetParams)
+	assert.Assert(t, errors.Is(err, ErrInvalidAccumulatorFile), err)
+	_, err = ReadAccumulatorFile(bytes.NewReader(valid[:len(valid)-1]), &chaincfg.MainNetParams)
+	assert.Assert(t, errors.Is(err, ErrInvalidAccumulatorFile), err)
+	_, err = ReadAccumulatorFile(bytes.NewReader(valid[:5]), &chaincfg.MainNetParams)
+	assert.Assert(t, errors.Is(err, ErrInvalidAccumulatorFile), err)
+	headersFile := bytes.Clone(valid)
+	copy(headersFile, headersFileMagic)
+	_, err = ReadAccumulatorFile(bytes.NewReader(headersFile), &chaincfg.MainNetParams)
+	assert.Assert(t, errors.Is(err, ErrInvalidAccumulatorFile), err)
+}
@@ -0,0 +1,40 @@
+package data
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+// Anchor is a trusted block the light client starts from. Fields use the
+// format of bitcoind getblockheader.
+type Anchor struct {
+	Network string `json:"network"`
+	// SignetChallenge is the hex encoded challenge script of a custom
+	// signet.
+	SignetChallenge string `json:"signet_challenge,omitempty"`
+	Height          int64  `json:"height"`
+	Hash            string `json:"hash"`
+	// Header is the hex encoded block header.
+	Header string `json:"header"`
+	// ChainWork is the hex encoded cumulative chain work up to the block.
+	ChainWork string `json:"chainwork"`
+	// Ancestors are the hex encoded headers before the anchor, ordered by height.
+	Ancestors []string `json:"ancestors"`
+}
+
+func ReadAnchorJSON(jsonFilePath string) (*chaincfg.Params, Anchor, error) {
+	var anchor Anchor
+	byteValue, err := os.ReadFile(jsonFilePath)
+	if err != nil {
+		return nil, anchor, err
+	}
+
+	if err := json.Unmarshal(byteValue, &anchor); err != nil {
+		return nil, anchor, err
+	}
+
+	networkParams, err := NetworkParams(anchor.Network, anchor.SignetChallenge)
+	return networkParams, anchor, err
+}
@@ -2,23 +2,28 @@ package data
 
 import (
 	"encoding/json"
-	"fmt"
 	"io"
 	"os"
 
+	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
+
 	"github.com/btcsuite/btcd/chaincfg"
 )
 
 type Sample struct {
-	Network      string   `json:"network"`
-	StartHeight  int64    `json:"start_height"`
-	BlockHeaders []string `json:"blockheaders"`
+	Network string `json:"network"`
+	// SignetChallenge is the hex encoded challenge script of a custom
+	// signet.
+	SignetChallenge string   `json:"signet_challenge,omitempty"`
+	StartHeight     int64    `json:"start_height"`
+	BlockHeaders    []string `json:"blockheaders"`
 }
 
 // map network name string to chaincfg param object
 var NetworkMap = map[string]*chaincfg.Params{
 	"mainnet":       &chaincfg.MainNetParams,
 	"testnet3":      &chaincfg.TestNet3Params,
+	"testnet4":      &btclightclient.TestNet4Params,
 	"simnet":        &chaincfg.SimNetParams,
 	"signet":        &chaincfg.SigNetParams,
 	"regressionnet": &chaincfg.RegressionNetParams,
@@ -31,7 +36,12 @@ func ReadJSON(jsonFilePath string) (*chaincfg.Params, int64, []string, error) {
 	}
 	defer jsonFile.Close()
 
-	byteValue, err := io.ReadAll(jsonFile)
+	return DecodeJSON(jsonFile)
+}
+
+// DecodeJSON reads the JSON sample format from r.
+func DecodeJSON(r io.Reader) (*chaincfg.Params, int64, []string, error) {
+	byteValue, err := io.ReadAll(r)
 	if err != nil {
 		return nil, 0, nil, err
 	}
@@ -41,9 +51,8 @@ func ReadJSON(jsonFilePath string) (*chaincfg.Params, int64, []string, error) {
 		return nil, 0, nil, err
 	}
 
-	networkParams, ok := NetworkMap[dataContent.Network]
-	if !ok {
-		err := fmt.Errorf("network %s not found", dataContent.Network)
+	networkParams, err := NetworkParams(dataContent.Network, dataContent.SignetChallenge)
+	if err != nil {
 		return nil, 0, nil, err
 	}
 
@@ -0,0 +1,58 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+// NetworkName returns the NetworkMap key of the network params.
+func NetworkName(params *chaincfg.Params) (string, error) {
+	if SignetChallenge(params) != "" {
+		return "signet", nil
+	}
+	for name, p := range NetworkMap {
+		if p.Net == params.Net && p.Name == params.Name {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("network %s not found", params.Name)
+}
+
+// WriteJSON writes headers to a file in the format read by ReadJSON.
+func WriteJSON(jsonFilePath string, params *chaincfg.Params, startHeight int64, blockHeaders []string) error {
+	jsonFile, err := os.Create(jsonFilePath)
+	if err != nil {
+		return err
+	}
+
+	if err := EncodeJSON(jsonFile, params, startHeight, blockHeaders); err != nil {
+		jsonFile.Close()
+		return err
+	}
+	return jsonFile.Close()
+}
+
+// EncodeJSON writes headers to w in the format read by ReadJSON.
+func EncodeJSON(w io.Writer, params *chaincfg.Params, startHeight int64, blockHeaders []string) error {
+	network, err := NetworkName(params)
+	if err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(Sample{
+		Network:         network,
+		SignetChallenge: SignetChallenge(params),
+		StartHeight:     startHeight,
+		BlockHeaders:    blockHeaders,
+	}, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(content, '\n'))
+	return err
+}
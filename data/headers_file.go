@@ -0,0 +1,170 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Binary headers file format. Integers are little endian unless noted.
+//
+//	magic          [4]byte "BTCH"
+//	version        uint8
+//	network magic  uint32 (wire.BitcoinNet)
+//	start height   uint32
+//	chain work     [32]byte big endian, chain work of the parent of the
+//	               first header (version 2 only)
+//	headers        80 bytes each, serialized as in the Bitcoin P2P protocol,
+//	               ordered by height until the end of the stream.
+const (
+	HeadersFileVersion      = 2
+	headersFilePreambleSzV1 = 13
+	headersFilePreambleSz   = headersFilePreambleSzV1 + chainWorkSize
+	chainWorkSize           = 32
+	headerSize              = 80
+)
+
+var headersFileMagic = []byte("BTCH")
+
+var ErrInvalidHeadersFile = errors.New("invalid headers file")
+
+// IsHeadersFile reports whether the stream starts with the binary headers
+// file magic. It doesn't consume any byte from r.
+func IsHeadersFile(r *bufio.Reader) bool {
+	b, err := r.Peek(len(headersFileMagic))
+	return err == nil && bytes.Equal(b, headersFileMagic)
+}
+
+// NetworkByMagic returns the network params with the given network magic.
+// Custom signets are found once their params are created with
+// CustomSignetParams.
+func NetworkByMagic(net wire.BitcoinNet) (*chaincfg.Params, error) {
+	for _, p := range NetworkMap {
+		if p.Net == net {
+			return p, nil
+		}
+	}
+	if p, ok := customSignetByMagic(net); ok {
+		return p, nil
+	}
+	return nil, fmt.Errorf("network with magic %s not found", net)
+}
+
+// HeadersFileReader decodes a binary headers file one header at a time.
+type HeadersFileReader struct {
+	r           *bufio.Reader
+	params      *chaincfg.Params
+	startHeight int64
+	chainWork   *big.Int
+	buf         [headerSize]byte
+}
+
+// NewHeadersFileReader reads and validates the file preamble.
+func NewHeadersFileReader(r io.Reader) (*HeadersFileReader, error) {
+	br := bufio.NewReader(r)
+	var preamble [headersFilePreambleSz]byte
+	if _, err := io.ReadFull(br, preamble[:headersFilePreambleSzV1]); err != nil {
+		return nil, fmt.Errorf("%w: read preamble: %w", ErrInvalidHeadersFile, err)
+	}
+	if !bytes.Equal(preamble[:4], headersFileMagic) {
+		return nil, fmt.Errorf("%w: bad magic %x", ErrInvalidHeadersFile, preamble[:4])
+	}
+
+	chainWork := big.NewInt(0)
+	switch version := preamble[4]; version {
+	case 1:
+	case 2:
+		if _, err := io.ReadFull(br, preamble[headersFilePreambleSzV1:]); err != nil {
+			return nil, fmt.Errorf("%w: read preamble: %w", ErrInvalidHeadersFile, err)
+		}
+		chainWork.SetBytes(preamble[headersFilePreambleSzV1:])
+	default:
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidHeadersFile, version)
+	}
+
+	params, err := NetworkByMagic(wire.BitcoinNet(binary.LittleEndian.Uint32(preamble[5:9])))
+	if err != nil {
+		return nil, err
+	}
+	return &HeadersFileReader{
+		r:           br,
+		params:      params,
+		startHeight: int64(binary.LittleEndian.Uint32(preamble[9:13])),
+		chainWork:   chainWork,
+	}, nil
+}
+
+func (h *HeadersFileReader) Params() *chaincfg.Params {
+	return h.params
+}
+
+// StartHeight is the height of the first header in the file.
+func (h *HeadersFileReader) StartHeight() int64 {
+	return h.startHeight
+}
+
+// ChainWork is the chain work of the parent of the first header in the file.
+// It is zero for version 1 files.
+func (h *HeadersFileReader) ChainWork() *big.Int {
+	return h.chainWork
+}
+
+// Next returns the next header. It returns io.EOF at the end of the stream
+// and an error if the stream ends in the middle of a header.
+func (h *HeadersFileReader) Next() (wire.BlockHeader, error) {
+	var header wire.BlockHeader
+	n, err := io.ReadFull(h.r, h.buf[:])
+	if err == io.EOF {
+		return header, io.EOF
+	}
+	if err != nil {
+		return header, fmt.Errorf("%w: truncated header (%d bytes): %w", ErrInvalidHeadersFile, n, err)
+	}
+
+	err = header.Deserialize(bytes.NewReader(h.buf[:]))
+	return header, err
+}
+
+// HeadersFileWriter encodes a binary headers file. Flush must be called after
+// the last header.
+type HeadersFileWriter struct {
+	w *bufio.Writer
+}
+
+// NewHeadersFileWriter writes the file preamble. chainWork is the chain work
+// of the parent of the first header.
+func NewHeadersFileWriter(w io.Writer, params *chaincfg.Params, startHeight int64, chainWork *big.Int) (*HeadersFileWriter, error) {
+	if startHeight < 0 || startHeight > int64(^uint32(0)) {
+		return nil, fmt.Errorf("start height %d out of range", startHeight)
+	}
+	if chainWork.Sign() < 0 || chainWork.BitLen() > chainWorkSize*8 {
+		return nil, fmt.Errorf("chain work %s out of range", chainWork)
+	}
+
+	bw := bufio.NewWriter(w)
+	preamble := make([]byte, 0, headersFilePreambleSz)
+	preamble = append(preamble, headersFileMagic...)
+	preamble = append(preamble, HeadersFileVersion)
+	preamble = binary.LittleEndian.AppendUint32(preamble, uint32(params.Net))
+	preamble = binary.LittleEndian.AppendUint32(preamble, uint32(startHeight))
+	preamble = append(preamble, chainWork.FillBytes(make([]byte, chainWorkSize))...)
+	if _, err := bw.Write(preamble); err != nil {
+		return nil, err
+	}
+	return &HeadersFileWriter{w: bw}, nil
+}
+
+func (h *HeadersFileWriter) Write(header wire.BlockHeader) error {
+	return header.Serialize(h.w)
+}
+
+func (h *HeadersFileWriter) Flush() error {
+	return h.w.Flush()
+}
@@ -0,0 +1,102 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"gotest.tools/assert"
+)
+
+func TestHeadersFileRoundTrip(t *testing.T) {
+	headers := []wire.BlockHeader{
+		chaincfg.MainNetParams.GenesisBlock.Header,
+		{Version: 2, PrevBlock: *chaincfg.MainNetParams.GenesisHash, Bits: 0x1d00ffff, Nonce: 7},
+	}
+
+	var buf bytes.Buffer
+	chainWork, _ := new(big.Int).SetString("7f4f4c2f5fb8a1e9f9df2e1d0000000000000000000000000000", 16)
+	w, err := NewHeadersFileWriter(&buf, &chaincfg.MainNetParams, 840000, chainWork)
+	assert.NilError(t, err)
+	for _, h := range headers {
+		assert.NilError(t, w.Write(h))
+	}
+	assert.NilError(t, w.Flush())
+	assert.Equal(t, buf.Len(), headersFilePreambleSz+len(headers)*headerSize)
+	assert.Assert(t, IsHeadersFile(bufio.NewReader(bytes.NewReader(buf.Bytes()))))
+
+	r, err := NewHeadersFileReader(&buf)
+	assert.NilError(t, err)
+	assert.Equal(t, r.Params(), &chaincfg.MainNetParams)
+	assert.Equal(t, r.StartHeight(), int64(840000))
+	assert.Equal(t, r.ChainWork().Cmp(chainWork), 0)
+	for _, want := range headers {
+		got, err := r.Next()
+		assert.NilError(t, err)
+		assert.Equal(t, got.BlockHash(), want.BlockHash())
+	}
+	_, err = r.Next()
+	assert.Equal(t, err, io.EOF)
+}
+
+func TestHeadersFileInvalid(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewHeadersFileWriter(&buf, &chaincfg.RegressionNetParams, 0, big.NewInt(0))
+	assert.NilError(t, err)
+	assert.NilError(t, w.Write(chaincfg.RegressionNetParams.GenesisBlock.Header))
+	assert.NilError(t, w.Flush())
+	valid := buf.Bytes()
+
+	// truncated header
+	r, err := NewHeadersFileReader(bytes.NewReader(valid[:len(valid)-1]))
+	assert.NilError(t, err)
+	_, err = r.Next()
+	assert.Assert(t, errors.Is(err, ErrInvalidHeadersFile))
+
+	// truncated preamble
+	_, err = NewHeadersFileReader(bytes.NewReader(valid[:5]))
+	assert.Assert(t, errors.Is(err, ErrInvalidHeadersFile))
+
+	// JSON file
+	assert.Assert(t, !IsHeadersFile(bufio.NewReader(bytes.NewReader([]byte(`{"network": "mainnet"}`)))))
+	_, err = NewHeadersFileReader(bytes.NewReader([]byte(`{"network": "mainnet"}`)))
+	assert.Assert(t, errors.Is(err, ErrInvalidHeadersFile))
+
+	unknownVersion := bytes.Clone(valid)
+	unknownVersion[4] = HeadersFileVersion + 1
+	_, err = NewHeadersFileReader(bytes.NewReader(unknownVersion))
+	assert.ErrorContains(t, err, "unsupported version")
+
+	unknownNetwork := bytes.Clone(valid)
+	unknownNetwork[5] = 0
+	_, err = NewHeadersFileReader(bytes.NewReader(unknownNetwork))
+	assert.ErrorContains(t, err, "not found")
+
+	_, err = NewHeadersFileWriter(&buf, &chaincfg.MainNetParams, -1, big.NewInt(0))
+	assert.ErrorContains(t, err, "out of range")
+	_, err = NewHeadersFileWriter(&buf, &chaincfg.MainNetParams, 0, new(big.Int).Lsh(big.NewInt(1), 256))
+	assert.ErrorContains(t, err, "out of range")
+}
+
+func TestHeadersFileVersion1(t *testing.T) {
+	genesis := chaincfg.RegressionNetParams.GenesisBlock.Header
+	file := []byte("BTCH\x01")
+	file = binary.LittleEndian.AppendUint32(file, uint32(chaincfg.RegressionNetParams.Net))
+	file = binary.LittleEndian.AppendUint32(file, 0)
+	var buf bytes.Buffer
+	assert.NilError(t, genesis.Serialize(&buf))
+	file = append(file, buf.Bytes()...)
+
+	r, err := NewHeadersFileReader(bytes.NewReader(file))
+	assert.NilError(t, err)
+	assert.Equal(t, r.ChainWork().Sign(), 0)
+	h, err := r.Next()
+	assert.NilError(t, err)
+	assert.Equal(t, h.BlockHash(), genesis.BlockHash())
+}
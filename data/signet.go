@@ -0,0 +1,90 @@
+package data
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// customSignet is a signet with its own challenge script.
+type customSignet struct {
+	params    *chaincfg.Params
+	challenge []byte
+}
+
+var (
+	customSignetsMu sync.Mutex
+	// custom signets by network magic. Params are created once per
+	// challenge, so they can be compared by pointer as the NetworkMap
+	// params.
+	customSignets = map[wire.BitcoinNet]customSignet{}
+)
+
+// CustomSignetParams returns the params of the signet with the hex encoded
+// challenge script. The network magic is derived from the challenge, the
+// genesis block is the global signet one. Signet block signatures are in the
+// coinbase transaction, so only the header proof of work is validated.
+func CustomSignetParams(challengeHex string) (*chaincfg.Params, error) {
+	challenge, err := hex.DecodeString(challengeHex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signet challenge: %w", err)
+	}
+	if len(challenge) == 0 {
+		return nil, fmt.Errorf("invalid signet challenge: empty script")
+	}
+
+	params := chaincfg.CustomSignetParams(challenge, nil)
+	if params.Net == chaincfg.SigNetParams.Net {
+		return &chaincfg.SigNetParams, nil
+	}
+
+	customSignetsMu.Lock()
+	defer customSignetsMu.Unlock()
+	if s, ok := customSignets[params.Net]; ok {
+		if !bytes.Equal(s.challenge, challenge) {
+			return nil, fmt.Errorf("signet challenge %x has the same network magic as %x", challenge, s.challenge)
+		}
+		return s.params, nil
+	}
+	customSignets[params.Net] = customSignet{params: &params, challenge: challenge}
+	return &params, nil
+}
+
+// SignetChallenge returns the hex encoded challenge of a custom signet
+// created with CustomSignetParams, or an empty string for other networks.
+func SignetChallenge(params *chaincfg.Params) string {
+	customSignetsMu.Lock()
+	defer customSignetsMu.Unlock()
+	if s, ok := customSignets[params.Net]; ok && s.params == params {
+		return hex.EncodeToString(s.challenge)
+	}
+	return ""
+}
+
+// NetworkParams returns the params of the named network. The signet
+// challenge selects a custom signet, it must be empty for other networks.
+func NetworkParams(network, signetChallenge string) (*chaincfg.Params, error) {
+	if signetChallenge != "" {
+		if network != "signet" {
+			return nil, fmt.Errorf("signet challenge set for network %s", network)
+		}
+		return CustomSignetParams(signetChallenge)
+	}
+
+	networkParams, ok := NetworkMap[network]
+	if !ok {
+		return nil, fmt.Errorf("network %s not found", network)
+	}
+	return networkParams, nil
+}
+
+func customSignetByMagic(net wire.BitcoinNet) (*chaincfg.Params, bool) {
+	customSignetsMu.Lock()
+	defer customSignetsMu.Unlock()
+	s, ok := customSignets[net]
+	return s.params, ok
+}
@@ -0,0 +1,64 @@
+package data
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"gotest.tools/assert"
+)
+
+// challenge of the global signet, a 1 of 2 multisig
+const globalSignetChallenge = "512103ad5e0edad18cb1f0fc0d28a3d4f1f3e445640337489abb10404f2d1e086be430210359ef5021964fe22d6f8e05b2463c9540ce96883fe3b278760f048f5189f2e6c452ae"
+
+func TestCustomSignetParams(t *testing.T) {
+	params, err := CustomSignetParams(globalSignetChallenge)
+	assert.NilError(t, err)
+	assert.Equal(t, params, &chaincfg.SigNetParams)
+	assert.Equal(t, SignetChallenge(params), "")
+
+	// OP_TRUE signet
+	params, err = CustomSignetParams("51")
+	assert.NilError(t, err)
+	assert.Equal(t, params.Net, wire.BitcoinNet(0xbd6fd254))
+	assert.Equal(t, params.Name, "signet")
+	assert.Equal(t, *params.GenesisHash, *chaincfg.SigNetParams.GenesisHash)
+	assert.Equal(t, SignetChallenge(params), "51")
+
+	// params are created once per challenge
+	again, err := NetworkParams("signet", "51")
+	assert.NilError(t, err)
+	assert.Equal(t, again, params)
+	byMagic, err := NetworkByMagic(params.Net)
+	assert.NilError(t, err)
+	assert.Equal(t, byMagic, params)
+
+	_, err = CustomSignetParams("5z")
+	assert.ErrorContains(t, err, "invalid signet challenge")
+	_, err = CustomSignetParams("")
+	assert.ErrorContains(t, err, "empty script")
+	_, err = NetworkParams("mainnet", "51")
+	assert.ErrorContains(t, err, "signet challenge set for network mainnet")
+}
+
+func TestCustomSignetFiles(t *testing.T) {
+	params, err := CustomSignetParams("51")
+	assert.NilError(t, err)
+
+	var buf bytes.Buffer
+	assert.NilError(t, EncodeJSON(&buf, params, 0, []string{}))
+	assert.Assert(t, bytes.Contains(buf.Bytes(), []byte(`"signet_challenge": "51"`)))
+	decoded, _, _, err := DecodeJSON(&buf)
+	assert.NilError(t, err)
+	assert.Equal(t, decoded, params)
+
+	buf.Reset()
+	w, err := NewHeadersFileWriter(&buf, params, 0, big.NewInt(0))
+	assert.NilError(t, err)
+	assert.NilError(t, w.Flush())
+	r, err := NewHeadersFileReader(&buf)
+	assert.NilError(t, err)
+	assert.Equal(t, r.Params(), params)
+}
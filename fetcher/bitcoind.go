@@ -0,0 +1,89 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Bitcoind fetches headers from a Bitcoin Core JSON-RPC endpoint.
+type Bitcoind struct {
+	client   *http.Client
+	url      string
+	user     string
+	password string
+}
+
+func NewBitcoind(client *http.Client, url, user, password string) *Bitcoind {
+	return &Bitcoind{
+		client:   client,
+		url:      url,
+		user:     user,
+		password: password,
+	}
+}
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      int    `json:"id"`
+	Method  string `json:"method"`
+	Params  []any  `json:"params"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (b *Bitcoind) BlockHeader(ctx context.Context, height int64) (wire.BlockHeader, error) {
+	var hash string
+	if err := b.call(ctx, "getblockhash", []any{height}, &hash); err != nil {
+		return wire.BlockHeader{}, err
+	}
+
+	var headerHex string
+	if err := b.call(ctx, "getblockheader", []any{hash, false}, &headerHex); err != nil {
+		return wire.BlockHeader{}, err
+	}
+	return btclightclient.BlockHeaderFromHex(headerHex)
+}
+
+func (b *Bitcoind) call(ctx context.Context, method string, params []any, result any) error {
+	body, err := json.Marshal(rpcRequest{JSONRPC: "1.0", ID: 1, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if b.user != "" {
+		req.SetBasicAuth(b.user, b.password)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var rpcResp rpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("bitcoind %s: %s: %w", method, resp.Status, err)
+	}
+	if rpcResp.Error != nil {
+		return fmt.Errorf("bitcoind %s: %s (code %d)", method, rpcResp.Error.Message, rpcResp.Error.Code)
+	}
+	return json.Unmarshal(rpcResp.Result, result)
+}
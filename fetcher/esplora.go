@@ -0,0 +1,61 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Esplora fetches headers from an esplora REST API (e.g. blockstream.info/api).
+type Esplora struct {
+	client *http.Client
+	url    string
+}
+
+func NewEsplora(client *http.Client, url string) *Esplora {
+	return &Esplora{
+		client: client,
+		url:    strings.TrimSuffix(url, "/"),
+	}
+}
+
+func (e *Esplora) BlockHeader(ctx context.Context, height int64) (wire.BlockHeader, error) {
+	hash, err := e.get(ctx, fmt.Sprintf("/block-height/%d", height))
+	if err != nil {
+		return wire.BlockHeader{}, err
+	}
+
+	headerHex, err := e.get(ctx, fmt.Sprintf("/block/%s/header", hash))
+	if err != nil {
+		return wire.BlockHeader{}, err
+	}
+	return btclightclient.BlockHeaderFromHex(headerHex)
+}
+
+func (e *Esplora) get(ctx context.Context, path string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.url+path, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("esplora %s: %s: %s", path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return strings.TrimSpace(string(body)), nil
+}
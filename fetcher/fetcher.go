@@ -0,0 +1,68 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Source is a remote service serving Bitcoin block headers.
+type Source interface {
+	BlockHeader(ctx context.Context, height int64) (wire.BlockHeader, error)
+}
+
+// Source types supported in the config.
+const (
+	SourceEsplora  = "esplora"
+	SourceBitcoind = "bitcoind"
+)
+
+// SourceConfig describes a header source.
+type SourceConfig struct {
+	// Type is either "esplora" or "bitcoind".
+	Type string `toml:"type"`
+	// URL of the esplora REST API or the bitcoind JSON-RPC endpoint.
+	URL string `toml:"url"`
+	// User and Password are the bitcoind RPC credentials.
+	User     string `toml:"user"`
+	Password string `toml:"password"`
+}
+
+const requestTimeout = 30 * time.Second
+
+// NewSource creates the header source described by cfg.
+func NewSource(cfg SourceConfig) (Source, error) {
+	client := &http.Client{Timeout: requestTimeout}
+	switch cfg.Type {
+	case SourceEsplora:
+		return NewEsplora(client, cfg.URL), nil
+	case SourceBitcoind:
+		return NewBitcoind(client, cfg.URL, cfg.User, cfg.Password), nil
+	default:
+		return nil, fmt.Errorf("unknown source type %q", cfg.Type)
+	}
+}
+
+// FetchHeaders fetches headers in range [from, to] and checks that each header
+// builds on the previous one.
+func FetchHeaders(ctx context.Context, src Source, from, to int64) ([]wire.BlockHeader, error) {
+	if from > to {
+		return nil, fmt.Errorf("invalid range [%d, %d]", from, to)
+	}
+
+	headers := make([]wire.BlockHeader, 0, to-from+1)
+	for h := from; h <= to; h++ {
+		header, err := src.BlockHeader(ctx, h)
+		if err != nil {
+			return nil, fmt.Errorf("fetch header at height %d: %w", h, err)
+		}
+		if len(headers) > 0 && header.PrevBlock != headers[len(headers)-1].BlockHash() {
+			return nil, fmt.Errorf("header at height %d doesn't connect to the previous header", h)
+		}
+		headers = append(headers, header)
+	}
+	return headers, nil
+}
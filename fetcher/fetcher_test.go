@@ -0,0 +1,116 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
+
+	"gotest.tools/assert"
+)
+
+var testHeaders = []string{
+	"0100000000000000000000000000000000000000000000000000000000000000000000003ba3edfd7a7b12b27ac72c3e67768f617fc81bc3888a51323a9fb8aa4b1e5e4adae5494dffff7f2002000000",
+	"0000002006226e46111a0b59caaf126043eb5bbf28c34f3a5e332a1fc7b2b73cf188910f2fe76e709f3031b5ed684f098b5cd35a09633943d141a6d0525f34a1643dcf44e0b23c67ffff7f2002000000",
+	"000000205dcd36bceabfc1816ab17503f753e8de66be7ceddee2b8b806b85e61cf9fdc68ce1efdd1cb457e408bc2984151f5ba8e7efcf8a64c2ca9f23de07a3e718a7e9de1b23c67ffff7f2001000000",
+}
+
+// testChain returns header hex indexed by block hash and block hashes by height.
+func testChain(t *testing.T) (map[string]string, []string) {
+	byHash := map[string]string{}
+	hashes := []string{}
+	for _, s := range testHeaders {
+		h, err := btclightclient.BlockHeaderFromHex(s)
+		assert.NilError(t, err)
+		byHash[h.BlockHash().String()] = s
+		hashes = append(hashes, h.BlockHash().String())
+	}
+	return byHash, hashes
+}
+
+func TestEsplora(t *testing.T) {
+	byHash, hashes := testChain(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var height int
+		var hash string
+		if _, err := fmt.Sscanf(r.URL.Path, "/block-height/%d", &height); err == nil && height < len(hashes) {
+			fmt.Fprint(w, hashes[height])
+			return
+		}
+		if _, err := fmt.Sscanf(r.URL.Path, "/block/%64s", &hash); err == nil && strings.HasSuffix(r.URL.Path, "/header") {
+			fmt.Fprint(w, byHash[hash])
+			return
+		}
+		http.Error(w, "Block not found", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	src, err := NewSource(SourceConfig{Type: SourceEsplora, URL: server.URL + "/"})
+	assert.NilError(t, err)
+
+	headers, err := FetchHeaders(context.Background(), src, 0, 2)
+	assert.NilError(t, err)
+	assert.Equal(t, len(headers), 3)
+	assert.Equal(t, headers[2].BlockHash().String(), hashes[2])
+
+	_, err = FetchHeaders(context.Background(), src, 2, 3)
+	assert.ErrorContains(t, err, "Block not found")
+}
+
+func TestBitcoind(t *testing.T) {
+	byHash, hashes := testChain(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "user" || password != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		assert.NilError(t, json.NewDecoder(r.Body).Decode(&req))
+		switch req.Method {
+		case "getblockhash":
+			var height int
+			assert.NilError(t, json.Unmarshal(req.Params[0], &height))
+			if height >= len(hashes) {
+				fmt.Fprint(w, `{"result":null,"error":{"code":-8,"message":"Block height out of range"}}`)
+				return
+			}
+			fmt.Fprintf(w, `{"result":%q,"error":null}`, hashes[height])
+		case "getblockheader":
+			var hash string
+			assert.NilError(t, json.Unmarshal(req.Params[0], &hash))
+			fmt.Fprintf(w, `{"result":%q,"error":null}`, byHash[hash])
+		}
+	}))
+	defer server.Close()
+
+	src, err := NewSource(SourceConfig{Type: SourceBitcoind, URL: server.URL, User: "user", Password: "pass"})
+	assert.NilError(t, err)
+
+	headers, err := FetchHeaders(context.Background(), src, 1, 2)
+	assert.NilError(t, err)
+	assert.Equal(t, len(headers), 2)
+	assert.Equal(t, headers[0].BlockHash().String(), hashes[1])
+
+	_, err = FetchHeaders(context.Background(), src, 2, 3)
+	assert.ErrorContains(t, err, "Block height out of range")
+
+	src, err = NewSource(SourceConfig{Type: SourceBitcoind, URL: server.URL})
+	assert.NilError(t, err)
+	_, err = src.BlockHeader(context.Background(), 0)
+	assert.ErrorContains(t, err, "401")
+}
+
+func TestUnknownSource(t *testing.T) {
+	_, err := NewSource(SourceConfig{Type: "p2p"})
+	assert.ErrorContains(t, err, "unknown source type")
+}
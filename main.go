@@ -1,49 +1,38 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"os"
 
-	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
-	"github.com/gonative-cc/bitcoin-lightclient/rpcserver"
-
-	"github.com/gonative-cc/bitcoin-lightclient/data"
-
-	"github.com/btcsuite/btcd/wire"
 	"github.com/rs/zerolog/log"
 )
 
 func main() {
-	// read the json file
-	// example: ./data/sample.json
+	// example: bitcoin-lightclient serve -network regressionnet -data-file ./data/regtest.json
 	if len(os.Args) < 2 {
-		log.Error().Msg("Missing filename.\nUsage: bitcoin-lightclient <sample_file.json>")
-		return
-	}
-	sampleFilename := os.Args[1]
-
-	if _, err := os.Stat(sampleFilename); os.IsNotExist(err) {
-		log.Error().Msgf("Sample file does not exist: %s", sampleFilename)
-		return
+		usage()
+		os.Exit(2)
 	}
 
-	networkParams, startHeight, blockHeaders, err := data.ReadJSON(sampleFilename)
-	if err != nil {
-		log.Error().Msgf("Error reading data file: %s", err)
+	name := os.Args[1]
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		if err := c.run(os.Args[2:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return
+			}
+			log.Error().Msgf("%s: %s", name, err)
+			os.Exit(1)
+		}
 		return
 	}
-	headers := make([]wire.BlockHeader, len(blockHeaders))
-
-	for id, headerStr := range blockHeaders {
-		h, _ := btclightclient.BlockHeaderFromHex(headerStr)
-		headers[id] = h
-	}
 
-	btcLC := btclightclient.NewBTCLightClientWithData(networkParams, headers, int(startHeight))
-	btcLC.Status()
-
-	err = rpcserver.StartRPCServer(btcLC)
-	if err != nil {
-		log.Error().Msgf("Error creating RPC server: %s", err)
-		return
+	if name != "help" && name != "-h" && name != "--help" {
+		log.Error().Msgf("Unknown command: %s", name)
 	}
+	usage()
+	os.Exit(2)
 }
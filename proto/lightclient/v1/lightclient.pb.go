@@ -0,0 +1,909 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        (unknown)
+// source: lightclient/v1/lightclient.proto
+
+package lightclientv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type SPVStatus int32
+
+const (
+	SPVStatus_SPV_STATUS_INVALID SPVStatus = 0
+	// SPV_STATUS_PARTIAL_VALID is a valid proof of a block not finalized yet.
+	SPVStatus_SPV_STATUS_PARTIAL_VALID SPVStatus = 1
+	SPVStatus_SPV_STATUS_VALID         SPVStatus = 2
+)
+
+// Enum value maps for SPVStatus.
+var (
+	SPVStatus_name = map[int32]string{
+		0: "SPV_STATUS_INVALID",
+		1: "SPV_STATUS_PARTIAL_VALID",
+		2: "SPV_STATUS_VALID",
+	}
+	SPVStatus_value = map[string]int32{
+		"SPV_STATUS_INVALID":       0,
+		"SPV_STATUS_PARTIAL_VALID": 1,
+		"SPV_STATUS_VALID":         2,
+	}
+)
+
+func (x SPVStatus) Enum() *SPVStatus {
+	p := new(SPVStatus)
+	*p = x
+	return p
+}
+
+func (x SPVStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (SPVStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_lightclient_v1_lightclient_proto_enumTypes[0].Descriptor()
+}
+
+func (SPVStatus) Type() protoreflect.EnumType {
+	return &file_lightclient_v1_lightclient_proto_enumTypes[0]
+}
+
+func (x SPVStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use SPVStatus.Descriptor instead.
+func (SPVStatus) EnumDescriptor() ([]byte, []int) {
+	return file_lightclient_v1_lightclient_proto_rawDescGZIP(), []int{0}
+}
+
+type Block struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Hash   string `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	Height int64  `protobuf:"varint,2,opt,name=height,proto3" json:"height,omitempty"`
+}
+
+func (x *Block) Reset() {
+	*x = Block{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lightclient_v1_lightclient_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Block) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Block) ProtoMessage() {}
+
+func (x *Block) ProtoReflect() protoreflect.Message {
+	mi := &file_lightclient_v1_lightclient_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Block.ProtoReflect.Descriptor instead.
+func (*Block) Descriptor() ([]byte, []int) {
+	return file_lightclient_v1_lightclient_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Block) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *Block) GetHeight() int64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+type Tip struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// best is the head of the most difficult fork.
+	Best *Block `protobuf:"bytes,1,opt,name=best,proto3" json:"best,omitempty"`
+	// finalized is the latest checkpoint.
+	Finalized *Block `protobuf:"bytes,2,opt,name=finalized,proto3" json:"finalized,omitempty"`
+}
+
+func (x *Tip) Reset() {
+	*x = Tip{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lightclient_v1_lightclient_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Tip) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Tip) ProtoMessage() {}
+
+func (x *Tip) ProtoReflect() protoreflect.Message {
+	mi := &file_lightclient_v1_lightclient_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Tip.ProtoReflect.Descriptor instead.
+func (*Tip) Descriptor() ([]byte, []int) {
+	return file_lightclient_v1_lightclient_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Tip) GetBest() *Block {
+	if x != nil {
+		return x.Best
+	}
+	return nil
+}
+
+func (x *Tip) GetFinalized() *Block {
+	if x != nil {
+		return x.Finalized
+	}
+	return nil
+}
+
+type InsertHeadersRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// headers are 80 bytes serialized block headers.
+	Headers [][]byte `protobuf:"bytes,1,rep,name=headers,proto3" json:"headers,omitempty"`
+}
+
+func (x *InsertHeadersRequest) Reset() {
+	*x = InsertHeadersRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lightclient_v1_lightclient_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *InsertHeadersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InsertHeadersRequest) ProtoMessage() {}
+
+func (x *InsertHeadersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lightclient_v1_lightclient_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InsertHeadersRequest.ProtoReflect.Descriptor instead.
+func (*InsertHeadersRequest) Descriptor() ([]byte, []int) {
+	return file_lightclient_v1_lightclient_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *InsertHeadersRequest) GetHeaders() [][]byte {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+type InsertHeadersResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// tip is the tip after the insertion.
+	Tip *Tip `protobuf:"bytes,1,opt,name=tip,proto3" json:"tip,omitempty"`
+}
+
+func (x *InsertHeadersResponse) Reset() {
+	*x = InsertHeadersResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lightclient_v1_lightclient_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *InsertHeadersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InsertHeadersResponse) ProtoMessage() {}
+
+func (x *InsertHeadersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_lightclient_v1_lightclient_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InsertHeadersResponse.ProtoReflect.Descriptor instead.
+func (*InsertHeadersResponse) Descriptor() ([]byte, []int) {
+	return file_lightclient_v1_lightclient_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *InsertHeadersResponse) GetTip() *Tip {
+	if x != nil {
+		return x.Tip
+	}
+	return nil
+}
+
+type GetTipRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *GetTipRequest) Reset() {
+	*x = GetTipRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lightclient_v1_lightclient_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetTipRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTipRequest) ProtoMessage() {}
+
+func (x *GetTipRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lightclient_v1_lightclient_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTipRequest.ProtoReflect.Descriptor instead.
+func (*GetTipRequest) Descriptor() ([]byte, []int) {
+	return file_lightclient_v1_lightclient_proto_rawDescGZIP(), []int{4}
+}
+
+type GetHeaderRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Block:
+	//	*GetHeaderRequest_Hash
+	//	*GetHeaderRequest_Height
+	Block isGetHeaderRequest_Block `protobuf_oneof:"block"`
+}
+
+func (x *GetHeaderRequest) Reset() {
+	*x = GetHeaderRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lightclient_v1_lightclient_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetHeaderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetHeaderRequest) ProtoMessage() {}
+
+func (x *GetHeaderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lightclient_v1_lightclient_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetHeaderRequest.ProtoReflect.Descriptor instead.
+func (*GetHeaderRequest) Descriptor() ([]byte, []int) {
+	return file_lightclient_v1_lightclient_proto_rawDescGZIP(), []int{5}
+}
+
+func (m *GetHeaderRequest) GetBlock() isGetHeaderRequest_Block {
+	if m != nil {
+		return m.Block
+	}
+	return nil
+}
+
+func (x *GetHeaderRequest) GetHash() string {
+	if x, ok := x.GetBlock().(*GetHeaderRequest_Hash); ok {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *GetHeaderRequest) GetHeight() int64 {
+	if x, ok := x.GetBlock().(*GetHeaderRequest_Height); ok {
+		return x.Height
+	}
+	return 0
+}
+
+type isGetHeaderRequest_Block interface {
+	isGetHeaderRequest_Block()
+}
+
+type GetHeaderRequest_Hash struct {
+	Hash string `protobuf:"bytes,1,opt,name=hash,proto3,oneof"`
+}
+
+type GetHeaderRequest_Height struct {
+	// height is a height of the best chain.
+	Height int64 `protobuf:"varint,2,opt,name=height,proto3,oneof"`
+}
+
+func (*GetHeaderRequest_Hash) isGetHeaderRequest_Block() {}
+
+func (*GetHeaderRequest_Height) isGetHeaderRequest_Block() {}
+
+type Header struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Hash   string `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	Height int64  `protobuf:"varint,2,opt,name=height,proto3" json:"height,omitempty"`
+	// raw is the 80 bytes serialized header.
+	Raw []byte `protobuf:"bytes,3,opt,name=raw,proto3" json:"raw,omitempty"`
+	// finalized is set for the headers of the finalized chain.
+	Finalized bool `protobuf:"varint,4,opt,name=finalized,proto3" json:"finalized,omitempty"`
+	// chain_work is the hex encoded total work of the chain up to the header.
+	ChainWork string `protobuf:"bytes,5,opt,name=chain_work,json=chainWork,proto3" json:"chain_work,omitempty"`
+}
+
+func (x *Header) Reset() {
+	*x = Header{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lightclient_v1_lightclient_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Header) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Header) ProtoMessage() {}
+
+func (x *Header) ProtoReflect() protoreflect.Message {
+	mi := &file_lightclient_v1_lightclient_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Header.ProtoReflect.Descriptor instead.
+func (*Header) Descriptor() ([]byte, []int) {
+	return file_lightclient_v1_lightclient_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Header) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *Header) GetHeight() int64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *Header) GetRaw() []byte {
+	if x != nil {
+		return x.Raw
+	}
+	return nil
+}
+
+func (x *Header) GetFinalized() bool {
+	if x != nil {
+		return x.Finalized
+	}
+	return false
+}
+
+func (x *Header) GetChainWork() string {
+	if x != nil {
+		return x.ChainWork
+	}
+	return ""
+}
+
+type VerifySPVRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	TxId string `protobuf:"bytes,1,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
+	// tx_out_proof is the hex proof returned by bitcoind gettxoutproof.
+	TxOutProof string `protobuf:"bytes,2,opt,name=tx_out_proof,json=txOutProof,proto3" json:"tx_out_proof,omitempty"`
+}
+
+func (x *VerifySPVRequest) Reset() {
+	*x = VerifySPVRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lightclient_v1_lightclient_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VerifySPVRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifySPVRequest) ProtoMessage() {}
+
+func (x *VerifySPVRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lightclient_v1_lightclient_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifySPVRequest.ProtoReflect.Descriptor instead.
+func (*VerifySPVRequest) Descriptor() ([]byte, []int) {
+	return file_lightclient_v1_lightclient_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *VerifySPVRequest) GetTxId() string {
+	if x != nil {
+		return x.TxId
+	}
+	return ""
+}
+
+func (x *VerifySPVRequest) GetTxOutProof() string {
+	if x != nil {
+		return x.TxOutProof
+	}
+	return ""
+}
+
+type VerifySPVResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Status SPVStatus `protobuf:"varint,1,opt,name=status,proto3,enum=lightclient.v1.SPVStatus" json:"status,omitempty"`
+}
+
+func (x *VerifySPVResponse) Reset() {
+	*x = VerifySPVResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lightclient_v1_lightclient_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VerifySPVResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifySPVResponse) ProtoMessage() {}
+
+func (x *VerifySPVResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_lightclient_v1_lightclient_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifySPVResponse.ProtoReflect.Descriptor instead.
+func (*VerifySPVResponse) Descriptor() ([]byte, []int) {
+	return file_lightclient_v1_lightclient_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *VerifySPVResponse) GetStatus() SPVStatus {
+	if x != nil {
+		return x.Status
+	}
+	return SPVStatus_SPV_STATUS_INVALID
+}
+
+type SubscribeTipsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *SubscribeTipsRequest) Reset() {
+	*x = SubscribeTipsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lightclient_v1_lightclient_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SubscribeTipsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeTipsRequest) ProtoMessage() {}
+
+func (x *SubscribeTipsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lightclient_v1_lightclient_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeTipsRequest.ProtoReflect.Descriptor instead.
+func (*SubscribeTipsRequest) Descriptor() ([]byte, []int) {
+	return file_lightclient_v1_lightclient_proto_rawDescGZIP(), []int{9}
+}
+
+var File_lightclient_v1_lightclient_proto protoreflect.FileDescriptor
+
+var file_lightclient_v1_lightclient_proto_rawDesc = []byte{
+	0x0a, 0x20, 0x6c, 0x69, 0x67, 0x68, 0x74, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x2f, 0x76, 0x31,
+	0x2f, 0x6c, 0x69, 0x67, 0x68, 0x74, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x12, 0x0e, 0x6c, 0x69, 0x67, 0x68, 0x74, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x2e,
+	0x76, 0x31, 0x22, 0x33, 0x0a, 0x05, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x12, 0x0a, 0x04, 0x68,
+	0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x68, 0x61, 0x73, 0x68, 0x12,
+	0x16, 0x0a, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x22, 0x65, 0x0a, 0x03, 0x54, 0x69, 0x70, 0x12, 0x29,
+	0x0a, 0x04, 0x62, 0x65, 0x73, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x6c,
+	0x69, 0x67, 0x68, 0x74, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x42, 0x6c,
+	0x6f, 0x63, 0x6b, 0x52, 0x04, 0x62, 0x65, 0x73, 0x74, 0x12, 0x33, 0x0a, 0x09, 0x66, 0x69, 0x6e,
+	0x61, 0x6c, 0x69, 0x7a, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x6c,
+	0x69, 0x67, 0x68, 0x74, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x42, 0x6c,
+	0x6f, 0x63, 0x6b, 0x52, 0x09, 0x66, 0x69, 0x6e, 0x61, 0x6c, 0x69, 0x7a, 0x65, 0x64, 0x22, 0x30,
+	0x0a, 0x14, 0x49, 0x6e, 0x73, 0x65, 0x72, 0x74, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72,
+	0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x07, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73,
+	0x22, 0x3e, 0x0a, 0x15, 0x49, 0x6e, 0x73, 0x65, 0x72, 0x74, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72,
+	0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x25, 0x0a, 0x03, 0x74, 0x69, 0x70,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x6c, 0x69, 0x67, 0x68, 0x74, 0x63, 0x6c,
+	0x69, 0x65, 0x6e, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x69, 0x70, 0x52, 0x03, 0x74, 0x69, 0x70,
+	0x22, 0x0f, 0x0a, 0x0d, 0x47, 0x65, 0x74, 0x54, 0x69, 0x70, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x22, 0x4b, 0x0a, 0x10, 0x47, 0x65, 0x74, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x04, 0x68, 0x61, 0x73, 0x68, 0x12, 0x18, 0x0a, 0x06, 0x68,
+	0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x48, 0x00, 0x52, 0x06, 0x68,
+	0x65, 0x69, 0x67, 0x68, 0x74, 0x42, 0x07, 0x0a, 0x05, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x22, 0x83,
+	0x01, 0x0a, 0x06, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x61, 0x73,
+	0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x68, 0x61, 0x73, 0x68, 0x12, 0x16, 0x0a,
+	0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x68,
+	0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x72, 0x61, 0x77, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x03, 0x72, 0x61, 0x77, 0x12, 0x1c, 0x0a, 0x09, 0x66, 0x69, 0x6e, 0x61, 0x6c,
+	0x69, 0x7a, 0x65, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x66, 0x69, 0x6e, 0x61,
+	0x6c, 0x69, 0x7a, 0x65, 0x64, 0x12, 0x1d, 0x0a, 0x0a, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x5f, 0x77,
+	0x6f, 0x72, 0x6b, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x63, 0x68, 0x61, 0x69, 0x6e,
+	0x57, 0x6f, 0x72, 0x6b, 0x22, 0x49, 0x0a, 0x10, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x53, 0x50,
+	0x56, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x13, 0x0a, 0x05, 0x74, 0x78, 0x5f, 0x69,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x78, 0x49, 0x64, 0x12, 0x20, 0x0a,
+	0x0c, 0x74, 0x78, 0x5f, 0x6f, 0x75, 0x74, 0x5f, 0x70, 0x72, 0x6f, 0x6f, 0x66, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0a, 0x74, 0x78, 0x4f, 0x75, 0x74, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x22,
+	0x46, 0x0a, 0x11, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x53, 0x50, 0x56, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x31, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0e, 0x32, 0x19, 0x2e, 0x6c, 0x69, 0x67, 0x68, 0x74, 0x63, 0x6c, 0x69, 0x65,
+	0x6e, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x50, 0x56, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52,
+	0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x22, 0x16, 0x0a, 0x14, 0x53, 0x75, 0x62, 0x73, 0x63,
+	0x72, 0x69, 0x62, 0x65, 0x54, 0x69, 0x70, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2a,
+	0x57, 0x0a, 0x09, 0x53, 0x50, 0x56, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x16, 0x0a, 0x12,
+	0x53, 0x50, 0x56, 0x5f, 0x53, 0x54, 0x41, 0x54, 0x55, 0x53, 0x5f, 0x49, 0x4e, 0x56, 0x41, 0x4c,
+	0x49, 0x44, 0x10, 0x00, 0x12, 0x1c, 0x0a, 0x18, 0x53, 0x50, 0x56, 0x5f, 0x53, 0x54, 0x41, 0x54,
+	0x55, 0x53, 0x5f, 0x50, 0x41, 0x52, 0x54, 0x49, 0x41, 0x4c, 0x5f, 0x56, 0x41, 0x4c, 0x49, 0x44,
+	0x10, 0x01, 0x12, 0x14, 0x0a, 0x10, 0x53, 0x50, 0x56, 0x5f, 0x53, 0x54, 0x41, 0x54, 0x55, 0x53,
+	0x5f, 0x56, 0x41, 0x4c, 0x49, 0x44, 0x10, 0x02, 0x32, 0x90, 0x03, 0x0a, 0x0b, 0x4c, 0x69, 0x67,
+	0x68, 0x74, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x12, 0x5c, 0x0a, 0x0d, 0x49, 0x6e, 0x73, 0x65,
+	0x72, 0x74, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x12, 0x24, 0x2e, 0x6c, 0x69, 0x67, 0x68,
+	0x74, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x6e, 0x73, 0x65, 0x72,
+	0x74, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x25, 0x2e, 0x6c, 0x69, 0x67, 0x68, 0x74, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x2e, 0x76, 0x31,
+	0x2e, 0x49, 0x6e, 0x73, 0x65, 0x72, 0x74, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3c, 0x0a, 0x06, 0x47, 0x65, 0x74, 0x54, 0x69, 0x70,
+	0x12, 0x1d, 0x2e, 0x6c, 0x69, 0x67, 0x68, 0x74, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x2e, 0x76,
+	0x31, 0x2e, 0x47, 0x65, 0x74, 0x54, 0x69, 0x70, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x13, 0x2e, 0x6c, 0x69, 0x67, 0x68, 0x74, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x2e, 0x76, 0x31,
+	0x2e, 0x54, 0x69, 0x70, 0x12, 0x45, 0x0a, 0x09, 0x47, 0x65, 0x74, 0x48, 0x65, 0x61, 0x64, 0x65,
+	0x72, 0x12, 0x20, 0x2e, 0x6c, 0x69, 0x67, 0x68, 0x74, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x2e,
+	0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x16, 0x2e, 0x6c, 0x69, 0x67, 0x68, 0x74, 0x63, 0x6c, 0x69, 0x65, 0x6e,
+	0x74, 0x2e, 0x76, 0x31, 0x2e, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12, 0x50, 0x0a, 0x09, 0x56,
+	0x65, 0x72, 0x69, 0x66, 0x79, 0x53, 0x50, 0x56, 0x12, 0x20, 0x2e, 0x6c, 0x69, 0x67, 0x68, 0x74,
+	0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79,
+	0x53, 0x50, 0x56, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x21, 0x2e, 0x6c, 0x69, 0x67,
+	0x68, 0x74, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x56, 0x65, 0x72, 0x69,
+	0x66, 0x79, 0x53, 0x50, 0x56, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4c, 0x0a,
+	0x0d, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x54, 0x69, 0x70, 0x73, 0x12, 0x24,
+	0x2e, 0x6c, 0x69, 0x67, 0x68, 0x74, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x2e, 0x76, 0x31, 0x2e,
+	0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x54, 0x69, 0x70, 0x73, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x13, 0x2e, 0x6c, 0x69, 0x67, 0x68, 0x74, 0x63, 0x6c, 0x69, 0x65,
+	0x6e, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x69, 0x70, 0x30, 0x01, 0x42, 0x4f, 0x5a, 0x4d, 0x67,
+	0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x67, 0x6f, 0x6e, 0x61, 0x74, 0x69,
+	0x76, 0x65, 0x2d, 0x63, 0x63, 0x2f, 0x62, 0x69, 0x74, 0x63, 0x6f, 0x69, 0x6e, 0x2d, 0x6c, 0x69,
+	0x67, 0x68, 0x74, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f,
+	0x6c, 0x69, 0x67, 0x68, 0x74, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x2f, 0x76, 0x31, 0x3b, 0x6c,
+	0x69, 0x67, 0x68, 0x74, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x76, 0x31, 0x62, 0x06, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_lightclient_v1_lightclient_proto_rawDescOnce sync.Once
+	file_lightclient_v1_lightclient_proto_rawDescData = file_lightclient_v1_lightclient_proto_rawDesc
+)
+
+func file_lightclient_v1_lightclient_proto_rawDescGZIP() []byte {
+	file_lightclient_v1_lightclient_proto_rawDescOnce.Do(func() {
+		file_lightclient_v1_lightclient_proto_rawDescData = protoimpl.X.CompressGZIP(file_lightclient_v1_lightclient_proto_rawDescData)
+	})
+	return file_lightclient_v1_lightclient_proto_rawDescData
+}
+
+var file_lightclient_v1_lightclient_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_lightclient_v1_lightclient_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_lightclient_v1_lightclient_proto_goTypes = []any{
+	(SPVStatus)(0),                // 0: lightclient.v1.SPVStatus
+	(*Block)(nil),                 // 1: lightclient.v1.Block
+	(*Tip)(nil),                   // 2: lightclient.v1.Tip
+	(*InsertHeadersRequest)(nil),  // 3: lightclient.v1.InsertHeadersRequest
+	(*InsertHeadersResponse)(nil), // 4: lightclient.v1.InsertHeadersResponse
+	(*GetTipRequest)(nil),         // 5: lightclient.v1.GetTipRequest
+	(*GetHeaderRequest)(nil),      // 6: lightclient.v1.GetHeaderRequest
+	(*Header)(nil),                // 7: lightclient.v1.Header
+	(*VerifySPVRequest)(nil),      // 8: lightclient.v1.VerifySPVRequest
+	(*VerifySPVResponse)(nil),     // 9: lightclient.v1.VerifySPVResponse
+	(*SubscribeTipsRequest)(nil),  // 10: lightclient.v1.SubscribeTipsRequest
+}
+var file_lightclient_v1_lightclient_proto_depIdxs = []int32{
+	1,  // 0: lightclient.v1.Tip.best:type_name -> lightclient.v1.Block
+	1,  // 1: lightclient.v1.Tip.finalized:type_name -> lightclient.v1.Block
+	2,  // 2: lightclient.v1.InsertHeadersResponse.tip:type_name -> lightclient.v1.Tip
+	0,  // 3: lightclient.v1.VerifySPVResponse.status:type_name -> lightclient.v1.SPVStatus
+	3,  // 4: lightclient.v1.LightClient.InsertHeaders:input_type -> lightclient.v1.InsertHeadersRequest
+	5,  // 5: lightclient.v1.LightClient.GetTip:input_type -> lightclient.v1.GetTipRequest
+	6,  // 6: lightclient.v1.LightClient.GetHeader:input_type -> lightclient.v1.GetHeaderRequest
+	8,  // 7: lightclient.v1.LightClient.VerifySPV:input_type -> lightclient.v1.VerifySPVRequest
+	10, // 8: lightclient.v1.LightClient.SubscribeTips:input_type -> lightclient.v1.SubscribeTipsRequest
+	4,  // 9: lightclient.v1.LightClient.InsertHeaders:output_type -> lightclient.v1.InsertHeadersResponse
+	2,  // 10: lightclient.v1.LightClient.GetTip:output_type -> lightclient.v1.Tip
+	7,  // 11: lightclient.v1.LightClient.GetHeader:output_type -> lightclient.v1.Header
+	9,  // 12: lightclient.v1.LightClient.VerifySPV:output_type -> lightclient.v1.VerifySPVResponse
+	2,  // 13: lightclient.v1.LightClient.SubscribeTips:output_type -> lightclient.v1.Tip
+	9,  // [9:14] is the sub-list for method output_type
+	4,  // [4:9] is the sub-list for method input_type
+	4,  // [4:4] is the sub-list for extension type_name
+	4,  // [4:4] is the sub-list for extension extendee
+	0,  // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_lightclient_v1_lightclient_proto_init() }
+func file_lightclient_v1_lightclient_proto_init() {
+	if File_lightclient_v1_lightclient_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_lightclient_v1_lightclient_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*Block); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lightclient_v1_lightclient_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*Tip); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lightclient_v1_lightclient_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*InsertHeadersRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lightclient_v1_lightclient_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*InsertHeadersResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lightclient_v1_lightclient_proto_msgTypes[4].Exporter = func(v any, i int) any {
+			switch v := v.(*GetTipRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lightclient_v1_lightclient_proto_msgTypes[5].Exporter = func(v any, i int) any {
+			switch v := v.(*GetHeaderRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lightclient_v1_lightclient_proto_msgTypes[6].Exporter = func(v any, i int) any {
+			switch v := v.(*Header); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lightclient_v1_lightclient_proto_msgTypes[7].Exporter = func(v any, i int) any {
+			switch v := v.(*VerifySPVRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lightclient_v1_lightclient_proto_msgTypes[8].Exporter = func(v any, i int) any {
+			switch v := v.(*VerifySPVResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lightclient_v1_lightclient_proto_msgTypes[9].Exporter = func(v any, i int) any {
+			switch v := v.(*SubscribeTipsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_lightclient_v1_lightclient_proto_msgTypes[5].OneofWrappers = []any{
+		(*GetHeaderRequest_Hash)(nil),
+		(*GetHeaderRequest_Height)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_lightclient_v1_lightclient_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_lightclient_v1_lightclient_proto_goTypes,
+		DependencyIndexes: file_lightclient_v1_lightclient_proto_depIdxs,
+		EnumInfos:         file_lightclient_v1_lightclient_proto_enumTypes,
+		MessageInfos:      file_lightclient_v1_lightclient_proto_msgTypes,
+	}.Build()
+	File_lightclient_v1_lightclient_proto = out.File
+	file_lightclient_v1_lightclient_proto_rawDesc = nil
+	file_lightclient_v1_lightclient_proto_goTypes = nil
+	file_lightclient_v1_lightclient_proto_depIdxs = nil
+}
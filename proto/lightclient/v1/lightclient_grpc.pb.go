@@ -0,0 +1,297 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: lightclient/v1/lightclient.proto
+
+package lightclientv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	LightClient_InsertHeaders_FullMethodName = "/lightclient.v1.LightClient/InsertHeaders"
+	LightClient_GetTip_FullMethodName        = "/lightclient.v1.LightClient/GetTip"
+	LightClient_GetHeader_FullMethodName     = "/lightclient.v1.LightClient/GetHeader"
+	LightClient_VerifySPV_FullMethodName     = "/lightclient.v1.LightClient/VerifySPV"
+	LightClient_SubscribeTips_FullMethodName = "/lightclient.v1.LightClient/SubscribeTips"
+)
+
+// LightClientClient is the client API for LightClient service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// LightClient is the gRPC API of the Bitcoin light client. Block hashes are
+// hex strings in the usual byte reversed order.
+type LightClientClient interface {
+	// InsertHeaders inserts the headers in order and updates the fork choice.
+	// It stops at the first invalid header.
+	InsertHeaders(ctx context.Context, in *InsertHeadersRequest, opts ...grpc.CallOption) (*InsertHeadersResponse, error)
+	// GetTip returns the best tip and the latest finalized block.
+	GetTip(ctx context.Context, in *GetTipRequest, opts ...grpc.CallOption) (*Tip, error)
+	// GetHeader returns a stored header by hash, or the header of the best
+	// chain at a height.
+	GetHeader(ctx context.Context, in *GetHeaderRequest, opts ...grpc.CallOption) (*Header, error)
+	// VerifySPV verifies a transaction inclusion proof.
+	VerifySPV(ctx context.Context, in *VerifySPVRequest, opts ...grpc.CallOption) (*VerifySPVResponse, error)
+	// SubscribeTips sends the current tip, then every tip change.
+	SubscribeTips(ctx context.Context, in *SubscribeTipsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Tip], error)
+}
+
+type lightClientClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewLightClientClient(cc grpc.ClientConnInterface) LightClientClient {
+	return &lightClientClient{cc}
+}
+
+func (c *lightClientClient) InsertHeaders(ctx context.Context, in *InsertHeadersRequest, opts ...grpc.CallOption) (*InsertHeadersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(InsertHeadersResponse)
+	err := c.cc.Invoke(ctx, LightClient_InsertHeaders_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lightClientClient) GetTip(ctx context.Context, in *GetTipRequest, opts ...grpc.CallOption) (*Tip, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Tip)
+	err := c.cc.Invoke(ctx, LightClient_GetTip_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lightClientClient) GetHeader(ctx context.Context, in *GetHeaderRequest, opts ...grpc.CallOption) (*Header, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Header)
+	err := c.cc.Invoke(ctx, LightClient_GetHeader_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lightClientClient) VerifySPV(ctx context.Context, in *VerifySPVRequest, opts ...grpc.CallOption) (*VerifySPVResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VerifySPVResponse)
+	err := c.cc.Invoke(ctx, LightClient_VerifySPV_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lightClientClient) SubscribeTips(ctx context.Context, in *SubscribeTipsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Tip], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &LightClient_ServiceDesc.Streams[0], LightClient_SubscribeTips_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeTipsRequest, Tip]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LightClient_SubscribeTipsClient = grpc.ServerStreamingClient[Tip]
+
+// LightClientServer is the server API for LightClient service.
+// All implementations must embed UnimplementedLightClientServer
+// for forward compatibility.
+//
+// LightClient is the gRPC API of the Bitcoin light client. Block hashes are
+// hex strings in the usual byte reversed order.
+type LightClientServer interface {
+	// InsertHeaders inserts the headers in order and updates the fork choice.
+	// It stops at the first invalid header.
+	InsertHeaders(context.Context, *InsertHeadersRequest) (*InsertHeadersResponse, error)
+	// GetTip returns the best tip and the latest finalized block.
+	GetTip(context.Context, *GetTipRequest) (*Tip, error)
+	// GetHeader returns a stored header by hash, or the header of the best
+	// chain at a height.
+	GetHeader(context.Context, *GetHeaderRequest) (*Header, error)
+	// VerifySPV verifies a transaction inclusion proof.
+	VerifySPV(context.Context, *VerifySPVRequest) (*VerifySPVResponse, error)
+	// SubscribeTips sends the current tip, then every tip change.
+	SubscribeTips(*SubscribeTipsRequest, grpc.ServerStreamingServer[Tip]) error
+	mustEmbedUnimplementedLightClientServer()
+}
+
+// UnimplementedLightClientServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedLightClientServer struct{}
+
+func (UnimplementedLightClientServer) InsertHeaders(context.Context, *InsertHeadersRequest) (*InsertHeadersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InsertHeaders not implemented")
+}
+func (UnimplementedLightClientServer) GetTip(context.Context, *GetTipRequest) (*Tip, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTip not implemented")
+}
+func (UnimplementedLightClientServer) GetHeader(context.Context, *GetHeaderRequest) (*Header, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHeader not implemented")
+}
+func (UnimplementedLightClientServer) VerifySPV(context.Context, *VerifySPVRequest) (*VerifySPVResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VerifySPV not implemented")
+}
+func (UnimplementedLightClientServer) SubscribeTips(*SubscribeTipsRequest, grpc.ServerStreamingServer[Tip]) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeTips not implemented")
+}
+func (UnimplementedLightClientServer) mustEmbedUnimplementedLightClientServer() {}
+func (UnimplementedLightClientServer) testEmbeddedByValue()                     {}
+
+// UnsafeLightClientServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to LightClientServer will
+// result in compilation errors.
+type UnsafeLightClientServer interface {
+	mustEmbedUnimplementedLightClientServer()
+}
+
+func RegisterLightClientServer(s grpc.ServiceRegistrar, srv LightClientServer) {
+	// If the following call pancis, it indicates UnimplementedLightClientServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&LightClient_ServiceDesc, srv)
+}
+
+func _LightClient_InsertHeaders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InsertHeadersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LightClientServer).InsertHeaders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LightClient_InsertHeaders_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LightClientServer).InsertHeaders(ctx, req.(*InsertHeadersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LightClient_GetTip_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTipRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LightClientServer).GetTip(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LightClient_GetTip_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LightClientServer).GetTip(ctx, req.(*GetTipRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LightClient_GetHeader_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetHeaderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LightClientServer).GetHeader(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LightClient_GetHeader_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LightClientServer).GetHeader(ctx, req.(*GetHeaderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LightClient_VerifySPV_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifySPVRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LightClientServer).VerifySPV(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LightClient_VerifySPV_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LightClientServer).VerifySPV(ctx, req.(*VerifySPVRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LightClient_SubscribeTips_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeTipsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LightClientServer).SubscribeTips(m, &grpc.GenericServerStream[SubscribeTipsRequest, Tip]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LightClient_SubscribeTipsServer = grpc.ServerStreamingServer[Tip]
+
+// LightClient_ServiceDesc is the grpc.ServiceDesc for LightClient service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var LightClient_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "lightclient.v1.LightClient",
+	HandlerType: (*LightClientServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "InsertHeaders",
+			Handler:    _LightClient_InsertHeaders_Handler,
+		},
+		{
+			MethodName: "GetTip",
+			Handler:    _LightClient_GetTip_Handler,
+		},
+		{
+			MethodName: "GetHeader",
+			Handler:    _LightClient_GetHeader_Handler,
+		},
+		{
+			MethodName: "VerifySPV",
+			Handler:    _LightClient_VerifySPV_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribeTips",
+			Handler:       _LightClient_SubscribeTips_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "lightclient/v1/lightclient.proto",
+}
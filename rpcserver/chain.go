@@ -0,0 +1,123 @@
+package rpcserver
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Tip is the head of the most difficult fork and the latest finalized block.
+type Tip struct {
+	Best      Block
+	Finalized Block
+}
+
+func sameBlock(a, b Block) bool {
+	return a.Height == b.Height && (a.Hash == b.Hash || a.Hash != nil && b.Hash != nil && *a.Hash == *b.Hash)
+}
+
+// tip returns the current tip, h.mu must be held.
+func (h *RPCServerHandler) tip() (Tip, error) {
+	cs, err := h.btcLC.ChainStatus()
+	if err != nil {
+		return Tip{}, err
+	}
+	return Tip{
+		Best:      Block{Hash: &cs.TipHash, Height: int64(cs.TipHeight)},
+		Finalized: Block{Hash: &cs.FinalizedHash, Height: int64(cs.FinalizedHeight)},
+	}, nil
+}
+
+// currentTip returns the current tip under the read lock.
+func (h *RPCServerHandler) currentTip() (Tip, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.tip()
+}
+
+// headerInfo is a stored header with its chain state.
+type headerInfo struct {
+	hash      chainhash.Hash
+	height    int64
+	header    wire.BlockHeader
+	finalized bool
+	work      *big.Int
+}
+
+// chainWork returns the hex encoded total work, as bitcoind getblockheader.
+func (info headerInfo) chainWork() string {
+	return fmt.Sprintf("%064x", info.work)
+}
+
+// headerInfo returns the chain state of lb, h.mu must be held.
+func (h *RPCServerHandler) headerInfo(lb *btclightclient.LightBlock) headerInfo {
+	hash := lb.Header.BlockHash()
+	return headerInfo{
+		hash:      hash,
+		height:    int64(lb.Height),
+		header:    lb.Header,
+		finalized: h.btcLC.IsFinalized(lb),
+		work:      h.btcLC.TotalWorkAtBlock(hash),
+	}
+}
+
+// headerByHash returns the stored header with the given hash, finalized or
+// on any fork.
+func (h *RPCServerHandler) headerByHash(hash chainhash.Hash) (headerInfo, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	lb, err := h.btcLC.BlockByHash(hash)
+	if err != nil {
+		return headerInfo{}, err
+	}
+	return h.headerInfo(lb), nil
+}
+
+// headerAtHeight returns the header at height of the best chain.
+func (h *RPCServerHandler) headerAtHeight(height int64) (headerInfo, error) {
+	if height > math.MaxInt32 {
+		return headerInfo{}, fmt.Errorf("%w: height %d", btclightclient.ErrHeightOutOfRange, height)
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	lb, err := h.btcLC.BlockAtHeight(int32(height))
+	if err != nil {
+		return headerInfo{}, err
+	}
+	return h.headerInfo(lb), nil
+}
+
+// forkHeads returns the heads of the forks, the best tip first.
+func (h *RPCServerHandler) forkHeads() ([]headerInfo, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	heads, err := h.btcLC.ForkHeads()
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]headerInfo, len(heads))
+	for i, lb := range heads {
+		infos[i] = h.headerInfo(lb)
+	}
+	return infos, nil
+}
+
+// ancestorProof returns the proof that the finalized block at height is an
+// ancestor of the latest finalized block.
+func (h *RPCServerHandler) ancestorProof(height int64) (AncestorProof, error) {
+	if height < 0 || height > math.MaxInt32 {
+		return AncestorProof{}, fmt.Errorf("%w: height %d", btclightclient.ErrHeightOutOfRange, height)
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	hash, proof, err := h.btcLC.AncestorProof(int32(height))
+	if err != nil {
+		return AncestorProof{}, err
+	}
+	return AncestorProof{Hash: hash, Root: h.btcLC.HeaderAccumulator().Root(), Proof: proof}, nil
+}
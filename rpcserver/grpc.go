@@ -0,0 +1,246 @@
+package rpcserver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
+	lightclientv1 "github.com/gonative-cc/bitcoin-lightclient/proto/lightclient/v1"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+// publishTip sends the tip to the subscribers when it changed, h.mu must be
+// held.
+func (h *RPCServerHandler) publishTip(tip Tip, err error) {
+	if err == nil {
+		h.tips.publish(tip)
+	}
+}
+
+// tipFeed sends the tip changes to its subscribers. A subscriber slower than
+// the changes only receives the latest tip.
+type tipFeed struct {
+	mu   sync.Mutex
+	last Tip
+	subs map[chan Tip]struct{}
+}
+
+// subscribe returns the channel of the tips following current, and the
+// function to stop receiving them.
+func (f *tipFeed) subscribe(current Tip) (<-chan Tip, func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.last = current
+	if f.subs == nil {
+		f.subs = make(map[chan Tip]struct{})
+	}
+	ch := make(chan Tip, 1)
+	f.subs[ch] = struct{}{}
+	return ch, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.subs, ch)
+	}
+}
+
+func (f *tipFeed) publish(tip Tip) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if sameBlock(tip.Best, f.last.Best) && sameBlock(tip.Finalized, f.last.Finalized) {
+		return
+	}
+	f.last = tip
+	for ch := range f.subs {
+		// replace the tip not received yet
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- tip
+	}
+}
+
+// grpcService implements the LightClient gRPC service with the handlers of
+// the JSON-RPC API, the network of a call is selected by its metadata.
+type grpcService struct {
+	lightclientv1.UnimplementedLightClientServer
+	nets networks
+}
+
+// NewGRPCServer creates the gRPC server of the LightClient service backed by
+// h. The caller is responsible for starting it.
+func NewGRPCServer(h *RPCServerHandler, opts ...grpc.ServerOption) *grpc.Server {
+	return newGRPCServer(singleNetwork(h.btcLC.ChainParams().Name, h), opts...)
+}
+
+func newGRPCServer(nets networks, opts ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(opts...)
+	lightclientv1.RegisterLightClientServer(server, &grpcService{nets: nets})
+	return server
+}
+
+// handler returns the handler of the network of the call.
+func (s *grpcService) handler(ctx context.Context) (*RPCServerHandler, error) {
+	h, err := s.nets.handlerFromContext(ctx)
+	if err != nil {
+		return nil, grpcstatus.Error(codes.NotFound, err.Error())
+	}
+	return h, nil
+}
+
+// grpcError returns err with the gRPC status code matching the light client
+// error, or code by default.
+func grpcError(err error, code codes.Code) error {
+	switch {
+	case errors.Is(err, btclightclient.ErrBlockNotInChain),
+		errors.Is(err, btclightclient.ErrHeightOutOfRange),
+		errors.Is(err, btclightclient.ErrMissingAncestor):
+		code = codes.NotFound
+	case errors.Is(err, btclightclient.ErrStoreUnavailable):
+		code = codes.Unavailable
+	}
+	return grpcstatus.Error(code, err.Error())
+}
+
+func blockProto(b Block) *lightclientv1.Block {
+	return &lightclientv1.Block{Hash: b.Hash.String(), Height: b.Height}
+}
+
+func tipProto(tip Tip) *lightclientv1.Tip {
+	return &lightclientv1.Tip{Best: blockProto(tip.Best), Finalized: blockProto(tip.Finalized)}
+}
+
+func currentTipProto(h *RPCServerHandler) (*lightclientv1.Tip, error) {
+	tip, err := h.currentTip()
+	if err != nil {
+		return nil, grpcError(err, codes.Internal)
+	}
+	return tipProto(tip), nil
+}
+
+func (s *grpcService) InsertHeaders(ctx context.Context, req *lightclientv1.InsertHeadersRequest) (*lightclientv1.InsertHeadersResponse, error) {
+	h, err := s.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	headers := make([]*wire.BlockHeader, len(req.Headers))
+	for i, raw := range req.Headers {
+		header, err := btclightclient.BlockHeaderFromBytes(raw)
+		if err != nil {
+			return nil, grpcstatus.Errorf(codes.InvalidArgument, "header %d: %v", i, err)
+		}
+		headers[i] = &header
+	}
+
+	// the insertion errors are about the headers
+	if err := h.InsertHeaders(ctx, headers); err != nil {
+		return nil, grpcstatus.Error(codes.InvalidArgument, err.Error())
+	}
+	tip, err := currentTipProto(h)
+	if err != nil {
+		return nil, err
+	}
+	return &lightclientv1.InsertHeadersResponse{Tip: tip}, nil
+}
+
+func (s *grpcService) GetTip(ctx context.Context, _ *lightclientv1.GetTipRequest) (tip *lightclientv1.Tip, err error) {
+	h, err := s.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func(start time.Time) { h.metrics.observe("get_tip", start, err) }(time.Now())
+	return currentTipProto(h)
+}
+
+func (s *grpcService) GetHeader(ctx context.Context, req *lightclientv1.GetHeaderRequest) (header *lightclientv1.Header, err error) {
+	h, err := s.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func(start time.Time) { h.metrics.observe("get_header", start, err) }(time.Now())
+
+	var info headerInfo
+	switch block := req.Block.(type) {
+	case *lightclientv1.GetHeaderRequest_Hash:
+		hash, err := chainhash.NewHashFromStr(block.Hash)
+		if err != nil {
+			return nil, grpcstatus.Errorf(codes.InvalidArgument, "invalid hash: %v", err)
+		}
+		info, err = h.headerByHash(*hash)
+		if err != nil {
+			return nil, grpcError(err, codes.Internal)
+		}
+	case *lightclientv1.GetHeaderRequest_Height:
+		info, err = h.headerAtHeight(block.Height)
+		if err != nil {
+			return nil, grpcError(err, codes.Internal)
+		}
+	default:
+		return nil, grpcstatus.Error(codes.InvalidArgument, "hash or height required")
+	}
+
+	var raw bytes.Buffer
+	if err := info.header.Serialize(&raw); err != nil {
+		return nil, grpcstatus.Error(codes.Internal, err.Error())
+	}
+	return &lightclientv1.Header{
+		Hash:      info.hash.String(),
+		Height:    info.height,
+		Raw:       raw.Bytes(),
+		Finalized: info.finalized,
+		ChainWork: info.chainWork(),
+	}, nil
+}
+
+func (s *grpcService) VerifySPV(ctx context.Context, req *lightclientv1.VerifySPVRequest) (*lightclientv1.VerifySPVResponse, error) {
+	h, err := s.handler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := btclightclient.SPVProofFromHex(req.TxOutProof, req.TxId)
+	if err != nil {
+		return nil, grpcstatus.Errorf(codes.InvalidArgument, "invalid proof: %v", err)
+	}
+	spvStatus, err := h.VerifySPV(proof)
+	if err != nil {
+		return nil, grpcError(err, codes.Internal)
+	}
+	return &lightclientv1.VerifySPVResponse{Status: lightclientv1.SPVStatus(spvStatus)}, nil
+}
+
+func (s *grpcService) SubscribeTips(_ *lightclientv1.SubscribeTipsRequest, stream grpc.ServerStreamingServer[lightclientv1.Tip]) error {
+	h, err := s.handler(stream.Context())
+	if err != nil {
+		return err
+	}
+	h.metrics.observe("subscribe_tips", time.Now(), nil)
+	// subscribe under the lock so that no change is missed after the
+	// current tip
+	h.mu.RLock()
+	tip, err := h.tip()
+	tips, cancel := h.tips.subscribe(tip)
+	h.mu.RUnlock()
+	defer cancel()
+	if err != nil {
+		return grpcError(err, codes.Internal)
+	}
+
+	for {
+		if err := stream.Send(tipProto(tip)); err != nil {
+			return err
+		}
+		select {
+		case tip = <-tips:
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package rpcserver
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
+	"github.com/gonative-cc/bitcoin-lightclient/chaingen"
+	lightclientv1 "github.com/gonative-cc/bitcoin-lightclient/proto/lightclient/v1"
+
+	"github.com/btcsuite/btcd/wire"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcstatus "google.golang.org/grpc/status"
+	"gotest.tools/assert"
+)
+
+// newTestChain returns a light client with the chaingen.RegtestChain blocks
+// and the next 3 blocks.
+func newTestChain(t *testing.T) (*btclightclient.BTCLightClient, *chaingen.Generator, []*chaingen.Block, []*chaingen.Block) {
+	t.Helper()
+	g, blocks, next := chaingen.RegtestChain()
+	return btclightclient.NewBTCLightClientWithData(g.Params(), chaingen.Headers(blocks), 0), g, blocks, next
+}
+
+// newGRPCClient serves the gRPC API of server on a random port.
+func newGRPCClient(t *testing.T, server *Server) lightclientv1.LightClientClient {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	go func() { _ = server.GRPC.Serve(ln) }()
+	t.Cleanup(server.GRPC.Stop)
+
+	conn, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NilError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return lightclientv1.NewLightClientClient(conn)
+}
+
+func rawHeader(t *testing.T, b *chaingen.Block) []byte {
+	t.Helper()
+	var raw bytes.Buffer
+	header := b.Header()
+	assert.NilError(t, header.Serialize(&raw))
+	return raw.Bytes()
+}
+
+func TestGRPC(t *testing.T) {
+	lc, _, blocks, next := newTestChain(t)
+	server := New(lc, Config{GRPCAddr: "127.0.0.1:0"})
+	c := newGRPCClient(t, server)
+	ctx := context.Background()
+
+	tip, err := c.GetTip(ctx, &lightclientv1.GetTipRequest{})
+	assert.NilError(t, err)
+	assert.Equal(t, tip.Best.Hash, blocks[20].Hash().String())
+	assert.Equal(t, tip.Finalized.Height, int64(13))
+
+	// the subscription starts with the current tip
+	stream, err := c.SubscribeTips(ctx, &lightclientv1.SubscribeTipsRequest{})
+	assert.NilError(t, err)
+	sub, err := stream.Recv()
+	assert.NilError(t, err)
+	assert.Equal(t, sub.Best.Hash, blocks[20].Hash().String())
+
+	res, err := c.InsertHeaders(ctx, &lightclientv1.InsertHeadersRequest{
+		Headers: [][]byte{rawHeader(t, next[0]), rawHeader(t, next[1])},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, res.Tip.Best.Hash, next[1].Hash().String())
+	assert.Equal(t, res.Tip.Finalized.Height, int64(15))
+	sub, err = stream.Recv()
+	assert.NilError(t, err)
+	assert.Equal(t, sub.Best.Hash, next[1].Hash().String())
+
+	_, err = c.InsertHeaders(ctx, &lightclientv1.InsertHeadersRequest{Headers: [][]byte{{1, 2}}})
+	assert.Equal(t, grpcstatus.Code(err), codes.InvalidArgument)
+
+	header, err := c.GetHeader(ctx, &lightclientv1.GetHeaderRequest{
+		Block: &lightclientv1.GetHeaderRequest_Height{Height: 5},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, header.Hash, blocks[5].Hash().String())
+	assert.Assert(t, header.Finalized)
+	assert.DeepEqual(t, header.Raw, rawHeader(t, blocks[5]))
+	assert.Equal(t, len(header.ChainWork), 64)
+	header, err = c.GetHeader(ctx, &lightclientv1.GetHeaderRequest{
+		Block: &lightclientv1.GetHeaderRequest_Hash{Hash: next[1].Hash().String()},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, header.Height, int64(22))
+	assert.Assert(t, !header.Finalized)
+	_, err = c.GetHeader(ctx, &lightclientv1.GetHeaderRequest{
+		Block: &lightclientv1.GetHeaderRequest_Height{Height: 23},
+	})
+	assert.Equal(t, grpcstatus.Code(err), codes.NotFound)
+	_, err = c.GetHeader(ctx, &lightclientv1.GetHeaderRequest{
+		Block: &lightclientv1.GetHeaderRequest_Hash{Hash: "zz"},
+	})
+	assert.Equal(t, grpcstatus.Code(err), codes.InvalidArgument)
+
+	txID := blocks[5].TxIDs()[1]
+	proof, err := blocks[5].TxOutProof(txID)
+	assert.NilError(t, err)
+	spv, err := c.VerifySPV(ctx, &lightclientv1.VerifySPVRequest{TxId: txID.String(), TxOutProof: proof})
+	assert.NilError(t, err)
+	assert.Equal(t, spv.Status, lightclientv1.SPVStatus_SPV_STATUS_VALID)
+
+	// the JSON-RPC insertions are streamed too
+	header2 := next[2].Header()
+	assert.NilError(t, server.Handler.InsertHeaders(ctx, []*wire.BlockHeader{&header2}))
+	sub, err = stream.Recv()
+	assert.NilError(t, err)
+	assert.Equal(t, sub.Best.Hash, next[2].Hash().String())
+}
+
+func TestTipFeed(t *testing.T) {
+	var f tipFeed
+	tips, cancel := f.subscribe(Tip{})
+	tip := func(height int64) Tip { return Tip{Best: Block{Height: height}} }
+
+	// a slow subscriber gets the latest tip
+	f.publish(tip(1))
+	f.publish(tip(2))
+	assert.Equal(t, (<-tips).Best.Height, int64(2))
+
+	// unchanged tips are not sent
+	f.publish(tip(2))
+	select {
+	case <-tips:
+		t.Fatal("unchanged tip sent")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	cancel()
+	f.publish(tip(3))
+	assert.Equal(t, len(tips), 0)
+}
@@ -0,0 +1,89 @@
+package rpcserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/rs/zerolog/log"
+)
+
+// Status is returned by the get_status RPC and the /readyz endpoint.
+type Status struct {
+	Network         string         `json:"network"`
+	TipHeight       int32          `json:"tip_height"`
+	TipHash         chainhash.Hash `json:"tip_hash"`
+	TipTimestamp    time.Time      `json:"tip_timestamp"`
+	TipAgeSeconds   int64          `json:"tip_age_seconds"`
+	FinalizedHeight int32          `json:"finalized_height"`
+	FinalizedHash   chainhash.Hash `json:"finalized_hash"`
+	Forks           int            `json:"forks"`
+	StateRoot       chainhash.Hash `json:"state_root"`
+	AccumulatorRoot chainhash.Hash `json:"accumulator_root"`
+	Ready           bool           `json:"ready"`
+	// Reason explains why the light client is not ready.
+	Reason string `json:"reason,omitempty"`
+}
+
+// status builds the light client status. The light client is not ready when
+// the store is unavailable or the best tip is older than maxTipAge.
+func status(btcLC *btclightclient.BTCLightClient, maxTipAge time.Duration, now time.Time) Status {
+	cs, err := btcLC.ChainStatus()
+	if err != nil {
+		return Status{Network: btcLC.ChainParams().Name, Reason: err.Error()}
+	}
+
+	s := Status{
+		Network:         cs.Network,
+		TipHeight:       cs.TipHeight,
+		TipHash:         cs.TipHash,
+		TipTimestamp:    cs.TipTimestamp,
+		TipAgeSeconds:   int64(now.Sub(cs.TipTimestamp) / time.Second),
+		FinalizedHeight: cs.FinalizedHeight,
+		FinalizedHash:   cs.FinalizedHash,
+		Forks:           cs.Forks,
+		StateRoot:       cs.StateRoot,
+		AccumulatorRoot: cs.AccumulatorRoot,
+		Ready:           true,
+	}
+	if tipAge := now.Sub(cs.TipTimestamp); maxTipAge > 0 && tipAge > maxTipAge {
+		s.Ready = false
+		s.Reason = fmt.Sprintf("best tip is stale: %s old, threshold %s", tipAge.Truncate(time.Second), maxTipAge)
+	}
+	return s
+}
+
+// GetStatus returns the light client status and readiness
+func (h *RPCServerHandler) GetStatus() (Status, error) {
+	defer h.metrics.observe("get_status", time.Now(), nil)
+	return h.status(time.Now()), nil
+}
+
+func handleHealthz(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+// status returns the light client status, see status.
+func (h *RPCServerHandler) status(now time.Time) Status {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return status(h.btcLC, h.maxTipAge, now)
+}
+
+func readyzHandler(h *RPCServerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		s := h.status(time.Now())
+		w.Header().Set("Content-Type", "application/json")
+		if !s.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if err := json.NewEncoder(w).Encode(s); err != nil {
+			log.Err(err).Msg("Failed to write readiness response")
+		}
+	}
+}
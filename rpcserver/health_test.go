@@ -0,0 +1,71 @@
+package rpcserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"gotest.tools/assert"
+)
+
+// regtest genesis header and its first child
+var testHeaders = []string{
+	"0100000000000000000000000000000000000000000000000000000000000000000000003ba3edfd7a7b12b27ac72c3e67768f617fc81bc3888a51323a9fb8aa4b1e5e4adae5494dffff7f2002000000",
+	"0000002006226e46111a0b59caaf126043eb5bbf28c34f3a5e332a1fc7b2b73cf188910f2fe76e709f3031b5ed684f098b5cd35a09633943d141a6d0525f34a1643dcf44e0b23c67ffff7f2002000000",
+}
+
+func newTestLightClient(t *testing.T) *btclightclient.BTCLightClient {
+	headers := make([]wire.BlockHeader, len(testHeaders))
+	for i, s := range testHeaders {
+		h, err := btclightclient.BlockHeaderFromHex(s)
+		assert.NilError(t, err)
+		headers[i] = h
+	}
+	return btclightclient.NewBTCLightClientWithData(&chaincfg.RegressionNetParams, headers, 0)
+}
+
+func TestStatus(t *testing.T) {
+	lc := newTestLightClient(t)
+	tip, err := btclightclient.BlockHeaderFromHex(testHeaders[1])
+	assert.NilError(t, err)
+
+	s := status(lc, time.Hour, tip.Timestamp.Add(time.Minute))
+	assert.Assert(t, s.Ready, s.Reason)
+	assert.Equal(t, s.TipHeight, int32(1))
+	assert.Equal(t, s.TipHash, tip.BlockHash())
+	assert.Equal(t, s.TipAgeSeconds, int64(60))
+	assert.Equal(t, s.Network, chaincfg.RegressionNetParams.Name)
+
+	s = status(lc, time.Hour, tip.Timestamp.Add(2*time.Hour))
+	assert.Assert(t, !s.Ready)
+
+	// zero threshold disables the staleness check
+	s = status(lc, 0, tip.Timestamp.Add(24*time.Hour))
+	assert.Assert(t, s.Ready)
+
+	s = status(btclightclient.NewBTCLightClient(&chaincfg.RegressionNetParams), time.Hour, time.Now())
+	assert.Assert(t, !s.Ready)
+	assert.Equal(t, s.Reason, btclightclient.ErrStoreUnavailable.Error())
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	lc := newTestLightClient(t)
+
+	rec := httptest.NewRecorder()
+	handleHealthz(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, rec.Code, http.StatusOK)
+
+	// the test headers are years old, so the tip is stale
+	rec = httptest.NewRecorder()
+	readyzHandler(&RPCServerHandler{btcLC: lc, maxTipAge: time.Hour})(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, rec.Code, http.StatusServiceUnavailable)
+
+	rec = httptest.NewRecorder()
+	readyzHandler(&RPCServerHandler{btcLC: lc})(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, rec.Code, http.StatusOK)
+}
@@ -0,0 +1,50 @@
+package rpcserver
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics groups the prometheus collectors updated by the RPC server.
+// A nil *Metrics is valid and disables instrumentation.
+type Metrics struct {
+	requests *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// NewMetrics creates the RPC server collectors and registers them in reg.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "rpcserver",
+			Name:      "requests_total",
+			Help:      "Number of RPC requests by method.",
+		}, []string{"method"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "rpcserver",
+			Name:      "errors_total",
+			Help:      "Number of RPC requests that returned an error, by method.",
+		}, []string{"method"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "rpcserver",
+			Name:      "request_duration_seconds",
+			Help:      "RPC request handling time by method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"}),
+	}
+	reg.MustRegister(m.requests, m.errors, m.duration)
+	return m
+}
+
+func (m *Metrics) observe(method string, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	m.requests.WithLabelValues(method).Inc()
+	m.duration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		m.errors.WithLabelValues(method).Inc()
+	}
+}
@@ -0,0 +1,168 @@
+package rpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc/metadata"
+)
+
+// ErrUnknownNetwork is returned for requests to a network not served.
+var ErrUnknownNetwork = errors.New("unknown network")
+
+// NetworkMetadataKey is the gRPC metadata key selecting the network of a
+// call, the default network is used when it is missing.
+const NetworkMetadataKey = "network"
+
+// NetworkQueryParam is the HTTP query parameter selecting the network of a
+// request not using a network path prefix.
+const NetworkQueryParam = "network"
+
+// Network is a light client served under a name.
+type Network struct {
+	Name        string
+	LightClient *btclightclient.BTCLightClient
+}
+
+var networkNameRe = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// reservedNames are the top level paths which can't be network prefixes.
+var reservedNames = map[string]bool{
+	"v1":           true,
+	"metrics":      true,
+	"healthz":      true,
+	"readyz":       true,
+	"openapi.json": true,
+}
+
+// ValidateNetworkName checks that name can be used as a path prefix.
+func ValidateNetworkName(name string) error {
+	if !networkNameRe.MatchString(name) {
+		return fmt.Errorf("invalid network name %q: must match %s", name, networkNameRe)
+	}
+	if reservedNames[name] {
+		return fmt.Errorf("invalid network name %q: reserved path", name)
+	}
+	return nil
+}
+
+// networks are the handlers of the served networks.
+type networks struct {
+	handlers map[string]*RPCServerHandler
+	// def is the network of the requests not selecting one.
+	def string
+}
+
+// singleNetwork returns the networks made of the handler h named name.
+func singleNetwork(name string, h *RPCServerHandler) networks {
+	return networks{handlers: map[string]*RPCServerHandler{name: h}, def: name}
+}
+
+// handler returns the handler of the named network, or of the default
+// network when name is empty.
+func (n networks) handler(name string) (*RPCServerHandler, error) {
+	if name == "" {
+		name = n.def
+	}
+	h, ok := n.handlers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownNetwork, name)
+	}
+	return h, nil
+}
+
+// handlerFromContext returns the handler of the network selected by the
+// gRPC metadata of ctx.
+func (n networks) handlerFromContext(ctx context.Context) (*RPCServerHandler, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(NetworkMetadataKey)
+	if len(values) == 0 {
+		return n.handler("")
+	}
+	return n.handler(values[0])
+}
+
+// NewMulti creates the servers of networks, the first one is the default
+// network. Each network is served under its name as path prefix, or by the
+// network query parameter, and by the network gRPC metadata. The metrics of
+// the networks have a network label. The caller is responsible for starting
+// the servers.
+func NewMulti(nets []Network, cfg Config) (*Server, error) {
+	if len(nets) == 0 {
+		return nil, errors.New("no network to serve")
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/healthz", handleHealthz)
+	// the rate limit is shared by the networks
+	limiter := newRateLimiter(cfg, registry)
+
+	all := networks{handlers: map[string]*RPCServerHandler{}, def: nets[0].Name}
+	apis := map[string]http.Handler{}
+	for _, n := range nets {
+		if err := ValidateNetworkName(n.Name); err != nil {
+			return nil, err
+		}
+		if _, ok := all.handlers[n.Name]; ok {
+			return nil, fmt.Errorf("duplicated network name %q", n.Name)
+		}
+		reg := prometheus.WrapRegistererWith(prometheus.Labels{"network": n.Name}, registry)
+		h := newHandler(n.LightClient, reg, cfg)
+		all.handlers[n.Name] = h
+
+		api := limiter.middleware(apiHandler(n.Name, h))
+		apis[n.Name] = api
+		mux.Handle("/"+n.Name+"/", http.StripPrefix("/"+n.Name, api))
+	}
+	mux.Handle("/", networkRouter(apis, all.def))
+
+	server := &Server{
+		HTTP: &http.Server{
+			Addr:         cfg.Addr,
+			Handler:      mux,
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 10 * time.Second,
+		},
+		GRPCAddr: cfg.GRPCAddr,
+		Handler:  all.handlers[all.def],
+		Networks: all.handlers,
+	}
+	if cfg.GRPCAddr != "" {
+		server.GRPC = newGRPCServer(all, limiter.serverOptions()...)
+	}
+	return server, nil
+}
+
+// networkRouter serves the requests without a network path prefix with the
+// API of the network query parameter, or of the default network.
+func networkRouter(apis map[string]http.Handler, def string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get(NetworkQueryParam)
+		if name == "" {
+			name = def
+		}
+		api, ok := apis[name]
+		if !ok {
+			err := fmt.Errorf("%w: %q", ErrUnknownNetwork, name)
+			writeJSON(w, http.StatusNotFound, ErrorResponse{Error: err.Error()})
+			return
+		}
+		api.ServeHTTP(w, r)
+	})
+}
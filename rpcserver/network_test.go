@@ -0,0 +1,107 @@
+package rpcserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gonative-cc/bitcoin-lightclient/chaingen"
+	lightclientv1 "github.com/gonative-cc/bitcoin-lightclient/proto/lightclient/v1"
+
+	"github.com/btcsuite/btcd/wire"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	grpcstatus "google.golang.org/grpc/status"
+	"gotest.tools/assert"
+)
+
+func TestMultiNetwork(t *testing.T) {
+	lcA, _, blocks, _ := newTestChain(t)
+	// the second network is 3 blocks ahead
+	lcB, _, _, next := newTestChain(t)
+	server, err := NewMulti([]Network{{Name: "a", LightClient: lcA}, {Name: "b", LightClient: lcB}}, Config{GRPCAddr: "127.0.0.1:0"})
+	assert.NilError(t, err)
+	headers := make([]*wire.BlockHeader, len(next))
+	for i, b := range next {
+		header := b.Header()
+		headers[i] = &header
+	}
+	assert.NilError(t, server.Networks["b"].InsertHeaders(context.Background(), headers))
+	ts := httptest.NewServer(server.HTTP.Handler)
+	defer ts.Close()
+	c := restClient{t: t, url: ts.URL}
+
+	tipHash := func(path string) *chaingen.Block {
+		t.Helper()
+		var tip TipResponse
+		assert.Equal(t, c.do(http.MethodGet, path, nil, &tip), http.StatusOK)
+		switch tip.Best.Hash {
+		case blocks[20].Hash():
+			return blocks[20]
+		case next[2].Hash():
+			return next[2]
+		}
+		t.Fatalf("unexpected tip %s", tip.Best.Hash)
+		return nil
+	}
+	assert.Equal(t, tipHash("/v1/tip"), blocks[20])
+	assert.Equal(t, tipHash("/a/v1/tip"), blocks[20])
+	assert.Equal(t, tipHash("/b/v1/tip"), next[2])
+	assert.Equal(t, tipHash("/v1/tip?network=b"), next[2])
+
+	var errRes ErrorResponse
+	assert.Equal(t, c.do(http.MethodGet, "/v1/tip?network=c", nil, &errRes), http.StatusNotFound)
+	assert.ErrorContains(t, errorString(errRes.Error), ErrUnknownNetwork.Error())
+
+	// JSON-RPC under a network prefix
+	res, err := http.Post(ts.URL+"/b/", "application/json",
+		strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"get_status","params":[]}`))
+	assert.NilError(t, err)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(body), `"tip_height":23`), string(body))
+
+	// gRPC selects the network with the call metadata
+	g := newGRPCClient(t, server)
+	ctx := context.Background()
+	tip, err := g.GetTip(ctx, &lightclientv1.GetTipRequest{})
+	assert.NilError(t, err)
+	assert.Equal(t, tip.Best.Hash, blocks[20].Hash().String())
+	tip, err = g.GetTip(metadata.AppendToOutgoingContext(ctx, NetworkMetadataKey, "b"), &lightclientv1.GetTipRequest{})
+	assert.NilError(t, err)
+	assert.Equal(t, tip.Best.Hash, next[2].Hash().String())
+	_, err = g.GetTip(metadata.AppendToOutgoingContext(ctx, NetworkMetadataKey, "c"), &lightclientv1.GetTipRequest{})
+	assert.Equal(t, grpcstatus.Code(err), codes.NotFound)
+
+	// the metrics of the networks are labelled
+	res, err = http.Get(ts.URL + "/metrics")
+	assert.NilError(t, err)
+	defer res.Body.Close()
+	body, err = io.ReadAll(res.Body)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(body), `rpcserver_requests_total{method="rest_get_tip",network="a"} 2`), string(body))
+	assert.Assert(t, strings.Contains(string(body), `rpcserver_requests_total{method="rest_get_tip",network="b"} 2`), string(body))
+}
+
+func TestNewMultiErrors(t *testing.T) {
+	lc, _, _, _ := newTestChain(t)
+	for _, tc := range []struct {
+		name string
+		nets []Network
+		err  string
+	}{
+		{"none", nil, "no network"},
+		{"invalid name", []Network{{Name: "Main Net", LightClient: lc}}, "invalid network name"},
+		{"reserved name", []Network{{Name: "metrics", LightClient: lc}}, "reserved path"},
+		{"duplicated", []Network{{Name: "a", LightClient: lc}, {Name: "a", LightClient: lc}}, "duplicated network name"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewMulti(tc.nets, Config{})
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package rpcserver
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// openAPI returns the OpenAPI 3 document of the REST routes, the schemas are
+// generated from the Go types of the request and response bodies.
+func openAPI(routes []restRoute) map[string]any {
+	g := schemaGen{schemas: map[string]any{}}
+	errorContent := jsonContent(g.schema(reflect.TypeOf(ErrorResponse{})))
+
+	paths := map[string]any{}
+	for _, route := range routes {
+		op := map[string]any{
+			"operationId": route.name,
+			"summary":     route.summary,
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "OK",
+					"content":     jsonContent(g.schema(reflect.TypeOf(route.response))),
+				},
+				"default": map[string]any{
+					"description": "Error",
+					"content":     errorContent,
+				},
+			},
+		}
+		if len(route.params) > 0 {
+			params := []any{}
+			for _, p := range route.params {
+				params = append(params, map[string]any{
+					"name":        p.name,
+					"in":          "path",
+					"required":    true,
+					"description": p.description,
+					"schema":      map[string]any{"type": p.typ},
+				})
+			}
+			op["parameters"] = params
+		}
+		if route.request != nil {
+			op["requestBody"] = map[string]any{
+				"required": true,
+				"content":  jsonContent(g.schema(reflect.TypeOf(route.request))),
+			}
+		}
+
+		item, ok := paths[route.path].(map[string]any)
+		if !ok {
+			item = map[string]any{}
+			paths[route.path] = item
+		}
+		item[strings.ToLower(route.method)] = op
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "Bitcoin light client REST API",
+			"version": "1.0.0",
+		},
+		"paths":      paths,
+		"components": map[string]any{"schemas": g.schemas},
+	}
+}
+
+func jsonContent(schema map[string]any) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
+
+// schemaGen generates the JSON schemas of Go types, the structs are
+// collected in schemas and referenced by name.
+type schemaGen struct {
+	schemas map[string]any
+}
+
+var hashType = reflect.TypeOf(chainhash.Hash{})
+
+func (g *schemaGen) schema(t reflect.Type) map[string]any {
+	if t == hashType {
+		return map[string]any{
+			"type":        "string",
+			"description": "hex encoded hash, in the usual byte reversed order",
+			"pattern":     "^[0-9a-f]{64}$",
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int32, reflect.Uint32, reflect.Uint16, reflect.Uint8:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Struct:
+		if _, ok := g.schemas[t.Name()]; !ok {
+			g.schemas[t.Name()] = g.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	}
+	panic(fmt.Sprintf("no JSON schema for %s", t))
+}
+
+// object returns the schema of a struct from its json tags.
+func (g *schemaGen) object(t reflect.Type) map[string]any {
+	// reserve the name of recursive types
+	g.schemas[t.Name()] = nil
+	properties := map[string]any{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = g.schema(field.Type)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
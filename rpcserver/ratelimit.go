@@ -0,0 +1,152 @@
+package rpcserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+// ErrRateLimited is returned to the clients exceeding Config.RateLimit.
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// sweepInterval is the minimum time between the removals of the idle
+// clients.
+const sweepInterval = time.Minute
+
+// rateLimiter limits the request rate of each client, identified by its IP
+// address. A nil *rateLimiter allows all requests.
+type rateLimiter struct {
+	limit rate.Limit
+	burst int
+	// limited counts the rejected requests.
+	limited prometheus.Counter
+
+	mu        sync.Mutex
+	clients   map[string]*rate.Limiter
+	lastSweep time.Time
+}
+
+// newRateLimiter returns the rate limiter of cfg, its metrics are registered
+// in reg. It returns nil when the rate limiting is disabled.
+func newRateLimiter(cfg Config, reg prometheus.Registerer) *rateLimiter {
+	if cfg.RateLimit <= 0 {
+		return nil
+	}
+	l := &rateLimiter{
+		limit: rate.Limit(cfg.RateLimit),
+		burst: max(cfg.RateBurst, 1),
+		limited: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "rpcserver",
+			Name:      "rate_limited_total",
+			Help:      "Number of requests rejected by the per client rate limit.",
+		}),
+		clients: make(map[string]*rate.Limiter),
+	}
+	reg.MustRegister(l.limited)
+	return l
+}
+
+// allow reports whether client can make a request at now.
+func (l *rateLimiter) allow(client string, now time.Time) bool {
+	if l == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	limiter, ok := l.clients[client]
+	if !ok {
+		limiter = rate.NewLimiter(l.limit, l.burst)
+		l.clients[client] = limiter
+	}
+	if !limiter.AllowN(now, 1) {
+		l.limited.Inc()
+		return false
+	}
+	return true
+}
+
+// sweep removes the clients with a full bucket, they are in the same state
+// as a new client. l.mu must be held.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for client, limiter := range l.clients {
+		if limiter.TokensAt(now) >= float64(l.burst) {
+			delete(l.clients, client)
+		}
+	}
+}
+
+// clientIP returns the host of addr, or addr when it has no port.
+func clientIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// middleware rejects the requests of the clients exceeding the rate limit
+// with a 429 status.
+func (l *rateLimiter) middleware(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.allow(clientIP(r.RemoteAddr), time.Now()) {
+			w.Header().Set("Retry-After", "1")
+			writeJSON(w, http.StatusTooManyRequests, ErrorResponse{Error: ErrRateLimited.Error()})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowCall reports whether the client of a gRPC call is within the rate
+// limit.
+func (l *rateLimiter) allowCall(ctx context.Context) error {
+	client := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		client = clientIP(p.Addr.String())
+	}
+	if !l.allow(client, time.Now()) {
+		return grpcstatus.Error(codes.ResourceExhausted, ErrRateLimited.Error())
+	}
+	return nil
+}
+
+// serverOptions returns the gRPC interceptors rejecting the calls of the
+// clients exceeding the rate limit with ResourceExhausted.
+func (l *rateLimiter) serverOptions() []grpc.ServerOption {
+	if l == nil {
+		return nil
+	}
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if err := l.allowCall(ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := l.allowCall(ss.Context()); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	}
+}
@@ -0,0 +1,65 @@
+package rpcserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	lightclientv1 "github.com/gonative-cc/bitcoin-lightclient/proto/lightclient/v1"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+	"gotest.tools/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(Config{RateLimit: 1, RateBurst: 2}, prometheus.NewRegistry())
+	now := time.Now()
+
+	assert.Assert(t, l.allow("a", now))
+	assert.Assert(t, l.allow("a", now))
+	assert.Assert(t, !l.allow("a", now))
+	// the clients have their own limit
+	assert.Assert(t, l.allow("b", now))
+	assert.Assert(t, l.allow("a", now.Add(time.Second)))
+	assert.Equal(t, testutil.ToFloat64(l.limited), float64(1))
+
+	// the idle clients are removed
+	assert.Assert(t, l.allow("c", now.Add(time.Hour)))
+	assert.Equal(t, len(l.clients), 1)
+
+	var disabled *rateLimiter
+	assert.Assert(t, disabled.allow("a", now))
+	assert.Assert(t, newRateLimiter(Config{}, prometheus.NewRegistry()) == nil)
+}
+
+func TestRateLimit(t *testing.T) {
+	lc, _, _, _ := newTestChain(t)
+	server, err := NewMulti([]Network{{Name: "regtest", LightClient: lc}},
+		Config{RateLimit: 0.001, RateBurst: 2, GRPCAddr: "127.0.0.1:0"})
+	assert.NilError(t, err)
+	ts := httptest.NewServer(server.HTTP.Handler)
+	defer ts.Close()
+	c := restClient{t: t, url: ts.URL}
+
+	assert.Equal(t, c.do(http.MethodGet, "/v1/tip", nil, nil), http.StatusOK)
+	assert.Equal(t, c.do(http.MethodGet, "/regtest/v1/tip", nil, nil), http.StatusOK)
+	var errRes ErrorResponse
+	assert.Equal(t, c.do(http.MethodGet, "/v1/tip", nil, &errRes), http.StatusTooManyRequests)
+	assert.Equal(t, errRes.Error, ErrRateLimited.Error())
+
+	// the probes are not limited
+	res, err := http.Get(ts.URL + "/healthz")
+	assert.NilError(t, err)
+	res.Body.Close()
+	assert.Equal(t, res.StatusCode, http.StatusOK)
+
+	// the limit of the client is shared by the HTTP and gRPC APIs
+	g := newGRPCClient(t, server)
+	_, err = g.GetTip(context.Background(), &lightclientv1.GetTipRequest{})
+	assert.Equal(t, grpcstatus.Code(err), codes.ResourceExhausted)
+}
@@ -0,0 +1,390 @@
+package rpcserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/rs/zerolog/log"
+)
+
+// maxRESTBody is the maximum size of a REST request body, except the header
+// insertions bounded by the maximum number of headers per request.
+const maxRESTBody = 1 << 20
+
+// hexHeaderJSONSize is the size of a hex encoded header in a JSON array: 160
+// characters, the quotes and the comma.
+const hexHeaderJSONSize = 163
+
+// BlockRef is a block of the REST API.
+type BlockRef struct {
+	Hash   chainhash.Hash `json:"hash"`
+	Height int64          `json:"height"`
+}
+
+// TipResponse is the best tip and the latest finalized block.
+type TipResponse struct {
+	Best      BlockRef `json:"best"`
+	Finalized BlockRef `json:"finalized"`
+}
+
+// HeaderResponse is a stored header with its chain state.
+type HeaderResponse struct {
+	Hash   chainhash.Hash `json:"hash"`
+	Height int64          `json:"height"`
+	// Header is the hex encoded 80 bytes serialized header.
+	Header    string         `json:"header"`
+	PrevHash  chainhash.Hash `json:"prev_hash"`
+	Timestamp int64          `json:"timestamp"`
+	Finalized bool           `json:"finalized"`
+	// ChainWork is the hex encoded total work up to the header.
+	ChainWork string `json:"chain_work"`
+}
+
+// ForkResponse is the head of a fork.
+type ForkResponse struct {
+	Head HeaderResponse `json:"head"`
+	// Best is set for the head of the most difficult fork.
+	Best bool `json:"best"`
+}
+
+// InsertHeadersRequest holds hex encoded 80 bytes headers, inserted in
+// order.
+type InsertHeadersRequest struct {
+	Headers []string `json:"headers"`
+}
+
+// InsertHeadersResponse holds the tip after an insertion.
+type InsertHeadersResponse struct {
+	Tip TipResponse `json:"tip"`
+}
+
+// VerifySPVRequest is a transaction inclusion proof, tx_out_proof is the hex
+// proof returned by bitcoind gettxoutproof.
+type VerifySPVRequest struct {
+	TxID       string `json:"tx_id"`
+	TxOutProof string `json:"tx_out_proof"`
+}
+
+// VerifySPVResponse holds the proof status: valid, partial_valid when the
+// block is not finalized yet, or invalid.
+type VerifySPVResponse struct {
+	Status string `json:"status"`
+}
+
+// ErrorResponse is the body of the REST API errors.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// restError is an error with its HTTP status code.
+type restError struct {
+	code int
+	err  error
+}
+
+func (e restError) Error() string { return e.err.Error() }
+func (e restError) Unwrap() error { return e.err }
+
+func badRequest(err error) error {
+	return restError{code: http.StatusBadRequest, err: err}
+}
+
+// httpError returns err with the HTTP status code matching the light client
+// error, or code by default.
+func httpError(err error, code int) error {
+	switch {
+	case errors.Is(err, btclightclient.ErrBlockNotInChain),
+		errors.Is(err, btclightclient.ErrHeightOutOfRange),
+		errors.Is(err, btclightclient.ErrMissingAncestor):
+		code = http.StatusNotFound
+	case errors.Is(err, btclightclient.ErrStoreUnavailable):
+		code = http.StatusServiceUnavailable
+	}
+	return restError{code: code, err: err}
+}
+
+// restParam is a path parameter of a REST route.
+type restParam struct {
+	name        string
+	typ         string
+	description string
+}
+
+// restRoute is a REST API endpoint, also used to generate the OpenAPI
+// document.
+type restRoute struct {
+	// name is the metrics method label and the OpenAPI operation ID.
+	name    string
+	method  string
+	path    string
+	summary string
+	params  []restParam
+	// request and response are values of the body types, request is nil
+	// without body.
+	request  any
+	response any
+	handle   func(h *RPCServerHandler, r *http.Request) (any, error)
+}
+
+var restRoutes = []restRoute{
+	{
+		name:     "rest_get_tip",
+		method:   http.MethodGet,
+		path:     "/v1/tip",
+		summary:  "Best tip and latest finalized block",
+		response: TipResponse{},
+		handle:   restGetTip,
+	},
+	{
+		name:     "rest_get_header",
+		method:   http.MethodGet,
+		path:     "/v1/headers/{hash}",
+		summary:  "Stored header by hash, finalized or on any fork",
+		params:   []restParam{{"hash", "string", "block hash"}},
+		response: HeaderResponse{},
+		handle:   restGetHeader,
+	},
+	{
+		name:     "rest_get_header_at_height",
+		method:   http.MethodGet,
+		path:     "/v1/headers/height/{height}",
+		summary:  "Header at a height of the best chain",
+		params:   []restParam{{"height", "integer", "block height"}},
+		response: HeaderResponse{},
+		handle:   restGetHeaderAtHeight,
+	},
+	{
+		name:     "rest_get_ancestor_proof",
+		method:   http.MethodGet,
+		path:     "/v1/proofs/ancestor/{height}",
+		summary:  "Proof that the finalized block at a height is an ancestor of the latest finalized block",
+		params:   []restParam{{"height", "integer", "block height"}},
+		response: AncestorProof{},
+		handle:   restGetAncestorProof,
+	},
+	{
+		name:     "rest_get_forks",
+		method:   http.MethodGet,
+		path:     "/v1/forks",
+		summary:  "Fork heads, the best tip first then by decreasing work",
+		response: []ForkResponse{},
+		handle:   restGetForks,
+	},
+	{
+		name:     "rest_insert_headers",
+		method:   http.MethodPost,
+		path:     "/v1/headers",
+		summary:  "Insert headers in order, stops at the first invalid header",
+		request:  InsertHeadersRequest{},
+		response: InsertHeadersResponse{},
+		handle:   restInsertHeaders,
+	},
+	{
+		name:     "rest_verify_spv",
+		method:   http.MethodPost,
+		path:     "/v1/spv/verify",
+		summary:  "Verify a transaction inclusion proof",
+		request:  VerifySPVRequest{},
+		response: VerifySPVResponse{},
+		handle:   restVerifySPV,
+	},
+}
+
+// registerREST registers the REST API routes and their OpenAPI document,
+// at /openapi.json, in mux.
+func registerREST(mux *http.ServeMux, h *RPCServerHandler) {
+	for _, route := range restRoutes {
+		mux.HandleFunc(route.method+" "+route.path, func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			res, err := route.handle(h, r)
+			h.metrics.observe(route.name, start, err)
+			if err != nil {
+				code := http.StatusInternalServerError
+				var restErr restError
+				if errors.As(err, &restErr) {
+					code = restErr.code
+				}
+				writeJSON(w, code, ErrorResponse{Error: err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, res)
+		})
+	}
+
+	doc, err := json.Marshal(openAPI(restRoutes))
+	if err != nil {
+		// the document only holds maps, slices and strings
+		panic(err)
+	}
+	mux.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(doc)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Err(err).Msg("Failed to write REST response")
+	}
+}
+
+// decodeBody decodes the JSON request body, of at most limit bytes.
+func decodeBody(r *http.Request, limit int64, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, limit))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return restError{code: http.StatusRequestEntityTooLarge, err: fmt.Errorf("request body larger than %d bytes", limit)}
+		}
+		return badRequest(fmt.Errorf("invalid request body: %w", err))
+	}
+	return nil
+}
+
+// insertBodyLimit is the maximum size of a header insertion request body.
+func (h *RPCServerHandler) insertBodyLimit() int64 {
+	if h.maxHeaders <= 0 {
+		return maxRESTBody
+	}
+	// the headers and the JSON object around them
+	return int64(h.maxHeaders)*hexHeaderJSONSize + 1024
+}
+
+func blockRef(b Block) BlockRef {
+	return BlockRef{Hash: *b.Hash, Height: b.Height}
+}
+
+func tipResponse(tip Tip) TipResponse {
+	return TipResponse{Best: blockRef(tip.Best), Finalized: blockRef(tip.Finalized)}
+}
+
+func headerResponse(info headerInfo) (HeaderResponse, error) {
+	header, err := btclightclient.BlockHeaderToHex(info.header)
+	if err != nil {
+		return HeaderResponse{}, err
+	}
+	return HeaderResponse{
+		Hash:      info.hash,
+		Height:    info.height,
+		Header:    header,
+		PrevHash:  info.header.PrevBlock,
+		Timestamp: info.header.Timestamp.Unix(),
+		Finalized: info.finalized,
+		ChainWork: info.chainWork(),
+	}, nil
+}
+
+func restGetTip(h *RPCServerHandler, _ *http.Request) (any, error) {
+	tip, err := h.currentTip()
+	if err != nil {
+		return nil, httpError(err, http.StatusInternalServerError)
+	}
+	return tipResponse(tip), nil
+}
+
+func restGetHeader(h *RPCServerHandler, r *http.Request) (any, error) {
+	hash, err := chainhash.NewHashFromStr(r.PathValue("hash"))
+	if err != nil {
+		return nil, badRequest(fmt.Errorf("invalid hash: %w", err))
+	}
+	info, err := h.headerByHash(*hash)
+	if err != nil {
+		return nil, httpError(err, http.StatusInternalServerError)
+	}
+	return headerResponse(info)
+}
+
+func restGetHeaderAtHeight(h *RPCServerHandler, r *http.Request) (any, error) {
+	height, err := strconv.ParseInt(r.PathValue("height"), 10, 64)
+	if err != nil {
+		return nil, badRequest(fmt.Errorf("invalid height: %w", err))
+	}
+	info, err := h.headerAtHeight(height)
+	if err != nil {
+		return nil, httpError(err, http.StatusInternalServerError)
+	}
+	return headerResponse(info)
+}
+
+func restGetAncestorProof(h *RPCServerHandler, r *http.Request) (any, error) {
+	height, err := strconv.ParseInt(r.PathValue("height"), 10, 64)
+	if err != nil {
+		return nil, badRequest(fmt.Errorf("invalid height: %w", err))
+	}
+	proof, err := h.ancestorProof(height)
+	if err != nil {
+		return nil, httpError(err, http.StatusInternalServerError)
+	}
+	return proof, nil
+}
+
+func restGetForks(h *RPCServerHandler, _ *http.Request) (any, error) {
+	heads, err := h.forkHeads()
+	if err != nil {
+		return nil, httpError(err, http.StatusInternalServerError)
+	}
+	forks := make([]ForkResponse, len(heads))
+	for i, info := range heads {
+		head, err := headerResponse(info)
+		if err != nil {
+			return nil, err
+		}
+		forks[i] = ForkResponse{Head: head, Best: i == 0}
+	}
+	return forks, nil
+}
+
+func restInsertHeaders(h *RPCServerHandler, r *http.Request) (any, error) {
+	var req InsertHeadersRequest
+	if err := decodeBody(r, h.insertBodyLimit(), &req); err != nil {
+		return nil, err
+	}
+	if err := h.checkBatch(len(req.Headers)); err != nil {
+		return nil, restError{code: http.StatusRequestEntityTooLarge, err: err}
+	}
+	headers := make([]*wire.BlockHeader, len(req.Headers))
+	for i, s := range req.Headers {
+		header, err := btclightclient.BlockHeaderFromHex(s)
+		if err != nil {
+			return nil, badRequest(fmt.Errorf("header %d: %w", i, err))
+		}
+		headers[i] = &header
+	}
+
+	// the insertion errors are about the headers
+	if err := h.InsertHeaders(r.Context(), headers); err != nil {
+		return nil, badRequest(err)
+	}
+	tip, err := h.currentTip()
+	if err != nil {
+		return nil, httpError(err, http.StatusInternalServerError)
+	}
+	return InsertHeadersResponse{Tip: tipResponse(tip)}, nil
+}
+
+func restVerifySPV(h *RPCServerHandler, r *http.Request) (any, error) {
+	var req VerifySPVRequest
+	if err := decodeBody(r, maxRESTBody, &req); err != nil {
+		return nil, err
+	}
+	proof, err := btclightclient.SPVProofFromHex(req.TxOutProof, req.TxID)
+	if err != nil {
+		return nil, badRequest(fmt.Errorf("invalid proof: %w", err))
+	}
+	status, err := h.VerifySPV(proof)
+	if err != nil {
+		return nil, httpError(err, http.StatusInternalServerError)
+	}
+	return VerifySPVResponse{Status: status.String()}, nil
+}
@@ -0,0 +1,196 @@
+package rpcserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
+	"github.com/gonative-cc/bitcoin-lightclient/chaingen"
+
+	"gotest.tools/assert"
+)
+
+// restClient calls the REST API of a test server.
+type restClient struct {
+	t   *testing.T
+	url string
+}
+
+func (c restClient) do(method, path string, body, res any) int {
+	c.t.Helper()
+	var reqBody bytes.Buffer
+	if body != nil {
+		assert.NilError(c.t, json.NewEncoder(&reqBody).Encode(body))
+	}
+	req, err := http.NewRequest(method, c.url+path, &reqBody)
+	assert.NilError(c.t, err)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NilError(c.t, err)
+	defer resp.Body.Close()
+	assert.Equal(c.t, resp.Header.Get("Content-Type"), "application/json")
+	if res != nil {
+		assert.NilError(c.t, json.NewDecoder(resp.Body).Decode(res))
+	}
+	return resp.StatusCode
+}
+
+func hexHeaders(t *testing.T, blocks []*chaingen.Block) []string {
+	t.Helper()
+	headers := make([]string, len(blocks))
+	for i, b := range blocks {
+		var err error
+		headers[i], err = btclightclient.BlockHeaderToHex(b.Header())
+		assert.NilError(t, err)
+	}
+	return headers
+}
+
+func TestREST(t *testing.T) {
+	lc, g, blocks, next := newTestChain(t)
+	ts := httptest.NewServer(NewServer(lc, Config{}).Handler)
+	defer ts.Close()
+	c := restClient{t: t, url: ts.URL}
+
+	var tip TipResponse
+	assert.Equal(t, c.do(http.MethodGet, "/v1/tip", nil, &tip), http.StatusOK)
+	assert.Equal(t, tip.Best, BlockRef{Hash: blocks[20].Hash(), Height: 20})
+	assert.Equal(t, tip.Finalized, BlockRef{Hash: blocks[13].Hash(), Height: 13})
+
+	var header HeaderResponse
+	assert.Equal(t, c.do(http.MethodGet, "/v1/headers/height/5", nil, &header), http.StatusOK)
+	assert.Equal(t, header.Hash, blocks[5].Hash())
+	assert.Equal(t, header.PrevHash, blocks[4].Hash())
+	assert.Equal(t, header.Header, hexHeaders(t, blocks[5:6])[0])
+	assert.Assert(t, header.Finalized)
+	assert.Equal(t, c.do(http.MethodGet, "/v1/headers/"+blocks[20].Hash().String(), nil, &header), http.StatusOK)
+	assert.Equal(t, header.Height, int64(20))
+	assert.Assert(t, !header.Finalized)
+
+	var errRes ErrorResponse
+	assert.Equal(t, c.do(http.MethodGet, "/v1/headers/height/21", nil, &errRes), http.StatusNotFound)
+	assert.ErrorContains(t, errorString(errRes.Error), btclightclient.ErrHeightOutOfRange.Error())
+	assert.Equal(t, c.do(http.MethodGet, "/v1/headers/"+next[0].Hash().String(), nil, nil), http.StatusNotFound)
+	assert.Equal(t, c.do(http.MethodGet, "/v1/headers/xyz", nil, nil), http.StatusBadRequest)
+	assert.Equal(t, c.do(http.MethodGet, "/v1/headers/height/x", nil, nil), http.StatusBadRequest)
+
+	var ancestorProof AncestorProof
+	assert.Equal(t, c.do(http.MethodGet, "/v1/proofs/ancestor/5", nil, &ancestorProof), http.StatusOK)
+	assert.Equal(t, ancestorProof.Hash, blocks[5].Hash())
+	assert.Equal(t, ancestorProof.Proof.Size, int32(14))
+	assert.NilError(t, btclightclient.VerifyAncestorProof(ancestorProof.Root, blocks[5].Hash(), ancestorProof.Proof))
+	// the blocks not finalized yet have no proof
+	assert.Equal(t, c.do(http.MethodGet, "/v1/proofs/ancestor/14", nil, nil), http.StatusNotFound)
+	assert.Equal(t, c.do(http.MethodGet, "/v1/proofs/ancestor/-1", nil, nil), http.StatusNotFound)
+	assert.Equal(t, c.do(http.MethodGet, "/v1/proofs/ancestor/x", nil, nil), http.StatusBadRequest)
+
+	var inserted InsertHeadersResponse
+	req := InsertHeadersRequest{Headers: hexHeaders(t, next)}
+	assert.Equal(t, c.do(http.MethodPost, "/v1/headers", req, &inserted), http.StatusOK)
+	assert.Equal(t, inserted.Tip.Best, BlockRef{Hash: next[2].Hash(), Height: 23})
+	// the headers already stored are skipped
+	assert.Equal(t, c.do(http.MethodPost, "/v1/headers", req, &inserted), http.StatusOK)
+	assert.Equal(t, inserted.Tip.Best, BlockRef{Hash: next[2].Hash(), Height: 23})
+	assert.Equal(t, c.do(http.MethodPost, "/v1/headers", map[string]any{"header": "00"}, nil), http.StatusBadRequest)
+	req = InsertHeadersRequest{Headers: []string{"00"}}
+	assert.Equal(t, c.do(http.MethodPost, "/v1/headers", req, &errRes), http.StatusBadRequest)
+	assert.ErrorContains(t, errorString(errRes.Error), btclightclient.ErrInvalidHeaderSize.Error())
+
+	// a fork from the finalized chain tip
+	fork := g.NextBlock(blocks[19])
+	req = InsertHeadersRequest{Headers: hexHeaders(t, []*chaingen.Block{fork})}
+	assert.Equal(t, c.do(http.MethodPost, "/v1/headers", req, nil), http.StatusOK)
+	var forks []ForkResponse
+	assert.Equal(t, c.do(http.MethodGet, "/v1/forks", nil, &forks), http.StatusOK)
+	assert.Equal(t, len(forks), 2)
+	assert.Equal(t, forks[0].Head.Hash, next[2].Hash())
+	assert.Assert(t, forks[0].Best)
+	assert.Equal(t, forks[1].Head.Hash, fork.Hash())
+	assert.Assert(t, !forks[1].Best)
+
+	txID := blocks[5].TxIDs()[2]
+	proof, err := blocks[5].TxOutProof(txID)
+	assert.NilError(t, err)
+	var spv VerifySPVResponse
+	spvReq := VerifySPVRequest{TxID: txID.String(), TxOutProof: proof}
+	assert.Equal(t, c.do(http.MethodPost, "/v1/spv/verify", spvReq, &spv), http.StatusOK)
+	assert.Equal(t, spv.Status, "valid")
+	spvReq.TxOutProof = "zz"
+	assert.Equal(t, c.do(http.MethodPost, "/v1/spv/verify", spvReq, nil), http.StatusBadRequest)
+}
+
+// errorString lets assert.ErrorContains check a message.
+type errorString string
+
+func (e errorString) Error() string { return string(e) }
+
+func TestRESTBodyLimit(t *testing.T) {
+	lc, _, _, next := newTestChain(t)
+	ts := httptest.NewServer(NewServer(lc, Config{MaxHeadersPerRequest: 2}).Handler)
+	defer ts.Close()
+	c := restClient{t: t, url: ts.URL}
+
+	// the number of headers is checked before they are decoded
+	var errRes ErrorResponse
+	req := InsertHeadersRequest{Headers: hexHeaders(t, next)}
+	assert.Equal(t, c.do(http.MethodPost, "/v1/headers", req, &errRes), http.StatusRequestEntityTooLarge)
+	assert.Assert(t, strings.Contains(errRes.Error, ErrTooManyHeaders.Error()), errRes.Error)
+	assert.Equal(t, lc.IsBlockPresent(next[0].Hash()), false)
+
+	// the body can't be larger than the headers allowed
+	req = InsertHeadersRequest{Headers: []string{strings.Repeat("00", 1000)}}
+	assert.Equal(t, c.do(http.MethodPost, "/v1/headers", req, &errRes), http.StatusRequestEntityTooLarge)
+
+	req = InsertHeadersRequest{Headers: hexHeaders(t, next[:2])}
+	assert.Equal(t, c.do(http.MethodPost, "/v1/headers", req, nil), http.StatusOK)
+	assert.Equal(t, lc.IsBlockPresent(next[1].Hash()), true)
+}
+
+func TestOpenAPI(t *testing.T) {
+	lc, _, _, _ := newTestChain(t)
+	ts := httptest.NewServer(NewServer(lc, Config{}).Handler)
+	defer ts.Close()
+
+	var doc struct {
+		OpenAPI    string                               `json:"openapi"`
+		Paths      map[string]map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	res, err := http.Get(ts.URL + "/openapi.json")
+	assert.NilError(t, err)
+	defer res.Body.Close()
+	raw := new(bytes.Buffer)
+	_, err = raw.ReadFrom(res.Body)
+	assert.NilError(t, err)
+	assert.NilError(t, json.Unmarshal(raw.Bytes(), &doc))
+	assert.Equal(t, doc.OpenAPI, "3.0.3")
+
+	for _, route := range restRoutes {
+		op, ok := doc.Paths[route.path][strings.ToLower(route.method)]
+		assert.Assert(t, ok, "%s %s", route.method, route.path)
+		assert.Equal(t, op["operationId"], route.name)
+	}
+	assert.DeepEqual(t, doc.Components.Schemas["BlockRef"], map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"hash": map[string]any{
+				"type":        "string",
+				"description": "hex encoded hash, in the usual byte reversed order",
+				"pattern":     "^[0-9a-f]{64}$",
+			},
+			"height": map[string]any{"type": "integer", "format": "int64"},
+		},
+		"required": []any{"hash", "height"},
+	})
+
+	// all the references are defined
+	for _, ref := range strings.Split(raw.String(), `"$ref":"#/components/schemas/`)[1:] {
+		name, _, _ := strings.Cut(ref, `"`)
+		assert.Assert(t, doc.Components.Schemas[name] != nil, name)
+	}
+}
@@ -1,7 +1,13 @@
 package rpcserver
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
@@ -9,7 +15,9 @@ import (
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/filecoin-project/go-jsonrpc"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
 )
 
 type Block struct {
@@ -17,53 +25,187 @@ type Block struct {
 	Height int64
 }
 
+// Config holds the RPC server settings.
+type Config struct {
+	// Addr is the TCP address the server listens on.
+	Addr string
+	// MaxTipAge is the maximum age of the best tip timestamp before the
+	// light client is reported as not ready. Zero disables the check.
+	MaxTipAge time.Duration
+	// GRPCAddr is the TCP address of the gRPC server. Empty disables the
+	// gRPC server.
+	GRPCAddr string
+	// MaxHeadersPerRequest is the maximum number of headers of an
+	// insertion request. Zero disables the limit.
+	MaxHeadersPerRequest int
+	// RateLimit is the number of requests per second allowed for each
+	// client IP address, with bursts of RateBurst requests. Zero disables
+	// the rate limiting.
+	RateLimit float64
+	RateBurst int
+}
+
+// ErrTooManyHeaders is returned for insertion requests with more than
+// Config.MaxHeadersPerRequest headers.
+var ErrTooManyHeaders = errors.New("too many headers in request")
+
+func DefaultConfig() Config {
+	return Config{
+		Addr:                 ":9797",
+		MaxTipAge:            2 * time.Hour,
+		MaxHeadersPerRequest: 2000,
+		RateLimit:            20,
+		RateBurst:            100,
+	}
+}
+
 // Have a type with some exported methods
 type RPCServerHandler struct {
-	btcLC *btclightclient.BTCLightClient
+	// mu serializes the insertions with the reads of the light client,
+	// shared by the JSON-RPC and gRPC APIs.
+	mu         sync.RWMutex
+	btcLC      *btclightclient.BTCLightClient
+	metrics    *Metrics
+	maxTipAge  time.Duration
+	maxHeaders int
+	tips       tipFeed
 }
 
 func (h *RPCServerHandler) Ping(in int) int {
+	defer h.metrics.observe("ping", time.Now(), nil)
 	return in
 }
 
-// txn to insert bitcoin block headers to light client
+// txn to insert bitcoin block headers to light client, it stops at the first
+// invalid header
 func (h *RPCServerHandler) InsertHeaders(
+	ctx context.Context,
 	blockHeaders []*wire.BlockHeader,
-) error {
-	for _, blockHeader := range blockHeaders {
-		if err := h.btcLC.InsertHeader(*blockHeader); err != nil {
-			log.Err(err).Msgf("Failed to insert block header %s", blockHeader.BlockHash())
+) (err error) {
+	defer func(start time.Time) { h.metrics.observe("insert_headers", start, err) }(time.Now())
+	if err := h.checkBatch(len(blockHeaders)); err != nil {
+		return err
+	}
+
+	source := headerSource(ctx)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// the headers inserted before an error are kept
+	defer func() { h.publishTip(h.tip()) }()
 
+	// the headers already stored are skipped, so that relayers can send
+	// overlapping ranges
+	for _, blockHeader := range blockHeaders {
+		if _, err := h.insertHeader(source, *blockHeader); err != nil {
 			return err
-		} else {
-			log.Info().Msgf("Inserted block header %s", blockHeader.BlockHash())
 		}
+	}
 
-		// fork cleanup updates last checkpointed height
-		if err := h.btcLC.CleanUpFork(); err != nil {
-			log.Err(err).Msgf(
-				"Failed to update fork choice after inserting block header %s",
-				blockHeader.BlockHash(),
-			)
+	return nil
+}
 
-			return err
-		} else {
-			log.Info().Msgf(
-				"Updated fork choice after inserting block header %s",
-				blockHeader.BlockHash(),
-			)
-		}
+// HeaderResult is the outcome of a header of insert_headers_hex and
+// insert_headers_base64.
+type HeaderResult struct {
+	// Hash is nil for a header which can't be decoded.
+	Hash    *chainhash.Hash              `json:"hash,omitempty"`
+	Outcome btclightclient.InsertOutcome `json:"outcome"`
+	// Reason is the error of a rejected header, or of the fork choice
+	// update after an insertion.
+	Reason string `json:"reason,omitempty"`
+}
+
+// InsertHeadersHex inserts 160 characters hex encoded headers in order. All
+// the headers are tried, the result reports the outcome of each one.
+func (h *RPCServerHandler) InsertHeadersHex(ctx context.Context, headers []string) (results []HeaderResult, err error) {
+	defer func(start time.Time) { h.metrics.observe("insert_headers_hex", start, err) }(time.Now())
+	if err := h.checkBatch(len(headers)); err != nil {
+		return nil, err
 	}
+	return h.insertEncodedHeaders(headerSource(ctx), headers, btclightclient.BlockHeaderFromHex), nil
+}
 
+// InsertHeadersBase64 inserts base64 encoded 80 bytes headers in order. All
+// the headers are tried, the result reports the outcome of each one.
+func (h *RPCServerHandler) InsertHeadersBase64(ctx context.Context, headers []string) (results []HeaderResult, err error) {
+	defer func(start time.Time) { h.metrics.observe("insert_headers_base64", start, err) }(time.Now())
+	if err := h.checkBatch(len(headers)); err != nil {
+		return nil, err
+	}
+	return h.insertEncodedHeaders(headerSource(ctx), headers, func(s string) (wire.BlockHeader, error) {
+		data, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return wire.BlockHeader{}, err
+		}
+		return btclightclient.BlockHeaderFromBytes(data)
+	}), nil
+}
+
+// checkBatch checks the number of headers of an insertion request.
+func (h *RPCServerHandler) checkBatch(n int) error {
+	if h.maxHeaders > 0 && n > h.maxHeaders {
+		return fmt.Errorf("%w: %d headers, limit %d", ErrTooManyHeaders, n, h.maxHeaders)
+	}
 	return nil
 }
 
+func (h *RPCServerHandler) insertEncodedHeaders(
+	source string, headers []string, decode func(string) (wire.BlockHeader, error),
+) []HeaderResult {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	defer func() { h.publishTip(h.tip()) }()
+
+	results := make([]HeaderResult, len(headers))
+	for i, s := range headers {
+		header, err := decode(s)
+		if err != nil {
+			results[i] = HeaderResult{Outcome: btclightclient.HeaderRejected, Reason: "invalid header: " + err.Error()}
+			continue
+		}
+		hash := header.BlockHash()
+		outcome, err := h.insertHeader(source, header)
+		results[i] = HeaderResult{Hash: &hash, Outcome: outcome}
+		if err != nil {
+			results[i].Reason = err.Error()
+		}
+	}
+	return results
+}
+
+// insertHeader inserts the header and updates the fork choice, h.mu must be
+// held.
+func (h *RPCServerHandler) insertHeader(source string, header wire.BlockHeader) (btclightclient.InsertOutcome, error) {
+	outcome, err := h.btcLC.InsertHeaderFrom(source, header)
+	if err != nil {
+		log.Err(err).Msgf("Failed to insert block header %s", header.BlockHash())
+		return outcome, err
+	}
+	if outcome == btclightclient.HeaderDuplicate {
+		return outcome, nil
+	}
+	log.Info().Msgf("Inserted block header %s: %s", header.BlockHash(), outcome)
+
+	if err := h.btcLC.CleanUpFork(); err != nil {
+		log.Err(err).Msgf("Failed to update fork choice after inserting block header %s", header.BlockHash())
+		return outcome, err
+	}
+	return outcome, nil
+}
+
 func (h *RPCServerHandler) ContainsBTCBlock(blockHash *chainhash.Hash) (bool, error) {
+	defer h.metrics.observe("contains_btc_block", time.Now(), nil)
+	h.mu.RLock()
+	defer h.mu.RUnlock()
 	return h.btcLC.IsBlockPresent(*blockHash), nil
 }
 
 // GetHeaderChainTip returns the latest finalized block stored in light client
 func (h *RPCServerHandler) GetHeaderChainTip() (Block, error) {
+	defer h.metrics.observe("get_header_chain_tip", time.Now(), nil)
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
 	latestFinalizedBlockHeight := h.btcLC.LatestFinalizedBlockHeight()
 	latestFinalizedBlockHash := h.btcLC.LatestFinalizedBlockHash()
 
@@ -75,8 +217,54 @@ func (h *RPCServerHandler) GetHeaderChainTip() (Block, error) {
 	return latestFinalizedBlock, nil
 }
 
+// GetAncestor returns the ancestor at height of the given block, finalized
+// or on any fork
+func (h *RPCServerHandler) GetAncestor(blockHash *chainhash.Hash, height int64) (block Block, err error) {
+	defer func(start time.Time) { h.metrics.observe("get_ancestor", start, err) }(time.Now())
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	ancestor, err := h.btcLC.AncestorAt(*blockHash, int32(height))
+	if err != nil {
+		return Block{}, err
+	}
+	ancestorHash := ancestor.Header.BlockHash()
+	return Block{Hash: &ancestorHash, Height: int64(ancestor.Height)}, nil
+}
+
+// GetBlockLocator returns the block locator of the given block, to request
+// the following headers with getheaders
+func (h *RPCServerHandler) GetBlockLocator(blockHash *chainhash.Hash) (locator []*chainhash.Hash, err error) {
+	defer func(start time.Time) { h.metrics.observe("get_block_locator", start, err) }(time.Now())
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.btcLC.BlockLocator(*blockHash)
+}
+
+// AncestorProof is returned by the get_ancestor_proof RPC and REST
+// endpoint. Proof is checked with btclightclient.VerifyAncestorProof.
+type AncestorProof struct {
+	Hash chainhash.Hash `json:"hash"`
+	// Root is the header accumulator root of the finalized chain, up to the
+	// latest finalized block.
+	Root  chainhash.Hash               `json:"root"`
+	Proof btclightclient.AncestorProof `json:"proof"`
+}
+
+// GetAncestorProof returns the proof that the finalized block at height is
+// an ancestor of the latest finalized block, pruned blocks included
+func (h *RPCServerHandler) GetAncestorProof(height int64) (proof AncestorProof, err error) {
+	defer func(start time.Time) { h.metrics.observe("get_ancestor_proof", start, err) }(time.Now())
+	return h.ancestorProof(height)
+}
+
 // VerifySPV verifies the proof if the transaction is included in a block
 func (h *RPCServerHandler) VerifySPV(spvProof *btclightclient.SPVProof) (btclightclient.SPVStatus, error) {
+	defer h.metrics.observe("verify_spv", time.Now(), nil)
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
 	log.Debug().Msgf("Recieved spvProof %v", spvProof)
 	checkSPV := h.btcLC.VerifySPV(*spvProof)
 
@@ -87,6 +275,10 @@ func (h *RPCServerHandler) VerifySPV(spvProof *btclightclient.SPVProof) (btcligh
 
 // VerifySPVs verifies proofs if the given batch of transactions are included in blocks
 func (h *RPCServerHandler) VerifySPVs(spvProofs []btclightclient.SPVProof) ([]btclightclient.SPVStatus, error) {
+	defer h.metrics.observe("verify_spvs", time.Now(), nil)
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
 	log.Debug().Msgf("Received list of SPV %v", spvProofs)
 	status := h.btcLC.VerifySPVs(spvProofs)
 
@@ -95,28 +287,156 @@ func (h *RPCServerHandler) VerifySPVs(spvProofs []btclightclient.SPVProof) ([]bt
 	return status, nil
 }
 
-// NewRPCServer creates a new instance of the rpcServer and starts listening
-func StartRPCServer(btcLC *btclightclient.BTCLightClient) error {
-	rpcServer := jsonrpc.NewServer()
-	serverHandler := &RPCServerHandler{
-		btcLC: btcLC,
+// DeploymentInfo is returned by the get_deployment_info RPC.
+type DeploymentInfo struct {
+	// Hash and Height are the latest finalized block, the deployment
+	// states are those of the next block.
+	Hash        chainhash.Hash                  `json:"hash"`
+	Height      int64                           `json:"height"`
+	Deployments []btclightclient.DeploymentInfo `json:"deployments"`
+}
+
+// GetDeploymentInfo returns the BIP9 soft fork deployment states over the
+// finalized chain
+func (h *RPCServerHandler) GetDeploymentInfo() (info DeploymentInfo, err error) {
+	defer func(start time.Time) { h.metrics.observe("get_deployment_info", start, err) }(time.Now())
+	// the deployment states are updated lazily
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hash, deployments, err := h.btcLC.DeploymentInfo()
+	if err != nil {
+		return DeploymentInfo{}, err
 	}
-	rpcServer.Register("RPCServerHandler", serverHandler)
+	return DeploymentInfo{
+		Hash:        hash,
+		Height:      h.btcLC.LatestFinalizedBlockHeight(),
+		Deployments: deployments,
+	}, nil
+}
+
+// StartRPCServer creates a new instance of the rpcServer and starts listening
+func StartRPCServer(btcLC *btclightclient.BTCLightClient, cfg Config) error {
+	server := New(btcLC, cfg)
+	log.Info().Msgf("RPC server running at: %s", server.HTTP.Addr)
+
+	return server.ListenAndServe()
+}
+
+// Server serves the JSON-RPC API, metrics and health endpoints over HTTP, and
+// the gRPC API, of one or more networks.
+type Server struct {
+	HTTP *http.Server
+	// GRPC is nil when Config.GRPCAddr is empty.
+	GRPC     *grpc.Server
+	GRPCAddr string
+	// Handler is the handler of the default network.
+	Handler *RPCServerHandler
+	// Networks are the handlers by network name.
+	Networks map[string]*RPCServerHandler
+}
+
+// New creates the servers of btcLC, named after its chain params. The caller
+// is responsible for starting them.
+func New(btcLC *btclightclient.BTCLightClient, cfg Config) *Server {
+	server, err := NewMulti([]Network{{Name: btcLC.ChainParams().Name, LightClient: btcLC}}, cfg)
+	if err != nil {
+		// the chain params names are valid network names
+		panic(err)
+	}
+	return server
+}
+
+// newHandler creates the handler of a network, its metrics are registered
+// in reg.
+func newHandler(btcLC *btclightclient.BTCLightClient, reg prometheus.Registerer, cfg Config) *RPCServerHandler {
+	btcLC.SetMetrics(btclightclient.NewMetrics(reg))
+	return &RPCServerHandler{
+		btcLC:      btcLC,
+		metrics:    NewMetrics(reg),
+		maxTipAge:  cfg.MaxTipAge,
+		maxHeaders: cfg.MaxHeadersPerRequest,
+	}
+}
+
+// apiHandler serves the JSON-RPC and REST APIs, and the readiness endpoint, of the network name served by h.
+func apiHandler(name string, h *RPCServerHandler) http.Handler {
+	rpcServer := jsonrpc.NewServer()
+	rpcServer.Register("RPCServerHandler", h)
 
 	rpcServer.AliasMethod("ping", "RPCServerHandler.Ping")
 	rpcServer.AliasMethod("insert_headers", "RPCServerHandler.InsertHeaders")
+	rpcServer.AliasMethod("insert_headers_hex", "RPCServerHandler.InsertHeadersHex")
+	rpcServer.AliasMethod("insert_headers_base64", "RPCServerHandler.InsertHeadersBase64")
 	rpcServer.AliasMethod("contains_btc_block", "RPCServerHandler.ContainsBTCBlock")
 	rpcServer.AliasMethod("get_header_chain_tip", "RPCServerHandler.GetHeaderChainTip")
+	rpcServer.AliasMethod("get_ancestor", "RPCServerHandler.GetAncestor")
+	rpcServer.AliasMethod("get_block_locator", "RPCServerHandler.GetBlockLocator")
+	rpcServer.AliasMethod("get_ancestor_proof", "RPCServerHandler.GetAncestorProof")
 	rpcServer.AliasMethod("verify_spv", "RPCServerHandler.VerifySPV")
 	rpcServer.AliasMethod("verify_spvs", "RPCServerHandler.VerifySPVs")
+	rpcServer.AliasMethod("get_status", "RPCServerHandler.GetStatus")
+	rpcServer.AliasMethod("get_deployment_info", "RPCServerHandler.GetDeploymentInfo")
+
+	mux := http.NewServeMux()
+	mux.Handle("/readyz", readyzHandler(h))
+	registerREST(mux, h)
+	mux.Handle("/", rpcServer)
+	return withHTTPSource(mux)
+}
+
+// NewServer creates the HTTP server serving the JSON-RPC API, metrics and
+// health endpoints. The caller is responsible for starting it.
+func NewServer(btcLC *btclightclient.BTCLightClient, cfg Config) *http.Server {
+	return New(btcLC, cfg).HTTP
+}
 
-	server := &http.Server{
-		Addr:         ":9797",
-		Handler:      rpcServer,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
+// ListenAndServe starts the HTTP and gRPC servers, it returns when one of
+// them fails.
+func (s *Server) ListenAndServe() error {
+	errs := make(chan error, 2)
+	if s.GRPC != nil {
+		ln, err := net.Listen("tcp", s.GRPCAddr)
+		if err != nil {
+			return err
+		}
+		log.Info().Msgf("gRPC server running at: %s", s.GRPCAddr)
+		go func() { errs <- s.GRPC.Serve(ln) }()
 	}
-	log.Info().Msgf("RPC server running at: %s", server.Addr)
+	go func() { errs <- s.HTTP.ListenAndServe() }()
+	return <-errs
+}
 
-	return server.ListenAndServe()
+// Shutdown gracefully stops the servers.
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.GRPC != nil {
+		stopped := make(chan struct{})
+		go func() {
+			s.GRPC.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			// the tip subscriptions don't end by themselves
+			s.GRPC.Stop()
+		}
+	}
+	err := s.HTTP.Shutdown(ctx)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// View calls fn with the light client of the named network under the read
+// lock, so that it doesn't change while fn runs, e.g. to save its state.
+func (s *Server) View(network string, fn func(*btclightclient.BTCLightClient) error) error {
+	h, ok := s.Networks[network]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownNetwork, network)
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return fn(h.btcLC)
 }
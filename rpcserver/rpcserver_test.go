@@ -0,0 +1,106 @@
+package rpcserver
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"gotest.tools/assert"
+)
+
+func TestGetDeploymentInfo(t *testing.T) {
+	lc := newTestLightClient(t)
+	h := &RPCServerHandler{btcLC: lc}
+
+	info, err := h.GetDeploymentInfo()
+	assert.NilError(t, err)
+	assert.Equal(t, info.Height, int64(0))
+	assert.Equal(t, info.Hash, *chaincfg.RegressionNetParams.GenesisHash)
+	assert.Equal(t, len(info.Deployments), int(chaincfg.DefinedDeployments))
+	// the genesis window is defined for all deployments
+	for _, d := range info.Deployments {
+		assert.Equal(t, d.State, btclightclient.DeploymentDefined, d.Name)
+	}
+
+	h = &RPCServerHandler{btcLC: btclightclient.NewBTCLightClient(&chaincfg.RegressionNetParams)}
+	_, err = h.GetDeploymentInfo()
+	assert.ErrorContains(t, err, btclightclient.ErrStoreUnavailable.Error())
+}
+
+func TestGetAncestor(t *testing.T) {
+	lc := newTestLightClient(t)
+	h := &RPCServerHandler{btcLC: lc}
+	tip, err := btclightclient.BlockHeaderFromHex(testHeaders[1])
+	assert.NilError(t, err)
+	tipHash := tip.BlockHash()
+
+	ancestor, err := h.GetAncestor(&tipHash, 0)
+	assert.NilError(t, err)
+	assert.Equal(t, *ancestor.Hash, *chaincfg.RegressionNetParams.GenesisHash)
+	assert.Equal(t, ancestor.Height, int64(0))
+
+	_, err = h.GetAncestor(&tipHash, 2)
+	assert.ErrorContains(t, err, btclightclient.ErrHeightOutOfRange.Error())
+
+	locator, err := h.GetBlockLocator(&tipHash)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, locator, []*chainhash.Hash{&tipHash, chaincfg.RegressionNetParams.GenesisHash})
+}
+
+func TestInsertHeadersEncoded(t *testing.T) {
+	lc, g, blocks, next := newTestChain(t)
+	h := &RPCServerHandler{btcLC: lc}
+
+	encoded := hexHeaders(t, next[:2])
+	results, err := h.InsertHeadersHex(context.Background(), []string{encoded[0], encoded[0], "00", encoded[1]})
+	assert.NilError(t, err)
+	hash0, hash1 := next[0].Hash(), next[1].Hash()
+	assert.DeepEqual(t, results, []HeaderResult{
+		{Hash: &hash0, Outcome: btclightclient.HeaderInserted},
+		{Hash: &hash0, Outcome: btclightclient.HeaderDuplicate},
+		{Outcome: btclightclient.HeaderRejected, Reason: "invalid header: " + btclightclient.ErrInvalidHeaderSize.Error()},
+		{Hash: &hash1, Outcome: btclightclient.HeaderInserted},
+	})
+
+	fork := g.NextBlock(blocks[20])
+	orphan := g.NextBlock(next[2])
+	results, err = h.InsertHeadersBase64(context.Background(), []string{
+		base64.StdEncoding.EncodeToString(rawHeader(t, fork)),
+		base64.StdEncoding.EncodeToString(rawHeader(t, orphan)),
+		"!",
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(results), 3)
+	assert.Equal(t, *results[0].Hash, fork.Hash())
+	assert.Equal(t, results[0].Outcome, btclightclient.HeaderNewFork)
+	assert.Equal(t, results[1].Outcome, btclightclient.HeaderRejected)
+	assert.Equal(t, results[1].Reason, btclightclient.ErrParentBlockNotInChain.Error())
+	assert.Equal(t, results[2].Outcome, btclightclient.HeaderRejected)
+	assert.Assert(t, results[2].Hash == nil)
+	assert.Equal(t, lc.IsBlockPresent(next[1].Hash()), true)
+}
+
+func TestMaxHeadersPerRequest(t *testing.T) {
+	lc, _, _, next := newTestChain(t)
+	h := &RPCServerHandler{btcLC: lc, maxHeaders: 2}
+
+	headers := make([]*wire.BlockHeader, len(next))
+	for i, b := range next {
+		header := b.Header()
+		headers[i] = &header
+	}
+	err := h.InsertHeaders(context.Background(), headers)
+	assert.Assert(t, errors.Is(err, ErrTooManyHeaders), err)
+	_, err = h.InsertHeadersHex(context.Background(), hexHeaders(t, next))
+	assert.Assert(t, errors.Is(err, ErrTooManyHeaders), err)
+	assert.Equal(t, lc.IsBlockPresent(next[0].Hash()), false)
+
+	assert.NilError(t, h.InsertHeaders(context.Background(), headers[:2]))
+	assert.Equal(t, lc.IsBlockPresent(next[1].Hash()), true)
+}
@@ -0,0 +1,31 @@
+package rpcserver
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/grpc/peer"
+)
+
+type sourceKey struct{}
+
+// withHTTPSource records the client address of the requests as the source
+// of the headers they submit.
+func withHTTPSource(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), sourceKey{}, "http:"+clientIP(r.RemoteAddr))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// headerSource returns the source of the headers submitted in the request of
+// ctx, recorded in the audit log: the API and the client IP address.
+func headerSource(ctx context.Context) string {
+	if source, ok := ctx.Value(sourceKey{}).(string); ok {
+		return source
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		return "grpc:" + clientIP(p.Addr.String())
+	}
+	return ""
+}
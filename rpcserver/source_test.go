@@ -0,0 +1,44 @@
+package rpcserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
+	"github.com/gonative-cc/bitcoin-lightclient/chaingen"
+	lightclientv1 "github.com/gonative-cc/bitcoin-lightclient/proto/lightclient/v1"
+
+	"gotest.tools/assert"
+)
+
+type sourceRecorder map[string]string
+
+func (r sourceRecorder) LogEvent(ev btclightclient.AuditEvent) {
+	if ev.Type == btclightclient.AuditInsert {
+		r[ev.Hash.String()] = ev.Source
+	}
+}
+
+func TestHeaderSource(t *testing.T) {
+	lc, _, _, next := newTestChain(t)
+	sources := sourceRecorder{}
+	lc.SetAuditLogger(sources)
+	server := New(lc, Config{GRPCAddr: "127.0.0.1:0"})
+	ts := httptest.NewServer(server.HTTP.Handler)
+	defer ts.Close()
+
+	c := restClient{t, ts.URL}
+	req := InsertHeadersRequest{Headers: hexHeaders(t, []*chaingen.Block{next[0]})}
+	assert.Equal(t, c.do(http.MethodPost, "/v1/headers", req, nil), http.StatusOK)
+	_, err := newGRPCClient(t, server).InsertHeaders(context.Background(), &lightclientv1.InsertHeadersRequest{
+		Headers: [][]byte{rawHeader(t, next[1])},
+	})
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, sources, sourceRecorder{
+		next[0].Hash().String(): "http:127.0.0.1",
+		next[1].Hash().String(): "grpc:127.0.0.1",
+	})
+}
@@ -0,0 +1,16 @@
+#!/bin/sh
+# Records the headers of a real network in btclightclient/testdata, from an
+# esplora API, e.g.
+#   scripts/record-headers.sh testnet4 https://mempool.space/testnet4/api 2016 4040
+set -e
+
+if [ $# -ne 4 ]; then
+	echo "usage: $0 <network> <esplora url> <from> <to>" >&2
+	exit 2
+fi
+
+cfg=$(mktemp)
+trap 'rm -f "$cfg"' EXIT
+printf '[[sources]]\n  type = "esplora"\n  url = "%s"\n' "$2" >"$cfg"
+go run . fetch-headers -config "$cfg" -network "$1" -from "$3" -to "$4" \
+	-out "btclightclient/testdata/$1-$3.json"
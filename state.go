@@ -0,0 +1,401 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+
+	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
+	"github.com/gonative-cc/bitcoin-lightclient/data"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/rs/zerolog/log"
+)
+
+// Header file formats supported by the CLI.
+const (
+	formatJSON   = "json"
+	formatBinary = "binary"
+)
+
+func decodeHeaders(blockHeaders []string) ([]wire.BlockHeader, error) {
+	headers := make([]wire.BlockHeader, len(blockHeaders))
+	for id, headerStr := range blockHeaders {
+		h, err := btclightclient.BlockHeaderFromHex(headerStr)
+		if err != nil {
+			return nil, NewInvalidHeaderErr(headerStr, id)
+		}
+		headers[id] = h
+	}
+	return headers, nil
+}
+
+// headersFile is an open header file in JSON or binary format.
+type headersFile struct {
+	f           *os.File
+	params      *chaincfg.Params
+	startHeight int64
+	// chain work of the parent of the first header
+	chainWork *big.Int
+	headers   btclightclient.HeaderIterator
+}
+
+// openHeadersFile opens a header file and checks it belongs to the configured
+// network. Binary files are streamed, JSON files are read at once.
+func openHeadersFile(cfg Config, path string) (*headersFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	hf := &headersFile{f: f}
+	r := bufio.NewReader(f)
+	if data.IsHeadersFile(r) {
+		reader, err := data.NewHeadersFileReader(r)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("read %s: %w", path, err)
+		}
+		hf.params, hf.startHeight, hf.chainWork, hf.headers = reader.Params(), reader.StartHeight(), reader.ChainWork(), reader
+	} else {
+		networkParams, startHeight, blockHeaders, err := data.DecodeJSON(r)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("read %s: %w", path, err)
+		}
+		headers, err := decodeHeaders(blockHeaders)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		hf.params, hf.startHeight, hf.chainWork = networkParams, startHeight, big.NewInt(0)
+		hf.headers = btclightclient.NewHeaderSliceIterator(headers)
+	}
+
+	if params, err := cfg.Params(); err != nil || hf.params != params {
+		f.Close()
+		return nil, fmt.Errorf("%s is a %s file, but network is %s", path, hf.params.Name, cfg.Network)
+	}
+	return hf, nil
+}
+
+func (hf *headersFile) Close() error {
+	return hf.f.Close()
+}
+
+// loadLightClient creates a light client from a header file.
+func loadLightClient(cfg Config, path string) (*btclightclient.BTCLightClient, error) {
+	hf, err := openHeadersFile(cfg, path)
+	if err != nil {
+		return nil, err
+	}
+	defer hf.Close()
+
+	btcLC, err := btclightclient.NewBTCLightClientFromIterator(
+		hf.params, hf.headers, int(hf.startHeight), hf.chainWork,
+		cfg.LightClientOptions()...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load %s: %w", path, err)
+	}
+	return btcLC, nil
+}
+
+// loadAnchor creates a light client from a trusted anchor file.
+func loadAnchor(cfg Config, path string) (*btclightclient.BTCLightClient, error) {
+	networkParams, a, err := data.ReadAnchorJSON(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	if params, err := cfg.Params(); err != nil || networkParams != params {
+		return nil, fmt.Errorf("%s is a %s file, but network is %s", path, networkParams.Name, cfg.Network)
+	}
+
+	anchor := btclightclient.TrustedAnchor{
+		Height:    int32(a.Height),
+		ChainWork: new(big.Int),
+	}
+	hash, err := chainhash.NewHashFromStr(a.Hash)
+	if err != nil {
+		return nil, err
+	}
+	anchor.Hash = *hash
+	if anchor.Header, err = btclightclient.BlockHeaderFromHex(a.Header); err != nil {
+		return nil, NewInvalidHeaderErr(a.Header, len(a.Ancestors))
+	}
+	if _, ok := anchor.ChainWork.SetString(a.ChainWork, 16); !ok {
+		return nil, fmt.Errorf("invalid chain work %q", a.ChainWork)
+	}
+	if anchor.Ancestors, err = decodeHeaders(a.Ancestors); err != nil {
+		return nil, err
+	}
+
+	return btclightclient.NewBTCLightClientFromAnchor(
+		networkParams, anchor,
+		cfg.LightClientOptions()...,
+	)
+}
+
+// loadState creates a light client from the state stored in the data dir.
+func loadState(cfg Config) (*btclightclient.BTCLightClient, error) {
+	if _, err := os.Stat(cfg.StateFile()); err != nil {
+		return nil, fmt.Errorf("no light client state in %s, run init first: %w", cfg.DataDir, err)
+	}
+	btcLC, err := loadLightClient(cfg, cfg.StateFile())
+	if err != nil {
+		return nil, err
+	}
+	if err := loadAccumulator(cfg, btcLC); err != nil {
+		// the light client accumulates from its first header instead
+		log.Warn().Err(err).Msgf("Failed to load the header accumulator %s", cfg.AccumulatorFile())
+	}
+	if err := loadForks(cfg.ForksFile(), btcLC); err != nil {
+		// the relayers send the missing fork headers again
+		log.Warn().Err(err).Msgf("Failed to restore the forks %s", cfg.ForksFile())
+	}
+	return btcLC, nil
+}
+
+// loadForks inserts the fork headers saved with the state in path, when
+// there are some.
+func loadForks(path string, btcLC *btclightclient.BTCLightClient) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	reader, err := data.NewHeadersFileReader(bufio.NewReader(f))
+	if err != nil {
+		return err
+	}
+	if reader.Params() != btcLC.ChainParams() {
+		return fmt.Errorf("forks of network %s, but network is %s", reader.Params().Name, btcLC.ChainParams().Name)
+	}
+	return restoreForks(btcLC, reader)
+}
+
+// restoreForks inserts fork headers, parents first. The headers which can't
+// be inserted anymore, e.g. after a finality depth change, are skipped.
+func restoreForks(btcLC *btclightclient.BTCLightClient, headers btclightclient.HeaderIterator) error {
+	var errs []error
+	for {
+		header, err := headers.Next()
+		if err == io.EOF {
+			return errors.Join(errs...)
+		}
+		if err != nil {
+			return err
+		}
+		if err := btcLC.InsertHeader(header); err != nil {
+			errs = append(errs, fmt.Errorf("fork header %s: %w", header.BlockHash(), err))
+		}
+	}
+}
+
+// loadAccumulator restores the header accumulator saved with the state, when
+// there is one.
+func loadAccumulator(cfg Config, btcLC *btclightclient.BTCLightClient) error {
+	f, err := os.Open(cfg.AccumulatorFile())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	acc, err := data.ReadAccumulatorFile(f, btcLC.ChainParams())
+	if err != nil {
+		return err
+	}
+	return btcLC.SetHeaderAccumulator(acc)
+}
+
+// encodeState writes the most difficult chain of the light client in the
+// given header file format.
+func encodeState(w io.Writer, btcLC *btclightclient.BTCLightClient, format string) error {
+	chain := btcLC.MainChain()
+	if len(chain) == 0 {
+		return btclightclient.ErrStoreUnavailable
+	}
+
+	headers := make([]wire.BlockHeader, len(chain))
+	for i, lb := range chain {
+		headers[i] = lb.Header
+	}
+	first := chain[0]
+	baseWork := new(big.Int).Sub(btcLC.TotalWorkAtBlock(first.Header.BlockHash()), first.CalcWork())
+	return writeHeaders(w, btcLC.ChainParams(), int64(first.Height), baseWork, headers, format)
+}
+
+// encodeForks writes the headers of the forks which are not on the most
+// difficult chain in the binary header file format, parents first. They
+// aren't consecutive, the start height is the height of the first one.
+func encodeForks(w io.Writer, btcLC *btclightclient.BTCLightClient) error {
+	headers, err := forkHeaders(btcLC)
+	if err != nil {
+		return err
+	}
+	startHeight := int64(0)
+	if len(headers) > 0 {
+		lb, err := btcLC.BlockByHash(headers[0].BlockHash())
+		if err != nil {
+			return err
+		}
+		startHeight = int64(lb.Height)
+	}
+	return writeHeaders(w, btcLC.ChainParams(), startHeight, new(big.Int), headers, formatBinary)
+}
+
+func forkHeaders(btcLC *btclightclient.BTCLightClient) ([]wire.BlockHeader, error) {
+	blocks, err := btcLC.ForkBlocks()
+	if err != nil {
+		return nil, err
+	}
+	headers := make([]wire.BlockHeader, len(blocks))
+	for i, lb := range blocks {
+		headers[i] = lb.Header
+	}
+	return headers, nil
+}
+
+// writeHeaders writes headers in the given header file format. baseWork is
+// the chain work of the parent of the first header, the JSON format doesn't
+// store it.
+func writeHeaders(w io.Writer, params *chaincfg.Params, startHeight int64, baseWork *big.Int, headers []wire.BlockHeader, format string) error {
+	switch format {
+	case formatBinary:
+		hw, err := data.NewHeadersFileWriter(w, params, startHeight, baseWork)
+		if err != nil {
+			return err
+		}
+		for _, h := range headers {
+			if err := hw.Write(h); err != nil {
+				return err
+			}
+		}
+		return hw.Flush()
+	case formatJSON:
+		blockHeaders := make([]string, len(headers))
+		for i, h := range headers {
+			headerHex, err := btclightclient.BlockHeaderToHex(h)
+			if err != nil {
+				return err
+			}
+			blockHeaders[i] = headerHex
+		}
+		return data.EncodeJSON(w, params, startHeight, blockHeaders)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// saveState writes the light client state, its forks and header accumulator
+// to the data dir. The files are written to a temporary file first, so a crash never
+// leaves a partial state.
+func saveState(cfg Config, btcLC *btclightclient.BTCLightClient) error {
+	if err := os.MkdirAll(cfg.DataDir, 0o755); err != nil {
+		return err
+	}
+
+	err := writeFile(cfg.StateFile(), func(w io.Writer) error {
+		return encodeState(w, btcLC, formatBinary)
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeFile(cfg.ForksFile(), func(w io.Writer) error {
+		return encodeForks(w, btcLC)
+	}); err != nil {
+		return err
+	}
+	return writeFile(cfg.AccumulatorFile(), func(w io.Writer) error {
+		return data.WriteAccumulatorFile(w, btcLC.ChainParams(), btcLC.HeaderAccumulator())
+	})
+}
+
+// writeFile writes path through a temporary file renamed once complete.
+func writeFile(path string, write func(io.Writer) error) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// reloadState returns the light client loaded from the saved state of btcLC,
+// as after a restart.
+func reloadState(cfg Config, btcLC *btclightclient.BTCLightClient) (*btclightclient.BTCLightClient, error) {
+	var buf bytes.Buffer
+	if err := encodeState(&buf, btcLC, formatBinary); err != nil {
+		return nil, err
+	}
+	reader, err := data.NewHeadersFileReader(&buf)
+	if err != nil {
+		return nil, err
+	}
+	reloaded, err := btclightclient.NewBTCLightClientFromIterator(
+		reader.Params(), reader, int(reader.StartHeight()), reader.ChainWork(),
+		cfg.LightClientOptions()...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	acc := btcLC.HeaderAccumulator()
+	err = reloaded.SetHeaderAccumulator(btclightclient.NewHeaderAccumulator(acc.StartHeight(), acc.Leaves()))
+	if err != nil {
+		return nil, err
+	}
+	forks, err := forkHeaders(btcLC)
+	if err != nil {
+		return nil, err
+	}
+	if err := restoreForks(reloaded, btclightclient.NewHeaderSliceIterator(forks)); err != nil {
+		return nil, err
+	}
+	return reloaded, nil
+}
+
+// insertHeaders inserts headers into the light client, skipping headers that
+// are already known. source identifies the headers in the audit log. It
+// returns the number of inserted headers.
+func insertHeaders(btcLC *btclightclient.BTCLightClient, source string, headers btclightclient.HeaderIterator) (int, error) {
+	inserted := 0
+	for {
+		header, err := headers.Next()
+		if err == io.EOF {
+			return inserted, nil
+		}
+		if err != nil {
+			return inserted, err
+		}
+
+		outcome, err := btcLC.InsertHeaderFrom(source, header)
+		if outcome == btclightclient.HeaderDuplicate {
+			continue
+		}
+		if err != nil {
+			return inserted, fmt.Errorf("insert header %s: %w", header.BlockHash(), err)
+		}
+		if err := btcLC.CleanUpFork(); err != nil {
+			return inserted, err
+		}
+		inserted++
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gonative-cc/bitcoin-lightclient/btclightclient"
+	"github.com/gonative-cc/bitcoin-lightclient/chaingen"
+	"github.com/gonative-cc/bitcoin-lightclient/data"
+
+	"github.com/btcsuite/btcd/wire"
+	"gotest.tools/assert"
+)
+
+func TestStateRoundTrip(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Network = "regressionnet"
+	cfg.DataDir = t.TempDir()
+
+	btcLC, err := loadLightClient(cfg, "data/regtest.json")
+	assert.NilError(t, err)
+	assert.NilError(t, saveState(cfg, btcLC))
+
+	loaded, err := loadState(cfg)
+	assert.NilError(t, err)
+	want, err := btcLC.ChainStatus()
+	assert.NilError(t, err)
+	got, err := loaded.ChainStatus()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, got, want)
+
+	cfg.Network = "mainnet"
+	_, err = loadState(cfg)
+	assert.ErrorContains(t, err, "network is mainnet")
+}
+
+func TestAccumulatorState(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Network = "regressionnet"
+	cfg.DataDir = t.TempDir()
+	params, err := cfg.Params()
+	assert.NilError(t, err)
+	g := chaingen.New(params)
+	blocks := chaingen.Chain(g.Extend(g.Genesis(), 30)[29])
+	full := btclightclient.NewBTCLightClientWithData(params, chaingen.Headers(blocks), 0, cfg.LightClientOptions()...)
+
+	// the saved state starts after pruned blocks, they are kept in the
+	// accumulator
+	assert.NilError(t, saveState(cfg, full))
+	recent := btclightclient.NewBTCLightClientWithData(params, chaingen.Headers(blocks[20:]), 20, cfg.LightClientOptions()...)
+	assert.NilError(t, writeFile(cfg.StateFile(), func(w io.Writer) error {
+		return encodeState(w, recent, formatBinary)
+	}))
+	loaded, err := loadState(cfg)
+	assert.NilError(t, err)
+	assert.Equal(t, loaded.HeaderAccumulator().StartHeight(), int32(0))
+	assert.Equal(t, loaded.HeaderAccumulator().Root(), full.HeaderAccumulator().Root())
+	hash, proof, err := loaded.AncestorProof(5)
+	assert.NilError(t, err)
+	assert.Equal(t, hash, blocks[5].Hash())
+	assert.NilError(t, btclightclient.VerifyAncestorProof(loaded.HeaderAccumulator().Root(), hash, proof))
+
+	// an invalid accumulator is ignored
+	assert.NilError(t, os.WriteFile(cfg.AccumulatorFile(), []byte("BTCA"), 0o644))
+	loaded, err = loadState(cfg)
+	assert.NilError(t, err)
+	assert.Equal(t, loaded.HeaderAccumulator().StartHeight(), int32(20))
+}
+
+func TestForksState(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Network = "regressionnet"
+	cfg.DataDir = t.TempDir()
+	params, err := cfg.Params()
+	assert.NilError(t, err)
+	g := chaingen.New(params)
+	blocks := chaingen.Chain(g.Extend(g.Genesis(), 20)[19])
+	btcLC := btclightclient.NewBTCLightClientWithData(params, chaingen.Headers(blocks), 0, cfg.LightClientOptions()...)
+	// two forks sharing their first block and a fork of the same work as
+	// the best chain
+	fork := g.Extend(blocks[15], 3)
+	forks := append(fork, g.NextBlock(fork[0]), g.NextBlock(blocks[19]))
+	for _, b := range forks {
+		assert.NilError(t, btcLC.InsertHeader(b.Header()))
+		assert.NilError(t, btcLC.CleanUpFork())
+	}
+	saved, err := btcLC.ChainStatus()
+	assert.NilError(t, err)
+
+	assert.NilError(t, saveState(cfg, btcLC))
+	loaded, err := loadState(cfg)
+	assert.NilError(t, err)
+	status, err := loaded.ChainStatus()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, status, saved)
+	for _, b := range forks {
+		assert.Assert(t, loaded.IsBlockPresent(b.Hash()), "block %d", b.Height)
+	}
+	// the forks can be extended after the restart
+	assert.NilError(t, loaded.InsertHeader(g.NextBlock(fork[2]).Header()))
+
+	reloaded, err := reloadState(cfg, btcLC)
+	assert.NilError(t, err)
+	status, err = reloaded.ChainStatus()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, status, saved)
+}
+
+func TestServeSavesOnFailure(t *testing.T) {
+	dataDir := t.TempDir()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	defer ln.Close()
+
+	// the bootstrapped state is saved when the RPC address is in use
+	err = runServe([]string{"-data-dir", dataDir, "-network", "regressionnet", "-data-file", "data/regtest.json",
+		"-rpc-addr", ln.Addr().String(), "-grpc-addr", ""})
+	assert.ErrorContains(t, err, "address already in use")
+	cfg := DefaultConfig()
+	cfg.Network = "regressionnet"
+	cfg.DataDir = dataDir
+	saved, err := loadState(cfg)
+	assert.NilError(t, err)
+	want, err := loadLightClient(cfg, "data/regtest.json")
+	assert.NilError(t, err)
+	assert.Equal(t, saved.LatestFinalizedBlockHash(), want.LatestFinalizedBlockHash())
+}
+
+func TestExportFormats(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Network = "regressionnet"
+	cfg.DataDir = t.TempDir()
+
+	btcLC, err := loadLightClient(cfg, "data/regtest.json")
+	assert.NilError(t, err)
+	want, err := btcLC.ChainStatus()
+	assert.NilError(t, err)
+
+	sizes := map[string]int64{}
+	for _, format := range []string{formatJSON, formatBinary} {
+		path := filepath.Join(cfg.DataDir, "export."+format)
+		f, err := os.Create(path)
+		assert.NilError(t, err)
+		assert.NilError(t, encodeState(f, btcLC, format))
+		assert.NilError(t, f.Close())
+
+		loaded, err := loadLightClient(cfg, path)
+		assert.NilError(t, err)
+		got, err := loaded.ChainStatus()
+		assert.NilError(t, err)
+		assert.DeepEqual(t, got, want)
+
+		info, err := os.Stat(path)
+		assert.NilError(t, err)
+		sizes[format] = info.Size()
+	}
+	assert.Assert(t, sizes[formatBinary] < sizes[formatJSON]/2)
+
+	assert.ErrorContains(t, encodeState(os.Stdout, btcLC, "xml"), "unknown format")
+}
+
+func TestAnchorBootstrap(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Network = "regressionnet"
+	cfg.DataDir = t.TempDir()
+
+	_, startHeight, blockHeaders, err := data.ReadJSON("data/regtest.json")
+	assert.NilError(t, err)
+	last := len(blockHeaders) - 1
+	header, err := btclightclient.BlockHeaderFromHex(blockHeaders[last])
+	assert.NilError(t, err)
+
+	anchorFile := filepath.Join(cfg.DataDir, "anchor.json")
+	content, err := json.Marshal(data.Anchor{
+		Network:   cfg.Network,
+		Height:    startHeight + int64(last),
+		Hash:      header.BlockHash().String(),
+		Header:    blockHeaders[last],
+		ChainWork: "1000000",
+		Ancestors: blockHeaders[:last],
+	})
+	assert.NilError(t, err)
+	assert.NilError(t, os.WriteFile(anchorFile, content, 0o644))
+
+	btcLC, err := bootstrap(cfg, "", anchorFile)
+	assert.NilError(t, err)
+	assert.Equal(t, btcLC.LatestFinalizedBlockHash(), header.BlockHash())
+	assert.Equal(t, btcLC.TotalWorkAtBlock(header.BlockHash()).Text(16), "1000000")
+
+	// the chain work is kept across restarts
+	assert.NilError(t, saveState(cfg, btcLC))
+	loaded, err := loadState(cfg)
+	assert.NilError(t, err)
+	assert.Equal(t, loaded.TotalWorkAtBlock(header.BlockHash()).Text(16), "1000000")
+
+	_, err = bootstrap(cfg, "data/regtest.json", anchorFile)
+	assert.ErrorContains(t, err, "mutually exclusive")
+}
+
+func TestStoreVerify(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Network = "regressionnet"
+	cfg.DataDir = t.TempDir()
+
+	btcLC, err := loadLightClient(cfg, "data/regtest.json")
+	assert.NilError(t, err)
+	assert.NilError(t, saveState(cfg, btcLC))
+
+	args := []string{"-data-dir", cfg.DataDir, "-network", cfg.Network}
+	assert.NilError(t, runStore(append([]string{"verify"}, args...)))
+	assert.NilError(t, runStore(append([]string{"verify", "-repair"}, args...)))
+	assert.ErrorContains(t, runStore(args), "usage")
+}
+
+func TestReplayAudit(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Network = "regressionnet"
+	cfg.DataDir = t.TempDir()
+	cfg.AuditLog = "audit.jsonl"
+	assert.NilError(t, WriteConfig(filepath.Join(cfg.DataDir, configFileName), cfg))
+	args := []string{"-data-dir", cfg.DataDir}
+	assert.NilError(t, runInit(append(args, "-data-file", "data/regtest.json")))
+
+	btcLC, err := loadState(cfg)
+	assert.NilError(t, err)
+	tip := btcLC.MainChain()[len(btcLC.MainChain())-1]
+	g := chaingen.NewFromBlock(btcLC.ChainParams(), &wire.MsgBlock{Header: tip.Header}, tip.Height)
+	importHeaders := func(name string, blocks []*chaingen.Block) {
+		path := filepath.Join(t.TempDir(), name)
+		f, err := os.Create(path)
+		assert.NilError(t, err)
+		assert.NilError(t, writeHeaders(f, g.Params(), int64(blocks[0].Height), big.NewInt(0), chaingen.Headers(blocks), formatJSON))
+		assert.NilError(t, f.Close())
+		assert.NilError(t, runImportHeaders(append(args, path)))
+	}
+
+	// the fork is lost on the restart before the next import
+	best := g.Extend(g.Genesis(), 3)
+	importHeaders("best.json", best)
+	importHeaders("fork.json", g.Extend(best[0], 1))
+	importHeaders("next.json", g.Extend(best[2], 10))
+
+	out := filepath.Join(t.TempDir(), "replayed.bin")
+	assert.NilError(t, runReplayAudit(append(args, "-data-file", "data/regtest.json", "-out", out)))
+	saved, err := loadState(cfg)
+	assert.NilError(t, err)
+	replayed, err := loadLightClient(cfg, out)
+	assert.NilError(t, err)
+	want, err := saved.ChainStatus()
+	assert.NilError(t, err)
+	got, err := replayed.ChainStatus()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, got, want)
+	assert.Equal(t, got.TipHeight, tip.Height+13)
+}